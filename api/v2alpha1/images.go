@@ -0,0 +1,127 @@
+/*
+Copyright 2025 whatapK8s.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultImageRegistry hosts the WhaTap images the operator deploys by default.
+const DefaultImageRegistry = "public.ecr.aws"
+
+// DefaultApmInitVersion is injected when a target does not set a language version.
+const DefaultApmInitVersion = "latest"
+
+// ApmInitImage returns the default APM init image of lang at version, for example
+// public.ecr.aws/whatap/apm-init-java:latest.
+func ApmInitImage(lang, version string) string {
+	return fmt.Sprintf("%s/whatap/apm-init-%s:%s", DefaultImageRegistry, lang, version)
+}
+
+// DefaultOtelImageRegistry hosts the OpenTelemetry auto-instrumentation images.
+const DefaultOtelImageRegistry = "ghcr.io"
+
+// OtelAutoInstrumentationImage returns the OpenTelemetry auto-instrumentation image of
+// lang at version, for example
+// ghcr.io/open-telemetry/opentelemetry-operator/autoinstrumentation-java:latest.
+func OtelAutoInstrumentationImage(lang, version string) string {
+	return fmt.Sprintf("%s/open-telemetry/opentelemetry-operator/autoinstrumentation-%s:%s", DefaultOtelImageRegistry, lang, version)
+}
+
+// Image returns a default image of the operator with spec.imageRegistry applied.
+// Custom images configured by the user are not passed through Image.
+func (in *WhatapAgent) Image(image string) string {
+	return RewriteRegistry(image, in.Spec.ImageRegistry)
+}
+
+// PullPolicy returns spec.imagePullPolicy, or def when it is not set.
+func (in *WhatapAgent) PullPolicy(def corev1.PullPolicy) corev1.PullPolicy {
+	if in.Spec.ImagePullPolicy != "" {
+		return in.Spec.ImagePullPolicy
+	}
+	return def
+}
+
+// PinnedImage returns image@digest when spec.pinImageDigests is set and the digest of
+// image was resolved for the current generation, and image otherwise.
+func (in *WhatapAgent) PinnedImage(image string) string {
+	status := in.Status.ImageDigests
+	if !in.Spec.PinImageDigests || status == nil || status.ObservedGeneration != in.Generation {
+		return image
+	}
+	digest, ok := status.Digests[image]
+	if !ok {
+		return image
+	}
+	repository, _ := SplitImageTag(image)
+	return repository + "@" + digest
+}
+
+// RewriteRegistry replaces the registry host of image with registry, keeping the
+// repository path and tag. An empty registry returns image unchanged.
+//
+//	RewriteRegistry("public.ecr.aws/whatap/kube_agent:1.0", "harbor.local/mirror")
+//	  -> "harbor.local/mirror/whatap/kube_agent:1.0"
+func RewriteRegistry(image, registry string) string {
+	registry = strings.TrimSuffix(registry, "/")
+	if registry == "" {
+		return image
+	}
+	if host, path, ok := strings.Cut(image, "/"); ok && isRegistryHost(host) {
+		return registry + "/" + path
+	}
+	return registry + "/" + image
+}
+
+// SplitImageTag splits image into its repository (including the registry) and tag.
+// The tag is empty when image has none or is pinned by digest.
+func SplitImageTag(image string) (repository, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], ""
+	}
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, ""
+}
+
+// isRegistryHost reports whether the first path component of an image reference is a
+// registry, following the rules of the docker reference grammar.
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
+
+// ApmInitImageFor returns the APM init image injected for target, before digest
+// pinning: the custom image of the target, or the default WhaTap (or OpenTelemetry, for
+// agentType "otel") image of its language and version with spec.imageRegistry applied.
+func (in *WhatapAgent) ApmInitImageFor(target TargetSpec) string {
+	if target.CustomImageFullName != "" {
+		return target.CustomImageFullName
+	}
+	// Deprecated CustomImageName, kept for backward compatibility
+	if target.CustomImageName != "" {
+		return target.CustomImageName
+	}
+	if target.UsesOtel() {
+		return in.Image(OtelAutoInstrumentationImage(target.Language, target.AgentVersion()))
+	}
+	return in.Image(ApmInitImage(target.Language, target.AgentVersion()))
+}
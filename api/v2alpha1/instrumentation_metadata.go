@@ -0,0 +1,100 @@
+/*
+Copyright 2025 whatapK8s.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+// Pod metadata written by the injection webhook and read back by the operator
+// to report instrumentation status.
+const (
+	// InjectionLabelKey is set on every pod matched by an instrumentation target:
+	// "true" when the agent was injected, "false" when injection was skipped.
+	// The operator only caches pods carrying this label.
+	InjectionLabelKey = "monitoring.whatap.com/apm-injected"
+
+	AnnotationApmInjected      = "whatap-apm-injected"
+	AnnotationApmLanguage      = "whatap-apm-language"
+	AnnotationApmVersion       = "whatap-apm-version"
+	AnnotationApmInstance      = "whatap-apm-instance"
+	AnnotationApmTarget        = "whatap-apm-target"
+	AnnotationApmSkippedReason = "whatap-apm-skipped-reason"
+)
+
+// Instrumentation modes of InstrumentationSpec.Mode and TargetSpec.Mode
+const (
+	InstrumentationModeInject       = "inject"
+	InstrumentationModeUninstrument = "uninstrument"
+)
+
+// TargetMode returns the effective mode of target: an instrumentation-wide
+// "uninstrument" applies to every target.
+func (in *InstrumentationSpec) TargetMode(target TargetSpec) string {
+	if in.Mode == InstrumentationModeUninstrument || target.Mode == InstrumentationModeUninstrument {
+		return InstrumentationModeUninstrument
+	}
+	return InstrumentationModeInject
+}
+
+// Agent types of TargetSpec.AgentType
+const (
+	AgentTypeWhatap = "whatap"
+	AgentTypeOtel   = "otel"
+)
+
+// UsesOtel reports whether target injects OpenTelemetry auto-instrumentation instead of
+// the WhaTap agent.
+func (in *TargetSpec) UsesOtel() bool {
+	return in.AgentType == AgentTypeOtel
+}
+
+// AgentVersion returns the agent version injected for target: the OpenTelemetry
+// auto-instrumentation version for agentType "otel", the WhaTap agent version of its
+// language otherwise.
+func (in *TargetSpec) AgentVersion() string {
+	version := in.WhatapApmVersions[in.Language]
+	if in.UsesOtel() {
+		version = ""
+		if in.Otel != nil {
+			version = in.Otel.Version
+		}
+	}
+	if version == "" {
+		return DefaultApmInitVersion
+	}
+	return version
+}
+
+// Python agent start modes of TargetSpec.PythonStartMode
+const (
+	PythonStartModeSitecustomize = "sitecustomize"
+	PythonStartModeWrap          = "wrap"
+)
+
+// Runtime version check (TargetSpec.RuntimeCheck). The webhook adds one init container
+// per checked container, named RuntimeCheckContainerPrefix-<container index>; its
+// termination message starts with one of the RuntimeCheck* results, e.g.
+// "disabled: java 1.6.0_45 of container app is older than 8".
+const (
+	RuntimeCheckContainerPrefix = "whatap-runtime-check"
+
+	RuntimeCheckEnabled  = "enabled"
+	RuntimeCheckDisabled = "disabled"
+	RuntimeCheckUnknown  = "unknown"
+
+	// RuntimeCheckShellDefault is the shell of RuntimeCheckSpec.Shell when unset, and
+	// RuntimeCheckShellNone turns the check off for images without a shell
+	RuntimeCheckShellDefault = "/bin/sh"
+	RuntimeCheckShellNone    = "none"
+)
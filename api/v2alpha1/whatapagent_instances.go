@@ -0,0 +1,212 @@
+/*
+Copyright 2025 whatapK8s.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+import (
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultWhatapAgentName is the name of the primary WhatapAgent instance.
+// Its child resources keep their historical, un-prefixed names.
+const DefaultWhatapAgentName = "whatap"
+
+// InstanceLabelKey marks pods that belong to a non-default WhatapAgent instance,
+// and the dcgm-exporter pods of every instance, so selectors shared by name (e.g.
+// the dcgm-exporter Service) stay disjoint.
+const InstanceLabelKey = "monitoring.whatap.com/instance"
+
+// IsDefaultInstance reports whether this is the primary "whatap" instance.
+func (in *WhatapAgent) IsDefaultInstance() bool {
+	return in.Name == "" || in.Name == DefaultWhatapAgentName
+}
+
+// ResourceName returns the name of a child resource derived from base.
+// The default instance returns base unchanged; any other instance prefixes it
+// with the CR name, e.g. "tenant" + "whatap-master-agent" -> "tenant-whatap-master-agent".
+func (in *WhatapAgent) ResourceName(base string) string {
+	if in.IsDefaultInstance() {
+		return base
+	}
+	return in.Name + "-" + base
+}
+
+// InstanceLabels returns the labels identifying pods of a non-default instance.
+// It is empty for the default instance so existing pod templates do not change.
+func (in *WhatapAgent) InstanceLabels() map[string]string {
+	if in.IsDefaultInstance() {
+		return map[string]string{}
+	}
+	return map[string]string{InstanceLabelKey: in.Name}
+}
+
+// IsOlderThan orders instances by creation time, then by name. When two
+// instances claim the same pod or node, the older one wins.
+func (in *WhatapAgent) IsOlderThan(other *WhatapAgent) bool {
+	if !in.CreationTimestamp.Equal(&other.CreationTimestamp) {
+		return in.CreationTimestamp.Before(&other.CreationTimestamp)
+	}
+	return in.Name < other.Name
+}
+
+// NodeAgentsOverlap reports whether the node agents of both instances can land
+// on the same node. nodeSelector and the required node affinity are compared
+// statically: the agents are disjoint when every pair of their node selector
+// terms requires incompatible values of the same label (or field). Tolerations
+// only widen where an agent may run and preferred affinities never prevent it,
+// so neither is considered; nor are the labels of the actual nodes, so agents
+// whose constraints merely happen to match no common node still overlap.
+func NodeAgentsOverlap(a, b *WhatapAgent) bool {
+	na, nb := a.Spec.Features.K8sAgent.NodeAgent, b.Spec.Features.K8sAgent.NodeAgent
+	if !na.Enabled || !nb.Enabled {
+		return false
+	}
+	for _, ta := range nodeSelectorTerms(na.NodeSelector, na.Affinity) {
+		for _, tb := range nodeSelectorTerms(nb.NodeSelector, nb.Affinity) {
+			if !requirementsDisjoint(ta, tb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeSelectorTerms returns the alternatives (ORed) of the node constraints of a pod,
+// each a list of ANDed requirements: nodeSelector combined with every required node
+// affinity term. Field requirements are keyed by "field:<name>" so that they never
+// compare with labels.
+func nodeSelectorTerms(nodeSelector map[string]string, affinity *corev1.Affinity) [][]corev1.NodeSelectorRequirement {
+	var base []corev1.NodeSelectorRequirement
+	for k, v := range nodeSelector {
+		base = append(base, corev1.NodeSelectorRequirement{Key: k, Operator: corev1.NodeSelectorOpIn, Values: []string{v}})
+	}
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return [][]corev1.NodeSelectorRequirement{base}
+	}
+	var terms [][]corev1.NodeSelectorRequirement
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		reqs := append(append([]corev1.NodeSelectorRequirement{}, base...), term.MatchExpressions...)
+		for _, f := range term.MatchFields {
+			f.Key = "field:" + f.Key
+			reqs = append(reqs, f)
+		}
+		terms = append(terms, reqs)
+	}
+	if len(terms) == 0 {
+		return [][]corev1.NodeSelectorRequirement{base}
+	}
+	return terms
+}
+
+// requirementsDisjoint reports whether no node can satisfy both lists of requirements,
+// judged on pairs of requirements of the same key.
+func requirementsDisjoint(a, b []corev1.NodeSelectorRequirement) bool {
+	for _, ra := range a {
+		for _, rb := range b {
+			if ra.Key == rb.Key && (requirementExcludes(ra, rb) || requirementExcludes(rb, ra)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requirementExcludes reports whether a node matching a cannot match b. Gt and Lt are
+// never judged exclusive.
+func requirementExcludes(a, b corev1.NodeSelectorRequirement) bool {
+	switch a.Operator {
+	case corev1.NodeSelectorOpIn:
+		switch b.Operator {
+		case corev1.NodeSelectorOpIn:
+			for _, v := range a.Values {
+				if slices.Contains(b.Values, v) {
+					return false
+				}
+			}
+			return true
+		case corev1.NodeSelectorOpNotIn:
+			for _, v := range a.Values {
+				if !slices.Contains(b.Values, v) {
+					return false
+				}
+			}
+			return true
+		case corev1.NodeSelectorOpDoesNotExist:
+			return true
+		}
+	case corev1.NodeSelectorOpExists:
+		return b.Operator == corev1.NodeSelectorOpDoesNotExist
+	}
+	return false
+}
+
+// instrumentedNamespaces returns the namespaces explicitly listed by the
+// enabled instrumentation targets, and whether some target selects every
+// namespace (no matchNames, matchLabels or matchExpressions).
+func (in *WhatapAgent) instrumentedNamespaces() (map[string]bool, bool) {
+	names := map[string]bool{}
+	inst := in.Spec.Features.Apm.Instrumentation
+	if !inst.Enabled {
+		return names, false
+	}
+	all := false
+	for _, t := range inst.Targets {
+		if !t.Enabled {
+			continue
+		}
+		sel := t.NamespaceSelector
+		if len(sel.MatchNames) == 0 && len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0 {
+			all = true
+		}
+		for _, n := range sel.MatchNames {
+			names[n] = true
+		}
+	}
+	return names, all
+}
+
+// OverlappingNamespaces returns the namespaces both instances instrument by
+// name ("*" when both select every namespace). Label based namespace
+// selectors cannot be compared statically and are resolved at admission time.
+func OverlappingNamespaces(a, b *WhatapAgent) []string {
+	an, aAll := a.instrumentedNamespaces()
+	bn, bAll := b.instrumentedNamespaces()
+	if aAll && bAll {
+		return []string{"*"}
+	}
+	var out []string
+	switch {
+	case aAll:
+		for n := range bn {
+			out = append(out, n)
+		}
+	case bAll:
+		for n := range an {
+			out = append(out, n)
+		}
+	default:
+		for n := range an {
+			if bn[n] {
+				out = append(out, n)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
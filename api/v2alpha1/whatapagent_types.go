@@ -34,8 +34,28 @@ type WhatapAgentSpec struct {
 	Host string `json:"host,omitempty"`
 	// Port for Whatap server
 	// +optional
-	Port     string       `json:"port,omitempty"`
-	Features FeaturesSpec `json:"features"`
+	Port string `json:"port,omitempty"`
+	// ImageRegistry replaces the registry of the operator's default images (APM init,
+	// kube_agent, open_agent, dcgm-exporter and the DCGM host engine), e.g. a mirror such as
+	// "harbor.example.com/ecr-proxy". The repository path is kept:
+	// public.ecr.aws/whatap/kube_agent:1.0 -> harbor.example.com/ecr-proxy/whatap/kube_agent:1.0.
+	// Images given as custom image names are used as is.
+	// +optional
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+	// ImagePullPolicy applies to every image the operator deploys or injects. Defaults to
+	// Always for APM init images and IfNotPresent for agent images.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// PinImageDigests resolves the tag of every APM init image to its digest when the
+	// WhatapAgent changes (recorded in status.imageDigests) and injects image@sha256:...,
+	// so every pod of a workload gets the same agent even for tags like "latest".
+	// Images whose digest cannot be resolved are injected by tag. Registries are read
+	// anonymously unless the operator's REGISTRY_AUTH_SECRETS env names docker config
+	// Secrets (e.g. image pull secrets) in the operator namespace holding their credentials.
+	// +optional
+	PinImageDigests bool         `json:"pinImageDigests,omitempty"`
+	Features        FeaturesSpec `json:"features"`
 }
 
 type FeaturesSpec struct {
@@ -51,6 +71,22 @@ type OpenAgentSpec struct {
 	// Targets defines the list of targets to scrape metrics from
 	// +optional
 	Targets []OpenAgentTargetSpec `json:"targets,omitempty"`
+	// PrometheusOperatorCompat imports Prometheus Operator (monitoring.coreos.com/v1)
+	// ServiceMonitor, PodMonitor and Probe resources as scrape targets
+	// +optional
+	PrometheusOperatorCompat *PrometheusOperatorCompatSpec `json:"prometheusOperatorCompat,omitempty"`
+	// AnnotationDiscovery scrapes the pods and services annotated with
+	// prometheus.io/scrape: "true", without monitor CRs
+	// +optional
+	AnnotationDiscovery *AnnotationDiscoverySpec `json:"annotationDiscovery,omitempty"`
+	// Shards splits the targets across this many OpenAgent Deployments. Every target is
+	// scraped by exactly one shard, chosen by a hash of its address (and of the target
+	// parameter of probes). Changing the count stops every shard before the shards of the
+	// new count start, so targets are not scraped for a few seconds but never twice.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Shards int32 `json:"shards,omitempty"`
 	// ImageName defines the name of the OpenAgent image to use
 	// +optional
 	ImageName string `json:"imageName,omitempty"`
@@ -104,6 +140,69 @@ type OpenAgentSpec struct {
 	DisableForeground bool `json:"disableForeground,omitempty"`
 }
 
+// PrometheusOperatorCompatSpec selects the Prometheus Operator resources the OpenAgent
+// scrapes. They are translated like the WhatapServiceMonitor, WhatapPodMonitor and
+// WhatapStaticEndpoint (Probe) CRs; a WhaTap CR of the same namespace/name takes
+// precedence. Kinds whose CRD is installed after the operator started are picked up
+// on the next operator restart.
+type PrometheusOperatorCompatSpec struct {
+	// +kubebuilder:default=false
+	Enabled bool `json:"enabled"`
+	// Kinds limits the imported kinds (default: ServiceMonitor, PodMonitor and Probe)
+	// +optional
+	Kinds []PrometheusOperatorKind `json:"kinds,omitempty"`
+	// NamespaceSelector selects the namespaces resources are imported from (default: all)
+	// +optional
+	NamespaceSelector *NamespaceSelector `json:"namespaceSelector,omitempty"`
+	// Selector selects the resources to import by their labels (default: all). Resources
+	// labelled monitoring.whatap.com/instance are only imported by that instance.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// AnnotationDiscoverySpec configures the discovery of scrape targets by the
+// prometheus.io/scrape, prometheus.io/port, prometheus.io/path and prometheus.io/scheme
+// annotations. Pods and services are discovered in separate targets named
+// "annotations/pods" and "annotations/services".
+type AnnotationDiscoverySpec struct {
+	// +kubebuilder:default=false
+	Enabled bool `json:"enabled"`
+	// Roles selects what is discovered (default: Pod and Service)
+	// +optional
+	Roles []AnnotationDiscoveryRole `json:"roles,omitempty"`
+	// NamespaceSelector selects the namespaces to discover in (default: all)
+	// +optional
+	NamespaceSelector *NamespaceSelector `json:"namespaceSelector,omitempty"`
+	// ExcludeNamespaces are never discovered in, even when selected
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// Interval is the scrape interval of the discovered targets
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// MetricRelabelConfigs are applied to the metrics of the discovered targets
+	// +optional
+	MetricRelabelConfigs []MetricRelabelConfig `json:"metricRelabelConfigs,omitempty"`
+}
+
+// AnnotationDiscoveryRole is a kind of object discovered by its annotations
+// +kubebuilder:validation:Enum=Pod;Service
+type AnnotationDiscoveryRole string
+
+const (
+	AnnotationDiscoveryPod     AnnotationDiscoveryRole = "Pod"
+	AnnotationDiscoveryService AnnotationDiscoveryRole = "Service"
+)
+
+// PrometheusOperatorKind is a monitoring.coreos.com/v1 kind the OpenAgent can import
+// +kubebuilder:validation:Enum=ServiceMonitor;PodMonitor;Probe
+type PrometheusOperatorKind string
+
+const (
+	PrometheusOperatorServiceMonitor PrometheusOperatorKind = "ServiceMonitor"
+	PrometheusOperatorPodMonitor     PrometheusOperatorKind = "PodMonitor"
+	PrometheusOperatorProbe          PrometheusOperatorKind = "Probe"
+)
+
 // OpenAgentTargetSpec defines a target for the OpenAgent to scrape metrics from
 type OpenAgentTargetSpec struct {
 	// TargetName is the name of the target
@@ -290,8 +389,11 @@ type K8sAgentSpec struct {
 	NodeAgent           NodeAgentComponentSpec        `json:"nodeAgent"`
 	GpuMonitoring       GpuMonitoringSpec             `json:"gpuMonitoring"`
 	ApiserverMonitoring AgentComponentSpec            `json:"apiserverMonitoring,omitempty"`
-	EtcdMonitoring      AgentComponentSpec            `json:"etcdMonitoring,omitempty"`
+	EtcdMonitoring      EtcdMonitoringSpec            `json:"etcdMonitoring,omitempty"`
 	SchedulerMonitoring AgentComponentSpec            `json:"schedulerMonitoring,omitempty"`
+	// ControllerManagerMonitoring scrapes kube-controller-manager static pods on their secure port
+	// +optional
+	ControllerManagerMonitoring AgentComponentSpec `json:"controllerManagerMonitoring,omitempty"`
 }
 
 type MasterAgentComponentSpec struct {
@@ -433,6 +535,26 @@ type AgentComponentSpec struct {
 	CustomImageFullName string `json:"customImageFullName,omitempty"`
 }
 
+// EtcdMonitoringSpec defines etcd monitoring specific settings.
+// etcd static pods (component=etcd in kube-system) are discovered and scraped over mTLS.
+type EtcdMonitoringSpec struct {
+	AgentComponentSpec `json:",inline"`
+	// Port is the etcd client port that serves /metrics
+	// +kubebuilder:default="2379"
+	// +optional
+	Port string `json:"port,omitempty"`
+	// TLSConfig references the CA and client certificate Secrets used to scrape etcd.
+	// Secrets must live in the operator namespace so they can be mounted.
+	// When no Secret is referenced, certificates are read from CertHostPath on control-plane nodes.
+	// +optional
+	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+	// CertHostPath is the control-plane host directory holding ca.crt,
+	// healthcheck-client.crt and healthcheck-client.key (kubeadm layout)
+	// +kubebuilder:default="/etc/kubernetes/pki/etcd"
+	// +optional
+	CertHostPath string `json:"certHostPath,omitempty"`
+}
+
 // GpuMonitoringSpec defines GPU monitoring specific settings
 type GpuMonitoringSpec struct {
 	// +kubebuilder:default=false
@@ -555,6 +677,13 @@ type InitContainerSecuritySpec struct {
 type InstrumentationSpec struct {
 	// +kubebuilder:default=true
 	Enabled bool `json:"enabled,omitempty"`
+	// Mode "uninstrument" removes the agent from every target: matched workloads are
+	// restarted and new pods are created without (and stripped of) injected artifacts.
+	// Keep enabled=true while uninstrumenting so the webhook still cleans up pods.
+	// +kubebuilder:validation:Enum=inject;uninstrument
+	// +kubebuilder:default=inject
+	// +optional
+	Mode string `json:"mode,omitempty"`
 	// Controls security context of injected initContainers. If unset, defaults apply.
 	// +optional
 	InitContainerSecurity *InitContainerSecuritySpec `json:"initContainerSecurity,omitempty"`
@@ -562,16 +691,116 @@ type InstrumentationSpec struct {
 	// Can be overridden per-target. If unset, no resource requirements are applied.
 	// +optional
 	InitContainerResources *corev1.ResourceRequirements `json:"initContainerResources,omitempty"`
+	// RestartPolicy controls whether workloads are restarted when their target changes.
+	// If unset, existing pods keep the agent they were started with until restarted manually.
+	// +optional
+	RestartPolicy *RestartPolicySpec `json:"restartPolicy,omitempty"`
+	// Webhook configures how the API server calls the pod injection webhook (mpod.kb.io).
+	// The webhook configuration is shared by every instance; the oldest instance with
+	// instrumentation enabled provides these settings.
+	// +optional
+	Webhook *InjectionWebhookSpec `json:"webhook,omitempty"`
 	// +optional
 	Targets []TargetSpec `json:"targets,omitempty"`
 }
 
+// InjectionWebhookSpec configures the MutatingWebhookConfiguration entry for pod injection.
+// Besides excludeNamespaces, the operator derives a namespaceSelector from the targets'
+// namespaceSelectors whenever their union can be expressed as a single label selector
+// (e.g. every target uses matchNames), so that only pods in target namespaces are sent to
+// the webhook. Targets in mode "uninstrument" and namespaces still holding injected pods
+// stay selected until those pods are gone. Per-pod opt-in annotations (apm.whatap.com/inject-<language>) then only
+// take effect inside those namespaces.
+type InjectionWebhookSpec struct {
+	// FailurePolicy defines how API server errors calling the webhook are handled.
+	// "Fail" rejects pod creation while the webhook is unavailable.
+	// +kubebuilder:validation:Enum=Ignore;Fail
+	// +kubebuilder:default=Ignore
+	// +optional
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is how long the API server waits for the webhook (1-30, default 10)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// ExcludeNamespaces are never sent to the webhook. Defaults to ["kube-system"]; the
+	// operator namespace is always excluded.
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// ObjectSelector restricts the webhook to pods whose labels match
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+}
+
+// RestartPolicySpec configures automatic rollout restarts of instrumented workloads
+type RestartPolicySpec struct {
+	// Mode selects when workloads are restarted. "onChange" restarts the Deployments,
+	// StatefulSets and DaemonSets matched by a target whenever that target is added or modified.
+	// Like the webhook, it skips the excluded namespaces and the workloads injected by an
+	// older instance.
+	// +kubebuilder:validation:Enum=never;onChange
+	// +kubebuilder:default=never
+	// +optional
+	Mode string `json:"mode,omitempty"`
+	// MaxConcurrent is the number of workloads restarted per batch
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MaxConcurrent int32 `json:"maxConcurrent,omitempty"`
+	// MinInterval is the minimum time between two restart batches (e.g. "30s", "2m")
+	// +kubebuilder:default="1m"
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+}
+
 type TargetSpec struct {
 	Name     string `json:"name"`
 	Enabled  bool   `json:"enabled"`  // +kubebuilder:default=true
 	Language string `json:"language"` // +kubebuilder:validation:Enum=java;python;php;dotnet;nodejs;golang
+	// Mode "uninstrument" removes the agent from the workloads matched by this target
+	// (see InstrumentationSpec.Mode)
+	// +kubebuilder:validation:Enum=inject;uninstrument
+	// +kubebuilder:default=inject
+	// +optional
+	Mode string `json:"mode,omitempty"`
 	// +optional
 	WhatapApmVersions map[string]string `json:"whatapApmVersions,omitempty"`
+	// AppNameTemplate names the application of every instrumented container, as a Go
+	// template resolved when the pod is admitted, e.g. "{{.Namespace}}-{{.OwnerName}}".
+	// Fields: .Namespace, .OwnerKind and .OwnerName (the workload owning the pod: a
+	// ReplicaSet resolves to its Deployment, a pod without owner to its generateName),
+	// .PodName (usually empty at admission), .ContainerName, .Language, .Labels and
+	// .Annotations (e.g. {{index .Labels "app"}}). The result is set as app_name
+	// (whatap.name for Java, OTEL_SERVICE_NAME for agentType "otel") unless the container
+	// or envs already set it. For Python and Node.js the name of the first instrumented
+	// container is also written into whatap.conf.
+	// +optional
+	AppNameTemplate string `json:"appNameTemplate,omitempty"`
+	// AgentType selects the agent injected into the target's containers. "whatap" injects
+	// the WhaTap APM agent. "otel" injects OpenTelemetry auto-instrumentation (java,
+	// python and nodejs targets) exporting OTLP to Otel.Endpoint; whatapApmVersions,
+	// config, pythonStartMode and runtimeCheck do not apply.
+	// +kubebuilder:validation:Enum=whatap;otel
+	// +kubebuilder:default=whatap
+	// +optional
+	AgentType string `json:"agentType,omitempty"`
+	// Otel configures the OpenTelemetry auto-instrumentation of agentType "otel"
+	// +optional
+	Otel *OtelSpec `json:"otel,omitempty"`
+	// PythonStartMode selects how the Python agent is started (python targets only).
+	// "sitecustomize" loads the agent through PYTHONPATH. "wrap" also runs the container
+	// command through whatap-start-agent, for apps that bypass sitecustomize (python -I,
+	// gunicorn/uwsgi resetting PYTHONPATH). Containers without a command run the image
+	// ENTRYPOINT/CMD, taken from the apm.whatap.com/entrypoint and apm.whatap.com/cmd
+	// pod annotations (JSON arrays) or read from the registry.
+	// +kubebuilder:validation:Enum=sitecustomize;wrap
+	// +kubebuilder:default=sitecustomize
+	// +optional
+	PythonStartMode string `json:"pythonStartMode,omitempty"`
+	// RuntimeCheck disables the agent in containers whose runtime version is outside the
+	// supported range (java, python and nodejs targets), instead of letting them crash.
+	// +optional
+	RuntimeCheck *RuntimeCheckSpec `json:"runtimeCheck,omitempty"`
 	// CustomImageFullName allows specifying a full custom image name (including repository and tag) for the APM init image
 	// If provided, this takes precedence over CustomImageName and the default image format
 	// +optional
@@ -589,7 +818,11 @@ type TargetSpec struct {
 	Envs              []corev1.EnvVar   `json:"envs,omitempty"`
 	NamespaceSelector NamespaceSelector `json:"namespaceSelector,omitempty"`
 	PodSelector       PodSelector       `json:"podSelector,omitempty"`
-	Config            ConfigSpec        `json:"config,omitempty"`
+	// ContainerSelector restricts injection to matching containers of the selected pods.
+	// If unset, every container except well-known sidecars is instrumented.
+	// +optional
+	ContainerSelector *ContainerSelector `json:"containerSelector,omitempty"`
+	Config            ConfigSpec         `json:"config,omitempty"`
 	// Controls security context of the injected initContainer for this target (overrides instrumentation-level settings)
 	// +optional
 	InitContainerSecurity *InitContainerSecuritySpec `json:"initContainerSecurity,omitempty"`
@@ -601,6 +834,66 @@ type TargetSpec struct {
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 }
 
+// RuntimeCheckSpec declares the runtime versions the agent of a target supports.
+//
+// An init container running the application image detects the runtime version (java
+// -version, python -V, node -v) and reports it in its termination message. The
+// application command is wrapped by a shell that starts the application without the
+// agent when the version is out of range, and the operator records a warning event on
+// the pod. The check and the wrapper run with Shell and need sed in the application
+// image; targets whose images have no shell, e.g. distroless images, set Shell to "none"
+// and get the agent without the check. Containers without a command need their image
+// ENTRYPOINT/CMD (see PythonStartMode).
+type RuntimeCheckSpec struct {
+	// Enabled turns the check on
+	Enabled bool `json:"enabled"`
+	// MinVersion is the oldest supported runtime version, e.g. "8" (or "1.8") for Java,
+	// "3.7" for Python, "14" for Node.js
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)*$`
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+	// MaxVersion is the newest supported runtime version, compared on its components:
+	// "3.12" allows every 3.12.x
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)*$`
+	// +optional
+	MaxVersion string `json:"maxVersion,omitempty"`
+	// Resources of the check init containers, which start the runtime of the application
+	// image (e.g. a JVM). Defaults to a limit of 500m CPU and 512Mi memory.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Shell is the POSIX shell of the application images the check and the wrapper run
+	// with. "none" skips the check for images without a shell.
+	// +kubebuilder:default="/bin/sh"
+	// +optional
+	Shell string `json:"shell,omitempty"`
+}
+
+// OtelSpec configures OpenTelemetry auto-instrumentation.
+//
+// The init container copies the auto-instrumentation of the OpenTelemetry Operator image
+// (or customImageFullName, which must use the same layout: /javaagent.jar for Java,
+// /autoinstrumentation for Python and Node.js) into the agent volume. OTEL_SERVICE_NAME
+// defaults to the name of the workload owning the pod, and OTEL_RESOURCE_ATTRIBUTES
+// describes the namespace, workload, pod, node, container and pod labels. Values set in
+// the container or in envs take precedence.
+type OtelSpec struct {
+	// Endpoint is the OTLP endpoint of the WhaTap-compatible collector, e.g.
+	// "http://whatap-otel-collector.whatap-monitoring:4318"
+	// +kubebuilder:validation:MinLength=1
+	Endpoint string `json:"endpoint"`
+	// Protocol is the OTLP protocol. Python only supports http/protobuf.
+	// +kubebuilder:validation:Enum=grpc;http/protobuf
+	// +kubebuilder:default="http/protobuf"
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+	// Version is the tag of the auto-instrumentation image (default "latest")
+	// +optional
+	Version string `json:"version,omitempty"`
+	// ResourceAttributes are added to OTEL_RESOURCE_ATTRIBUTES of every instrumented container
+	// +optional
+	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
+}
+
 // NamespaceSelector matches specific namespaces
 type NamespaceSelector struct {
 	// matchNames is a list of namespace names to include
@@ -624,6 +917,24 @@ type PodSelector struct {
 	MatchExpressions []LabelSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
+// ContainerSelector matches containers within a pod. Every field that is set must match
+// (the requirements are ANDed). Well-known sidecars (istio-proxy, linkerd-proxy,
+// vault-agent, fluent-bit) are skipped unless listed in names or includeSidecars is set.
+type ContainerSelector struct {
+	// names is a list of container names to instrument
+	// +optional
+	Names []string `json:"names,omitempty"`
+	// nameRegex is a regular expression (RE2) the container name must match
+	// +optional
+	NameRegex string `json:"nameRegex,omitempty"`
+	// imagePatterns is a list of glob patterns (e.g. "*/my-app:*"); the container image must match one of them
+	// +optional
+	ImagePatterns []string `json:"imagePatterns,omitempty"`
+	// includeSidecars disables the automatic skipping of well-known sidecar containers
+	// +optional
+	IncludeSidecars bool `json:"includeSidecars,omitempty"`
+}
+
 // A label selector requirement is a selector that contains values, a key, and an operator that
 // relates the key and values.
 type LabelSelectorRequirement struct {
@@ -684,11 +995,111 @@ type WhatapAgentStatus struct {
 	// ObservedGeneration represents the .metadata.generation that the status was set based on.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Rollout reports the progress of restarts triggered by instrumentation.restartPolicy
+	// +optional
+	Rollout *InstrumentationRolloutStatus `json:"rollout,omitempty"`
+
+	// Instrumentation summarizes the pods matched by each instrumentation target
+	// +optional
+	Instrumentation *InstrumentationStatus `json:"instrumentation,omitempty"`
+
+	// ImageDigests records the digests APM init images are pinned to (spec.pinImageDigests)
+	// +optional
+	ImageDigests *ImageDigestStatus `json:"imageDigests,omitempty"`
+}
+
+// ImageDigestStatus records the digest each APM init image tag resolved to
+type ImageDigestStatus struct {
+	// ObservedGeneration is the generation the digests were resolved for. Tags are
+	// resolved again whenever the WhatapAgent spec changes.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Digests maps image references (registry/repository:tag) to their digest (sha256:...)
+	// +optional
+	Digests map[string]string `json:"digests,omitempty"`
+	// Failed lists the images whose digest could not be resolved; they are injected by tag
+	// +optional
+	Failed []string `json:"failed,omitempty"`
+}
+
+// InstrumentationStatus summarizes APM injection results, built from the
+// labels and annotations the injection webhook puts on pods.
+type InstrumentationStatus struct {
+	// Targets holds one entry per target that matched at least one running pod
+	// +optional
+	Targets []TargetInstrumentationStatus `json:"targets,omitempty"`
+	// LastUpdateTime is when the summary was last recomputed
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// InstrumentationCounts are pod counts for a target or one of its namespaces
+type InstrumentationCounts struct {
+	// Matched is the number of pods matched by the target (Injected + Skipped)
+	Matched int32 `json:"matched"`
+	// Injected is the number of pods running with the agent injected
+	Injected int32 `json:"injected"`
+	// Skipped is the number of matched pods that opted out or had no container selected
+	Skipped int32 `json:"skipped"`
+	// Outdated is the number of injected pods running an agent version other than the desired one
+	Outdated int32 `json:"outdated"`
+}
+
+// TargetInstrumentationStatus is the injection summary of one target
+type TargetInstrumentationStatus struct {
+	// Name is the target name
+	Name string `json:"name"`
+	// Language is the target language
+	Language string `json:"language,omitempty"`
+	// DesiredVersion is the agent version the target currently injects
+	// +optional
+	DesiredVersion string `json:"desiredVersion,omitempty"`
+
+	InstrumentationCounts `json:",inline"`
+
+	// Versions maps each running agent version to its number of pods
+	// +optional
+	Versions map[string]int32 `json:"versions,omitempty"`
+	// Namespaces breaks the counts down per namespace
+	// +optional
+	Namespaces []NamespaceInstrumentationStatus `json:"namespaces,omitempty"`
+	// OutdatedPods lists up to 10 pods ("namespace/name") still running an outdated agent version
+	// +optional
+	OutdatedPods []string `json:"outdatedPods,omitempty"`
+}
+
+// NamespaceInstrumentationStatus is the injection summary of a target in one namespace
+type NamespaceInstrumentationStatus struct {
+	// Namespace is the namespace name
+	Namespace string `json:"namespace"`
+
+	InstrumentationCounts `json:",inline"`
+}
+
+// InstrumentationRolloutStatus tracks rollout restarts of workloads matched by changed targets
+type InstrumentationRolloutStatus struct {
+	// TargetHashes records, per "instance/target" key, the hash of the target spec workloads
+	// are rolled to. The entry keyed by the instance name alone marks the baseline.
+	// +optional
+	TargetHashes map[string]string `json:"targetHashes,omitempty"`
+	// Pending lists the workloads still waiting for a restart, as "Kind/namespace/name"
+	// +optional
+	Pending []string `json:"pending,omitempty"`
+	// Restarted is the number of workloads restarted since the last target change
+	// +optional
+	Restarted int32 `json:"restarted,omitempty"`
+	// LastRestartTime is when the last restart batch was triggered
+	// +optional
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // WhatapAgent is the Schema for the whatapagents API
 type WhatapAgent struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -43,12 +43,30 @@ type WhatapServiceMonitorSpec struct {
 
 // WhatapServiceMonitorStatus defines the observed state of WhatapServiceMonitor
 type WhatapServiceMonitorStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the generation of the spec the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions: Accepted (the monitor is part of the OpenAgent scrape config) and
+	// Invalid (the monitor cannot be scraped as configured, see the reason)
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Instance is the WhatapAgent whose OpenAgent handles the monitor
+	// +optional
+	Instance string `json:"instance,omitempty"`
+	// TargetName is the name of the monitor's target in the OpenAgent scrape config
+	// +optional
+	TargetName string `json:"targetName,omitempty"`
+	// MatchedServices is the number of services selected by the monitor
+	// +optional
+	MatchedServices int32 `json:"matchedServices"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Accepted",type=string,JSONPath=`.status.conditions[?(@.type=="Accepted")].status`
+//+kubebuilder:printcolumn:name="Invalid",type=string,JSONPath=`.status.conditions[?(@.type=="Invalid")].reason`
+//+kubebuilder:printcolumn:name="Services",type=integer,JSONPath=`.status.matchedServices`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // WhatapServiceMonitor is the Schema for the whatapservicemonitors API
 type WhatapServiceMonitor struct {
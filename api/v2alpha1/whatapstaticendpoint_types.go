@@ -39,12 +39,30 @@ type WhatapStaticEndpointSpec struct {
 
 // WhatapStaticEndpointStatus defines the observed state of WhatapStaticEndpoint
 type WhatapStaticEndpointStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the generation of the spec the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions: Accepted (the monitor is part of the OpenAgent scrape config) and
+	// Invalid (the monitor cannot be scraped as configured, see the reason)
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Instance is the WhatapAgent whose OpenAgent handles the monitor
+	// +optional
+	Instance string `json:"instance,omitempty"`
+	// TargetName is the name of the monitor's target in the OpenAgent scrape config
+	// +optional
+	TargetName string `json:"targetName,omitempty"`
+	// MatchedEndpoints is the number of endpoints with an address
+	// +optional
+	MatchedEndpoints int32 `json:"matchedEndpoints"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Accepted",type=string,JSONPath=`.status.conditions[?(@.type=="Accepted")].status`
+//+kubebuilder:printcolumn:name="Invalid",type=string,JSONPath=`.status.conditions[?(@.type=="Invalid")].reason`
+//+kubebuilder:printcolumn:name="Endpoints",type=integer,JSONPath=`.status.matchedEndpoints`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // WhatapStaticEndpoint is the Schema for the whatapstaticendpoints API
 type WhatapStaticEndpoint struct {
@@ -41,6 +41,43 @@ func (in *AgentComponentSpec) DeepCopy() *AgentComponentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnnotationDiscoverySpec) DeepCopyInto(out *AnnotationDiscoverySpec) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]AnnotationDiscoveryRole, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(NamespaceSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetricRelabelConfigs != nil {
+		in, out := &in.MetricRelabelConfigs, &out.MetricRelabelConfigs
+		*out = make([]MetricRelabelConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnnotationDiscoverySpec.
+func (in *AnnotationDiscoverySpec) DeepCopy() *AnnotationDiscoverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AnnotationDiscoverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApmSpec) DeepCopyInto(out *ApmSpec) {
 	*out = *in
@@ -142,6 +179,31 @@ func (in *ConfigSpec) DeepCopy() *ConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerSelector) DeepCopyInto(out *ContainerSelector) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePatterns != nil {
+		in, out := &in.ImagePatterns, &out.ImagePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerSelector.
+func (in *ContainerSelector) DeepCopy() *ContainerSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerSpec) DeepCopyInto(out *ContainerSpec) {
 	*out = *in
@@ -186,6 +248,27 @@ func (in *DcgmHostEngineSpec) DeepCopy() *DcgmHostEngineSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdMonitoringSpec) DeepCopyInto(out *EtcdMonitoringSpec) {
+	*out = *in
+	out.AgentComponentSpec = in.AgentComponentSpec
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdMonitoringSpec.
+func (in *EtcdMonitoringSpec) DeepCopy() *EtcdMonitoringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdMonitoringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeaturesSpec) DeepCopyInto(out *FeaturesSpec) {
 	*out = *in
@@ -278,6 +361,33 @@ func (in *GpuMonitoringSpec) DeepCopy() *GpuMonitoringSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageDigestStatus) DeepCopyInto(out *ImageDigestStatus) {
+	*out = *in
+	if in.Digests != nil {
+		in, out := &in.Digests, &out.Digests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Failed != nil {
+		in, out := &in.Failed, &out.Failed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageDigestStatus.
+func (in *ImageDigestStatus) DeepCopy() *ImageDigestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageDigestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InitContainerSecuritySpec) DeepCopyInto(out *InitContainerSecuritySpec) {
 	*out = *in
@@ -303,6 +413,82 @@ func (in *InitContainerSecuritySpec) DeepCopy() *InitContainerSecuritySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectionWebhookSpec) DeepCopyInto(out *InjectionWebhookSpec) {
+	*out = *in
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectionWebhookSpec.
+func (in *InjectionWebhookSpec) DeepCopy() *InjectionWebhookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectionWebhookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstrumentationCounts) DeepCopyInto(out *InstrumentationCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstrumentationCounts.
+func (in *InstrumentationCounts) DeepCopy() *InstrumentationCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(InstrumentationCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstrumentationRolloutStatus) DeepCopyInto(out *InstrumentationRolloutStatus) {
+	*out = *in
+	if in.TargetHashes != nil {
+		in, out := &in.TargetHashes, &out.TargetHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastRestartTime != nil {
+		in, out := &in.LastRestartTime, &out.LastRestartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstrumentationRolloutStatus.
+func (in *InstrumentationRolloutStatus) DeepCopy() *InstrumentationRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstrumentationRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstrumentationSpec) DeepCopyInto(out *InstrumentationSpec) {
 	*out = *in
@@ -316,6 +502,16 @@ func (in *InstrumentationSpec) DeepCopyInto(out *InstrumentationSpec) {
 		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RestartPolicy != nil {
+		in, out := &in.RestartPolicy, &out.RestartPolicy
+		*out = new(RestartPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(InjectionWebhookSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Targets != nil {
 		in, out := &in.Targets, &out.Targets
 		*out = make([]TargetSpec, len(*in))
@@ -335,6 +531,32 @@ func (in *InstrumentationSpec) DeepCopy() *InstrumentationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstrumentationStatus) DeepCopyInto(out *InstrumentationStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]TargetInstrumentationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstrumentationStatus.
+func (in *InstrumentationStatus) DeepCopy() *InstrumentationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstrumentationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K8sAgentSpec) DeepCopyInto(out *K8sAgentSpec) {
 	*out = *in
@@ -347,8 +569,9 @@ func (in *K8sAgentSpec) DeepCopyInto(out *K8sAgentSpec) {
 	in.NodeAgent.DeepCopyInto(&out.NodeAgent)
 	in.GpuMonitoring.DeepCopyInto(&out.GpuMonitoring)
 	out.ApiserverMonitoring = in.ApiserverMonitoring
-	out.EtcdMonitoring = in.EtcdMonitoring
+	in.EtcdMonitoring.DeepCopyInto(&out.EtcdMonitoring)
 	out.SchedulerMonitoring = in.SchedulerMonitoring
+	out.ControllerManagerMonitoring = in.ControllerManagerMonitoring
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sAgentSpec.
@@ -486,6 +709,22 @@ func (in *MetricRelabelConfig) DeepCopy() *MetricRelabelConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceInstrumentationStatus) DeepCopyInto(out *NamespaceInstrumentationStatus) {
+	*out = *in
+	out.InstrumentationCounts = in.InstrumentationCounts
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceInstrumentationStatus.
+func (in *NamespaceInstrumentationStatus) DeepCopy() *NamespaceInstrumentationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceInstrumentationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespaceSelector) DeepCopyInto(out *NamespaceSelector) {
 	*out = *in
@@ -678,6 +917,16 @@ func (in *OpenAgentSpec) DeepCopyInto(out *OpenAgentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PrometheusOperatorCompat != nil {
+		in, out := &in.PrometheusOperatorCompat, &out.PrometheusOperatorCompat
+		*out = new(PrometheusOperatorCompatSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AnnotationDiscovery != nil {
+		in, out := &in.AnnotationDiscovery, &out.AnnotationDiscovery
+		*out = new(AnnotationDiscoverySpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -785,6 +1034,28 @@ func (in *OpenAgentTargetSpec) DeepCopy() *OpenAgentTargetSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OtelSpec) DeepCopyInto(out *OtelSpec) {
+	*out = *in
+	if in.ResourceAttributes != nil {
+		in, out := &in.ResourceAttributes, &out.ResourceAttributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OtelSpec.
+func (in *OtelSpec) DeepCopy() *OtelSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OtelSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodSelector) DeepCopyInto(out *PodSelector) {
 	*out = *in
@@ -814,6 +1085,76 @@ func (in *PodSelector) DeepCopy() *PodSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusOperatorCompatSpec) DeepCopyInto(out *PrometheusOperatorCompatSpec) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]PrometheusOperatorKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(NamespaceSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusOperatorCompatSpec.
+func (in *PrometheusOperatorCompatSpec) DeepCopy() *PrometheusOperatorCompatSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusOperatorCompatSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestartPolicySpec) DeepCopyInto(out *RestartPolicySpec) {
+	*out = *in
+	if in.MinInterval != nil {
+		in, out := &in.MinInterval, &out.MinInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestartPolicySpec.
+func (in *RestartPolicySpec) DeepCopy() *RestartPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RestartPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeCheckSpec) DeepCopyInto(out *RuntimeCheckSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeCheckSpec.
+func (in *RuntimeCheckSpec) DeepCopy() *RuntimeCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
 	*out = *in
@@ -859,6 +1200,39 @@ func (in *TLSConfig) DeepCopy() *TLSConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetInstrumentationStatus) DeepCopyInto(out *TargetInstrumentationStatus) {
+	*out = *in
+	out.InstrumentationCounts = in.InstrumentationCounts
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceInstrumentationStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.OutdatedPods != nil {
+		in, out := &in.OutdatedPods, &out.OutdatedPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetInstrumentationStatus.
+func (in *TargetInstrumentationStatus) DeepCopy() *TargetInstrumentationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetInstrumentationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetSpec) DeepCopyInto(out *TargetSpec) {
 	*out = *in
@@ -869,6 +1243,16 @@ func (in *TargetSpec) DeepCopyInto(out *TargetSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Otel != nil {
+		in, out := &in.Otel, &out.Otel
+		*out = new(OtelSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RuntimeCheck != nil {
+		in, out := &in.RuntimeCheck, &out.RuntimeCheck
+		*out = new(RuntimeCheckSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AdditionalArgs != nil {
 		in, out := &in.AdditionalArgs, &out.AdditionalArgs
 		*out = make(map[string]string, len(*in))
@@ -885,6 +1269,11 @@ func (in *TargetSpec) DeepCopyInto(out *TargetSpec) {
 	}
 	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
 	in.PodSelector.DeepCopyInto(&out.PodSelector)
+	if in.ContainerSelector != nil {
+		in, out := &in.ContainerSelector, &out.ContainerSelector
+		*out = new(ContainerSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Config.DeepCopyInto(&out.Config)
 	if in.InitContainerSecurity != nil {
 		in, out := &in.InitContainerSecurity, &out.InitContainerSecurity
@@ -998,6 +1387,21 @@ func (in *WhatapAgentStatus) DeepCopyInto(out *WhatapAgentStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(InstrumentationRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Instrumentation != nil {
+		in, out := &in.Instrumentation, &out.Instrumentation
+		*out = new(InstrumentationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = new(ImageDigestStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapAgentStatus.
@@ -1016,7 +1420,7 @@ func (in *WhatapPodMonitor) DeepCopyInto(out *WhatapPodMonitor) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapPodMonitor.
@@ -1107,6 +1511,13 @@ func (in *WhatapPodMonitorSpec) DeepCopy() *WhatapPodMonitorSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WhatapPodMonitorStatus) DeepCopyInto(out *WhatapPodMonitorStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapPodMonitorStatus.
@@ -1125,7 +1536,7 @@ func (in *WhatapServiceMonitor) DeepCopyInto(out *WhatapServiceMonitor) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapServiceMonitor.
@@ -1216,6 +1627,13 @@ func (in *WhatapServiceMonitorSpec) DeepCopy() *WhatapServiceMonitorSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WhatapServiceMonitorStatus) DeepCopyInto(out *WhatapServiceMonitorStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapServiceMonitorStatus.
@@ -1234,7 +1652,7 @@ func (in *WhatapStaticEndpoint) DeepCopyInto(out *WhatapStaticEndpoint) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapStaticEndpoint.
@@ -1319,6 +1737,13 @@ func (in *WhatapStaticEndpointSpec) DeepCopy() *WhatapStaticEndpointSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WhatapStaticEndpointStatus) DeepCopyInto(out *WhatapStaticEndpointStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WhatapStaticEndpointStatus.
@@ -17,16 +17,10 @@ limitations under the License.
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
+	"context"
 	"crypto/tls"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"flag"
-	"math/big"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -35,11 +29,16 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -49,7 +48,9 @@ import (
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	"github.com/whatap/whatap-operator/internal/config"
 	"github.com/whatap/whatap-operator/internal/controller"
+	"github.com/whatap/whatap-operator/internal/registry"
 	webhookmonitoringv2alpha1 "github.com/whatap/whatap-operator/internal/webhook/v2alpha1"
+	"github.com/whatap/whatap-operator/internal/webhookcert"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -67,55 +68,13 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
-// generateSelfSignedCert creates a CA and a server cert for "serviceName.ns.svc"
-func generateSelfSignedCert(serviceName, namespace string) (caCertPEM, caKeyPEM, serverCertPEM, serverKeyPEM []byte, err error) {
-	// 1) CA 키·인증서
-	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+// injectionLabelSelector selects pods carrying the injection webhook's label.
+func injectionLabelSelector() labels.Selector {
+	req, err := labels.NewRequirement(monitoringv2alpha1.InjectionLabelKey, selection.Exists, nil)
 	if err != nil {
-		return
+		panic(err)
 	}
-	caTpl := &x509.Certificate{
-		SerialNumber:          big.NewInt(time.Now().UnixNano()),
-		Subject:               pkix.Name{CommonName: "whatap-webhook-ca"},
-		NotBefore:             time.Now().Add(-time.Hour),
-		NotAfter:              time.Now().AddDate(1000, 0, 0),
-		KeyUsage:              x509.KeyUsageCertSign,
-		IsCA:                  true,
-		BasicConstraintsValid: true,
-	}
-	caDER, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
-	if err != nil {
-		return
-	}
-	caCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
-	caKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)})
-
-	// 2) 서버 키·인증서 (CA로 서명)
-	srvKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return
-	}
-	srvTpl := &x509.Certificate{
-		SerialNumber: big.NewInt(time.Now().UnixNano()),
-		Subject:      pkix.Name{CommonName: serviceName + "." + namespace + ".svc"},
-		NotBefore:    time.Now().Add(-time.Hour),
-		NotAfter:     time.Now().AddDate(1000, 0, 0),
-		KeyUsage:     x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		DNSNames: []string{
-			serviceName + "." + namespace + ".svc",
-			serviceName + "." + namespace + ".svc.cluster.local",
-		},
-	}
-	caCert, _ := x509.ParseCertificate(caDER)
-	serverDER, err := x509.CreateCertificate(rand.Reader, srvTpl, caCert, &srvKey.PublicKey, caKey)
-	if err != nil {
-		return
-	}
-	serverCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER})
-	serverKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(srvKey)})
-
-	return
+	return labels.NewSelector().Add(*req)
 }
 
 func main() {
@@ -194,54 +153,56 @@ func main() {
 	if !enableHTTP2 {
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
-	// 1) 인증서 한 번만 생성
-	caCert, caKey, serverPEM, serverKeyPEM, err := generateSelfSignedCert("whatap-admission-controller", defaultNS)
+	// 1) Webhook 인증서: self-signed(기본값, Secret에 CA 보관 + 만료 전 자동 교체) 또는 cert-manager
+	restConfig := ctrl.GetConfigOrDie()
+	certClient, err := client.New(restConfig, client.Options{Scheme: scheme})
 	if err != nil {
-		setupLog.Error(err, "unable to generate self-signed cert for webhook")
+		setupLog.Error(err, "unable to create client for webhook certificates")
 		os.Exit(1)
 	}
-
-	// 2) 디스크에 TLS용 cert/key 내려쓰기
-	certDir := "/etc/webhook/certs"
-	if err := os.MkdirAll(certDir, 0700); err != nil {
-		setupLog.Error(err, "unable to create cert directory", "path", certDir)
-		os.Exit(1)
+	certMode := config.GetWebhookCertMode()
+	if certMode == "" {
+		certMode = webhookcert.ModeSelfSigned
 	}
-
-	// CA bundle
-	caCertFile := filepath.Join(certDir, "ca.crt")
-	if err := os.WriteFile(caCertFile, caCert, 0o644); err != nil {
-		setupLog.Error(err, "Failed to write caCertFile file", "file", caCertFile)
+	if certMode != webhookcert.ModeSelfSigned && certMode != webhookcert.ModeCertManager {
+		setupLog.Error(nil, "invalid WEBHOOK_CERT_MODE, expected self-signed or cert-manager", "mode", certMode)
 		os.Exit(1)
 	}
-
-	caKeyFile := filepath.Join(certDir, "ca.key")
-	if err := os.WriteFile(caKeyFile, caKey, 0o644); err != nil {
-		setupLog.Error(err, "Failed to write caKeyFile file", "file", caKeyFile)
-		os.Exit(1)
+	certSecret := config.GetWebhookCertSecret()
+	if certSecret == "" {
+		certSecret = webhookcert.DefaultSecretName
+		if certMode == webhookcert.ModeCertManager {
+			certSecret = webhookcert.DefaultCertManagerSecretName
+		}
 	}
-
-	// server.crt
-	certFile := filepath.Join(certDir, "tls.crt")
-	if err := os.WriteFile(certFile, serverPEM, 0o600); err != nil {
-		setupLog.Error(err, "Failed to write serverCert file", "file", certFile)
-		os.Exit(1)
+	certDir := "/etc/webhook/certs"
+	certManager := &webhookcert.Manager{
+		Client:                   certClient,
+		Mode:                     certMode,
+		Namespace:                defaultNS,
+		ServiceName:              "whatap-admission-controller",
+		SecretName:               certSecret,
+		Issuer:                   config.GetWebhookCertIssuer(),
+		CertificateName:          "whatap-webhook-certificate",
+		CertDir:                  certDir,
+		WebhookConfigurationName: "whatap-webhook",
 	}
-
-	// server.key
-	keyFile := filepath.Join(certDir, "tls.key")
-	if err := os.WriteFile(keyFile, serverKeyPEM, 0o644); err != nil {
-		setupLog.Error(err, "Failed to write serverKey file", "file", keyFile)
+	ctx := ctrl.SetupSignalHandler()
+	initCtx, cancelInit := context.WithTimeout(ctx, 5*time.Minute)
+	err = certManager.Init(initCtx)
+	cancelInit()
+	if err != nil {
+		setupLog.Error(err, "unable to load webhook certificate", "mode", certMode, "secret", certSecret)
 		os.Exit(1)
 	}
 
-	// 3) Webhook 서버 생성 (파일 읽기)
+	// 2) Webhook 서버 생성 (certDir 파일이 바뀌면 재시작 없이 다시 읽음)
 	webhookServer := webhook.NewServer(webhook.Options{
 		Port:     9443,
 		CertDir:  certDir,
 		CertName: "tls.crt",
 		KeyName:  "tls.key",
-		TLSOpts:  tlsOpts, // 필요하다면 동적 갱신 콜백 포함
+		TLSOpts:  tlsOpts,
 	})
 
 	// Metrics endpoint is enabled in 'config/default/kustomization.yaml'. The Metrics options configure the server.
@@ -268,13 +229,20 @@ func main() {
 		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "19e8a60c.whatap.com",
+		// Only pods labelled by the injection webhook are cached; they feed
+		// status.instrumentation. Other pod lookups go through the API reader.
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Pod{}: {Label: injectionLabelSelector()},
+			},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -297,23 +265,61 @@ func main() {
 		Scheme:           mgr.GetScheme(),
 		Recorder:         mgr.GetEventRecorderFor("whatap-operator"),
 		DefaultNamespace: defaultNS,
-		WebhookCABundle:  caCert,
-		CaKey:            caKey,
-		ServerCert:       serverPEM,
-		ServerKey:        serverKeyPEM,
+		APIReader:        mgr.GetAPIReader(),
+		Certs:            certManager,
+		Registry:         registry.NewClient(registry.NewSecretKeychain(mgr.GetAPIReader(), defaultNS, config.GetRegistryAuthSecrets())),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "WhatapAgent")
 		os.Exit(1)
 	}
+	if err = (&controller.InstrumentationStatusReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "InstrumentationStatus")
+		os.Exit(1)
+	}
+	if err = (&controller.RuntimeCheckReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("whatap-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RuntimeCheck")
+		os.Exit(1)
+	}
+	if err = (&controller.MonitorStatusReconciler{
+		Client:           mgr.GetClient(),
+		APIReader:        mgr.GetAPIReader(),
+		Scheme:           mgr.GetScheme(),
+		DefaultNamespace: defaultNS,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MonitorStatus")
+		os.Exit(1)
+	}
 	// nolint:goconst
 	if config.GetEnableWebhooks() != "false" {
-		if err = webhookmonitoringv2alpha1.SetupWhatapAgentWebhookWithManager(mgr); err != nil {
+		// The injection dry-run exposes the targets of every WhatapAgent: it is only served
+		// behind the authentication and authorization of the secure metrics server
+		if !secureMetrics {
+			setupLog.Info("injection dry-run disabled: it requires --metrics-secure", "path", webhookmonitoringv2alpha1.DryRunPath)
+		}
+		if err = webhookmonitoringv2alpha1.SetupWhatapAgentWebhookWithManager(mgr, defaultNS, secureMetrics); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "WhatapAgent")
 			os.Exit(1)
 		}
+		if err = webhookmonitoringv2alpha1.SetupMonitorWebhooksWithManager(mgr, defaultNS); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Monitors")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
+	// Every replica keeps its webhook certificate in sync with the Secret
+	if err := mgr.Add(certManager); err != nil {
+		setupLog.Error(err, "unable to add webhook certificate manager")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -342,7 +348,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -13,6 +14,10 @@ type EnvConfig struct {
 	WhatapDefaultNamespace string
 	EnableWebhooks         string
 	DebugMode              string
+	WebhookCertMode        string
+	WebhookCertSecret      string
+	WebhookCertIssuer      string
+	RegistryAuthSecrets    string
 }
 
 var (
@@ -34,6 +39,10 @@ func GetEnvConfig() *EnvConfig {
 			WhatapDefaultNamespace: os.Getenv("WHATAP_DEFAULT_NAMESPACE"),
 			EnableWebhooks:         os.Getenv("ENABLE_WEBHOOKS"),
 			DebugMode:              debugVal,
+			WebhookCertMode:        os.Getenv("WEBHOOK_CERT_MODE"),
+			WebhookCertSecret:      os.Getenv("WEBHOOK_CERT_SECRET"),
+			WebhookCertIssuer:      os.Getenv("WEBHOOK_CERT_ISSUER"),
+			RegistryAuthSecrets:    os.Getenv("REGISTRY_AUTH_SECRETS"),
 		}
 	})
 	return envConfig
@@ -68,3 +77,30 @@ func GetEnableWebhooks() string {
 func GetDebugMode() string {
 	return GetEnvConfig().DebugMode
 }
+
+// GetWebhookCertMode returns the cached WEBHOOK_CERT_MODE value ("self-signed" or "cert-manager")
+func GetWebhookCertMode() string {
+	return GetEnvConfig().WebhookCertMode
+}
+
+// GetWebhookCertSecret returns the cached WEBHOOK_CERT_SECRET value
+func GetWebhookCertSecret() string {
+	return GetEnvConfig().WebhookCertSecret
+}
+
+// GetWebhookCertIssuer returns the cached WEBHOOK_CERT_ISSUER value ("Issuer/<name>" or "ClusterIssuer/<name>")
+func GetWebhookCertIssuer() string {
+	return GetEnvConfig().WebhookCertIssuer
+}
+
+// GetRegistryAuthSecrets returns the docker config Secrets named by the cached
+// REGISTRY_AUTH_SECRETS value (comma separated), used to read image metadata from registries
+func GetRegistryAuthSecrets() []string {
+	var names []string
+	for _, name := range strings.Split(GetEnvConfig().RegistryAuthSecrets, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"strings"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// annotationDiscoveryTargetNames are the target names of the annotation discovery, per role
+var annotationDiscoveryTargetNames = map[monitoringv2alpha1.AnnotationDiscoveryRole]string{
+	monitoringv2alpha1.AnnotationDiscoveryPod:     "annotations/pods",
+	monitoringv2alpha1.AnnotationDiscoveryService: "annotations/services",
+}
+
+// annotationDiscoveryTargets renders the OpenAgent targets of annotationDiscovery: one
+// PodMonitor / ServiceMonitor target per role that selects every object in the included
+// namespaces and keeps only those annotated with prometheus.io/scrape: "true". The
+// port, path and scheme annotations are applied by relabeling, the same way the
+// kubernetes-pods job of the Prometheus example configuration does.
+func annotationDiscoveryTargets(spec *monitoringv2alpha1.AnnotationDiscoverySpec) []map[string]interface{} {
+	if spec == nil || !spec.Enabled {
+		return nil
+	}
+	roles := spec.Roles
+	if len(roles) == 0 {
+		roles = []monitoringv2alpha1.AnnotationDiscoveryRole{monitoringv2alpha1.AnnotationDiscoveryPod, monitoringv2alpha1.AnnotationDiscoveryService}
+	}
+
+	// An empty namespaceSelector selects all namespaces
+	nsSelector := make(map[string]interface{})
+	if ns := spec.NamespaceSelector; ns != nil {
+		if len(ns.MatchNames) > 0 {
+			nsSelector["matchNames"] = ns.MatchNames
+		}
+		if len(ns.MatchLabels) > 0 {
+			nsSelector["matchLabels"] = ns.MatchLabels
+		}
+		if len(ns.MatchExpressions) > 0 {
+			matchExpressions := make([]interface{}, 0)
+			for _, expr := range ns.MatchExpressions {
+				exprMap := make(map[string]interface{})
+				exprMap["key"] = expr.Key
+				exprMap["operator"] = expr.Operator
+				exprMap["values"] = expr.Values
+				matchExpressions = append(matchExpressions, exprMap)
+			}
+			nsSelector["matchExpressions"] = matchExpressions
+		}
+	}
+
+	endpoints := convertEndpoints([]monitoringv2alpha1.OpenAgentEndpoint{{
+		Path:                 "/metrics",
+		Interval:             spec.Interval,
+		MetricRelabelConfigs: spec.MetricRelabelConfigs,
+	}})
+
+	var targets []map[string]interface{}
+	seen := make(map[monitoringv2alpha1.AnnotationDiscoveryRole]bool)
+	for _, role := range roles {
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+
+		targetType, meta, objectLabel := "PodMonitor", "__meta_kubernetes_pod", "pod"
+		if role == monitoringv2alpha1.AnnotationDiscoveryService {
+			targetType, meta, objectLabel = "ServiceMonitor", "__meta_kubernetes_service", "service"
+		}
+		annotation := meta + "_annotation_prometheus_io_"
+
+		relabelConfigs := []monitoringv2alpha1.MetricRelabelConfig{
+			{SourceLabels: []string{annotation + "scrape"}, Regex: "true", Action: "keep"},
+		}
+		if len(spec.ExcludeNamespaces) > 0 {
+			relabelConfigs = append(relabelConfigs, monitoringv2alpha1.MetricRelabelConfig{
+				SourceLabels: []string{"__meta_kubernetes_namespace"},
+				Regex:        strings.Join(spec.ExcludeNamespaces, "|"),
+				Action:       "drop",
+			})
+		}
+		relabelConfigs = append(relabelConfigs,
+			monitoringv2alpha1.MetricRelabelConfig{SourceLabels: []string{annotation + "scheme"}, Regex: "(https?)", TargetLabel: "__scheme__", Action: "replace"},
+			monitoringv2alpha1.MetricRelabelConfig{SourceLabels: []string{annotation + "path"}, Regex: "(.+)", TargetLabel: "__metrics_path__", Action: "replace"},
+			monitoringv2alpha1.MetricRelabelConfig{
+				SourceLabels: []string{"__address__", annotation + "port"},
+				Regex:        `([^:]+)(?::\d+)?;(\d+)`,
+				Replacement:  "$1:$2",
+				TargetLabel:  "__address__",
+				Action:       "replace",
+			},
+			monitoringv2alpha1.MetricRelabelConfig{SourceLabels: []string{"__meta_kubernetes_namespace"}, TargetLabel: "namespace", Action: "replace"},
+			monitoringv2alpha1.MetricRelabelConfig{SourceLabels: []string{meta + "_name"}, TargetLabel: objectLabel, Action: "replace"},
+		)
+
+		targets = append(targets, map[string]interface{}{
+			"targetName":        annotationDiscoveryTargetNames[role],
+			"type":              targetType,
+			"enabled":           true,
+			"namespaceSelector": nsSelector,
+			"selector":          map[string]interface{}{},
+			"relabelConfigs":    convertRelabelConfigs(relabelConfigs),
+			"endpoints":         endpoints,
+		})
+	}
+	return targets
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// testScheme holds the core and WhaTap types used by the reconciler tests
+var testScheme = func() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(monitoringv2alpha1.AddToScheme(scheme))
+	return scheme
+}()
+
+// newFakeClient returns a fake client holding objs. The monitor CRs have a status
+// subresource, like in the cluster.
+func newFakeClient(objs ...client.Object) client.WithWatch {
+	return fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objs...).
+		WithStatusSubresource(&monitoringv2alpha1.WhatapPodMonitor{}, &monitoringv2alpha1.WhatapServiceMonitor{}, &monitoringv2alpha1.WhatapStaticEndpoint{}).
+		Build()
+}
@@ -190,6 +190,50 @@ func TestGenerateScrapeConfig_JobLabel(t *testing.T) {
 	}
 }
 
+func TestGenerateScrapeConfig_AnnotationDiscovery(t *testing.T) {
+	cr := &monitoringv2alpha1.WhatapAgent{
+		Spec: monitoringv2alpha1.WhatapAgentSpec{
+			Features: monitoringv2alpha1.FeaturesSpec{
+				OpenAgent: monitoringv2alpha1.OpenAgentSpec{
+					Enabled: true,
+					AnnotationDiscovery: &monitoringv2alpha1.AnnotationDiscoverySpec{
+						Enabled:           true,
+						Roles:             []monitoringv2alpha1.AnnotationDiscoveryRole{monitoringv2alpha1.AnnotationDiscoveryPod},
+						NamespaceSelector: &monitoringv2alpha1.NamespaceSelector{MatchLabels: map[string]string{"team": "shop"}},
+						ExcludeNamespaces: []string{"kube-system", "shop-sandbox"},
+						Interval:          "30s",
+					},
+				},
+			},
+		},
+	}
+
+	config := generateScrapeConfig(cr, "default", nil, nil, nil)
+
+	for _, want := range []string{
+		"targetName: annotations/pods",
+		"type: PodMonitor",
+		"team: shop",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape",
+		"regex: kube-system|shop-sandbox",
+		"target_label: __metrics_path__",
+		"interval: 30s",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+	if strings.Contains(config, "annotations/services") {
+		t.Errorf("Expected only the Pod role to be discovered, got:\n%s", config)
+	}
+
+	// Disabled discovery adds no target
+	cr.Spec.Features.OpenAgent.AnnotationDiscovery.Enabled = false
+	if config := generateScrapeConfig(cr, "default", nil, nil, nil); strings.Contains(config, "annotations/") {
+		t.Errorf("Expected no annotation targets, got:\n%s", config)
+	}
+}
+
 func TestGenerateScrapeConfig_GpuMonitoringGroupLabel(t *testing.T) {
 	cr := &monitoringv2alpha1.WhatapAgent{
 		Spec: monitoringv2alpha1.WhatapAgentSpec{
@@ -250,3 +294,95 @@ func TestGenerateScrapeConfig_GpuMonitoringClusterName(t *testing.T) {
 		t.Errorf("Expected config to contain 'replacement: test-cluster', got: \n%s", config)
 	}
 }
+
+func TestApiserverScrapeConfig(t *testing.T) {
+	config := renderOpenAgentScrapeConfig(getApiserverScrapeTargets())
+
+	for _, want := range []string{
+		"targetName: kube-apiserver",
+		"type: ServiceMonitor",
+		"- default",
+		"component: apiserver",
+		"provider: kubernetes",
+		"scheme: https",
+		"caFile: " + serviceAccountCAPath,
+		"credentialsFile: " + serviceAccountTokenPath,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+}
+
+func TestEtcdScrapeConfig_HostPathCerts(t *testing.T) {
+	config := renderOpenAgentScrapeConfig(getEtcdScrapeTargets(monitoringv2alpha1.EtcdMonitoringSpec{}))
+
+	for _, want := range []string{
+		"targetName: etcd",
+		"type: PodMonitor",
+		"- kube-system",
+		"component: etcd",
+		"port: \"2379\"",
+		"caFile: /etc/kubernetes/pki/etcd/ca.crt",
+		"certFile: /etc/kubernetes/pki/etcd/healthcheck-client.crt",
+		"keyFile: /etc/kubernetes/pki/etcd/healthcheck-client.key",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+}
+
+func TestEtcdScrapeConfig_SecretCerts(t *testing.T) {
+	spec := monitoringv2alpha1.EtcdMonitoringSpec{
+		TLSConfig: &monitoringv2alpha1.TLSConfig{
+			CASecret:   &monitoringv2alpha1.SecretKeySelector{Name: "etcd-client", Key: "ca.crt"},
+			CertSecret: &monitoringv2alpha1.SecretKeySelector{Name: "etcd-client", Key: "tls.crt"},
+			KeySecret:  &monitoringv2alpha1.SecretKeySelector{Name: "etcd-client", Key: "tls.key"},
+		},
+	}
+	config := renderOpenAgentScrapeConfig(getEtcdScrapeTargets(spec))
+
+	for _, want := range []string{
+		"caFile: /etc/ssl/certs/etcd-client/ca.crt",
+		"certFile: /etc/ssl/certs/etcd-client/tls.crt",
+		"keyFile: /etc/ssl/certs/etcd-client/tls.key",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected config to contain %q, got:\n%s", want, config)
+		}
+	}
+
+	volumes, mounts := tlsSecretVolumes(spec.TLSConfig)
+	if len(volumes) != 1 || len(mounts) != 1 {
+		t.Fatalf("Expected a single secret volume, got %d volumes and %d mounts", len(volumes), len(mounts))
+	}
+	if got := len(volumes[0].Secret.Items); got != 3 {
+		t.Errorf("Expected 3 secret items, got %d", got)
+	}
+}
+
+func TestSecureControlPlaneScrapeConfig(t *testing.T) {
+	for _, tc := range []struct {
+		component string
+		port      string
+	}{
+		{"kube-scheduler", "10259"},
+		{"kube-controller-manager", "10257"},
+	} {
+		config := renderOpenAgentScrapeConfig(getSecureControlPlaneScrapeTargets(tc.component, tc.port))
+		for _, want := range []string{
+			"targetName: " + tc.component,
+			"component: " + tc.component,
+			"port: \"" + tc.port + "\"",
+			"scheme: https",
+			"insecureSkipVerify: true",
+			"type: Bearer",
+			"credentialsFile: " + serviceAccountTokenPath,
+		} {
+			if !strings.Contains(config, want) {
+				t.Errorf("%s: expected config to contain %q, got:\n%s", tc.component, want, config)
+			}
+		}
+	}
+}
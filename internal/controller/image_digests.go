@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+// apmInitImages returns the APM init images the webhook injects for cr, sorted. Images
+// already pinned by digest are left out.
+func apmInitImages(cr *monitoringv2alpha1.WhatapAgent) []string {
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+	if !instrumentation.Enabled {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var images []string
+	for _, target := range instrumentation.Targets {
+		if !target.Enabled || instrumentation.TargetMode(target) == monitoringv2alpha1.InstrumentationModeUninstrument {
+			continue
+		}
+		image := cr.ApmInitImageFor(target)
+		if _, ok := seen[image]; ok || strings.Contains(image, "@") {
+			continue
+		}
+		seen[image] = struct{}{}
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// resolveImageDigests computes status.imageDigests for spec.pinImageDigests. Tags are
+// resolved once per generation; images that failed are retried on later passes. It
+// returns nil when pinning is off.
+func resolveImageDigests(ctx context.Context, resolver registry.Resolver, cr *monitoringv2alpha1.WhatapAgent) (*monitoringv2alpha1.ImageDigestStatus, map[string]error) {
+	if !cr.Spec.PinImageDigests {
+		return nil, nil
+	}
+	status := &monitoringv2alpha1.ImageDigestStatus{ObservedGeneration: cr.Generation, Digests: map[string]string{}}
+	previous := cr.Status.ImageDigests
+	failures := map[string]error{}
+	for _, image := range apmInitImages(cr) {
+		if previous != nil && previous.ObservedGeneration == cr.Generation {
+			if digest, ok := previous.Digests[image]; ok {
+				status.Digests[image] = digest
+				continue
+			}
+		}
+		digest, err := resolver.Resolve(ctx, image)
+		if err != nil {
+			failures[image] = err
+			status.Failed = append(status.Failed, image)
+			continue
+		}
+		status.Digests[image] = digest
+	}
+	if len(status.Digests) == 0 {
+		status.Digests = nil
+	}
+	return status, failures
+}
+
+// reconcileImageDigests resolves the APM init image digests of cr and stores them in its
+// status right away, so pods created by this pass (e.g. restarts) already get the
+// pinned images. Unresolved images are reported as events and injected by tag.
+func (r *WhatapAgentReconciler) reconcileImageDigests(ctx context.Context, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
+	resolver := r.Registry
+	if resolver == nil {
+		resolver = registry.NewClient(nil)
+	}
+	status, failures := resolveImageDigests(ctx, resolver, cr)
+	for image, err := range failures {
+		logger.Info("Failed to resolve image digest; injecting by tag", "image", image, "error", err.Error())
+		r.Recorder.Event(cr, corev1.EventTypeWarning, "ImageDigestUnresolved", fmt.Sprintf("%s is injected by tag: %v", image, err))
+	}
+	if equality.Semantic.DeepEqual(cr.Status.ImageDigests, status) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(cr), cr); err != nil {
+			return err
+		}
+		cr.Status.ImageDigests = status
+		return r.Status().Update(ctx, cr)
+	})
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// fakeResolver resolves images from a map and counts the lookups.
+type fakeResolver struct {
+	digests map[string]string
+	calls   int
+}
+
+func (f *fakeResolver) Resolve(_ context.Context, image string) (string, error) {
+	f.calls++
+	if digest, ok := f.digests[image]; ok {
+		return digest, nil
+	}
+	return "", errors.New("manifest unknown")
+}
+
+func TestResolveImageDigests(t *testing.T) {
+	cr := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "whatap", Generation: 3}}
+	cr.Spec.ImageRegistry = "harbor.example.com/ecr-proxy"
+	cr.Spec.PinImageDigests = true
+	cr.Spec.Features.Apm.Instrumentation = monitoringv2alpha1.InstrumentationSpec{
+		Enabled: true,
+		Targets: []monitoringv2alpha1.TargetSpec{
+			{Name: "java", Enabled: true, Language: "java"},
+			{Name: "java-2", Enabled: true, Language: "java"},
+			{Name: "python", Enabled: true, Language: "python", WhatapApmVersions: map[string]string{"python": "1.8.0"}},
+			{Name: "pinned", Enabled: true, Language: "nodejs", CustomImageFullName: "registry.local/apm-init-nodejs@sha256:abc"},
+			{Name: "off", Language: "php"},
+		},
+	}
+	javaImage := "harbor.example.com/ecr-proxy/whatap/apm-init-java:latest"
+	pythonImage := "harbor.example.com/ecr-proxy/whatap/apm-init-python:1.8.0"
+	resolver := &fakeResolver{digests: map[string]string{javaImage: "sha256:1111"}}
+
+	status, failures := resolveImageDigests(context.Background(), resolver, cr)
+	if status.ObservedGeneration != 3 || status.Digests[javaImage] != "sha256:1111" || len(status.Digests) != 1 {
+		t.Errorf("Unexpected digests %+v", status)
+	}
+	if len(status.Failed) != 1 || status.Failed[0] != pythonImage || failures[pythonImage] == nil {
+		t.Errorf("Expected %s to be reported as unresolved, got %+v", pythonImage, status.Failed)
+	}
+	if resolver.calls != 2 {
+		t.Errorf("Expected one lookup per distinct image, got %d", resolver.calls)
+	}
+
+	// The same generation keeps the recorded digests and only retries failed images
+	cr.Status.ImageDigests = status
+	resolver.digests[javaImage] = "sha256:2222"
+	resolver.digests[pythonImage] = "sha256:3333"
+	resolver.calls = 0
+	status, _ = resolveImageDigests(context.Background(), resolver, cr)
+	if resolver.calls != 1 || status.Digests[javaImage] != "sha256:1111" || status.Digests[pythonImage] != "sha256:3333" || len(status.Failed) != 0 {
+		t.Errorf("Expected only the failed image to be resolved again, got %+v after %d lookups", status, resolver.calls)
+	}
+
+	cr.Status.ImageDigests = status
+	target := cr.Spec.Features.Apm.Instrumentation.Targets[0]
+	if got := cr.PinnedImage(cr.ApmInitImageFor(target)); got != "harbor.example.com/ecr-proxy/whatap/apm-init-java@sha256:1111" {
+		t.Errorf("Expected the pinned image, got %s", got)
+	}
+
+	// A spec change resolves the tags again; until then images are injected by tag
+	cr.Generation = 4
+	if got := cr.PinnedImage(cr.ApmInitImageFor(target)); got != javaImage {
+		t.Errorf("Expected the tag while digests are stale, got %s", got)
+	}
+	resolver.calls = 0
+	status, _ = resolveImageDigests(context.Background(), resolver, cr)
+	if resolver.calls != 2 || status.Digests[javaImage] != "sha256:2222" {
+		t.Errorf("Expected the tags to be resolved again, got %+v", status)
+	}
+
+	cr.Spec.PinImageDigests = false
+	if status, _ := resolveImageDigests(context.Background(), resolver, cr); status != nil {
+		t.Errorf("Expected no digest status when pinning is off, got %+v", status)
+	}
+}
+
+func TestRewriteRegistry(t *testing.T) {
+	for _, c := range []struct{ image, registry, want string }{
+		{"public.ecr.aws/whatap/kube_agent:1.0", "harbor.example.com/ecr-proxy/", "harbor.example.com/ecr-proxy/whatap/kube_agent:1.0"},
+		{"nvcr.io/nvidia/cloud-native/dcgm:4.6.0", "mirror.local:5000", "mirror.local:5000/nvidia/cloud-native/dcgm:4.6.0"},
+		{"whatap/kube_agent:1.0", "mirror.local", "mirror.local/whatap/kube_agent:1.0"},
+		{"public.ecr.aws/whatap/open_agent:latest", "", "public.ecr.aws/whatap/open_agent:latest"},
+	} {
+		if got := monitoringv2alpha1.RewriteRegistry(c.image, c.registry); got != c.want {
+			t.Errorf("RewriteRegistry(%q, %q) = %q, want %q", c.image, c.registry, got, c.want)
+		}
+	}
+}
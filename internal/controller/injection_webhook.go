@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"sort"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// defaultExcludedNamespaces are never sent to the pod injection webhook unless
+// InjectionWebhookSpec.ExcludeNamespaces overrides them.
+var defaultExcludedNamespaces = []string{"kube-system"}
+
+// defaultWebhookTimeoutSeconds matches the API server default, so that unchanged
+// settings do not cause an update of the webhook configuration on every reconcile.
+const defaultWebhookTimeoutSeconds int32 = 10
+
+// podWebhookSettings holds the mpod.kb.io fields derived from every WhatapAgent.
+type podWebhookSettings struct {
+	FailurePolicy     admissionregistrationv1.FailurePolicyType
+	TimeoutSeconds    *int32
+	NamespaceSelector *metav1.LabelSelector
+	ObjectSelector    *metav1.LabelSelector
+}
+
+// computePodWebhookSettings derives the pod injection webhook settings from agents.
+// failurePolicy, timeoutSeconds, excludeNamespaces and objectSelector come from the
+// oldest instance with instrumentation enabled; the namespaceSelector covers the
+// targets of every such instance, including the ones being uninstrumented, and the
+// injectedNamespaces still holding injected pods, so that their replacements are
+// stripped of the agent. It always excludes operatorNamespace.
+func computePodWebhookSettings(agents []monitoringv2alpha1.WhatapAgent, injectedNamespaces []string, operatorNamespace string) podWebhookSettings {
+	enabled := instrumentingAgents(agents)
+
+	timeout := defaultWebhookTimeoutSeconds
+	settings := podWebhookSettings{
+		FailurePolicy:  admissionregistrationv1.Ignore,
+		TimeoutSeconds: &timeout,
+		ObjectSelector: &metav1.LabelSelector{},
+	}
+	if len(enabled) > 0 {
+		if spec := enabled[0].Spec.Features.Apm.Instrumentation.Webhook; spec != nil {
+			if spec.FailurePolicy == string(admissionregistrationv1.Fail) {
+				settings.FailurePolicy = admissionregistrationv1.Fail
+			}
+			if spec.TimeoutSeconds != nil {
+				timeout = *spec.TimeoutSeconds
+			}
+			if spec.ObjectSelector != nil {
+				settings.ObjectSelector = spec.ObjectSelector.DeepCopy()
+			}
+		}
+	}
+
+	var targets []monitoringv2alpha1.TargetSpec
+	for _, cr := range enabled {
+		inst := &cr.Spec.Features.Apm.Instrumentation
+		for _, target := range inst.Targets {
+			if target.Enabled || inst.TargetMode(target) == monitoringv2alpha1.InstrumentationModeUninstrument {
+				targets = append(targets, target)
+			}
+		}
+	}
+	// The namespaces holding injected pods are kept as if a target selected them by name
+	if len(targets) > 0 && len(injectedNamespaces) > 0 {
+		targets = append(targets, monitoringv2alpha1.TargetSpec{
+			NamespaceSelector: monitoringv2alpha1.NamespaceSelector{MatchNames: injectedNamespaces},
+		})
+	}
+	selector := targetNamespaceSelector(targets)
+	if selector == nil {
+		selector = &metav1.LabelSelector{}
+	}
+
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      corev1.LabelMetadataName,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   excludedInjectionNamespaces(enabled, operatorNamespace),
+	})
+	settings.NamespaceSelector = selector
+	return settings
+}
+
+// instrumentingAgents returns the instances with instrumentation enabled, oldest first:
+// the order in which the pod webhook picks the instance injecting a pod.
+func instrumentingAgents(agents []monitoringv2alpha1.WhatapAgent) []*monitoringv2alpha1.WhatapAgent {
+	var enabled []*monitoringv2alpha1.WhatapAgent
+	for i := range agents {
+		if agents[i].DeletionTimestamp.IsZero() && agents[i].Spec.Features.Apm.Instrumentation.Enabled {
+			enabled = append(enabled, &agents[i])
+		}
+	}
+	sort.Slice(enabled, func(i, j int) bool { return enabled[i].IsOlderThan(enabled[j]) })
+	return enabled
+}
+
+// excludedInjectionNamespaces returns the sorted namespaces never sent to the pod webhook:
+// the excludeNamespaces of the oldest instrumenting instance (kube-system by default) and
+// operatorNamespace.
+func excludedInjectionNamespaces(enabled []*monitoringv2alpha1.WhatapAgent, operatorNamespace string) []string {
+	excluded := defaultExcludedNamespaces
+	if len(enabled) > 0 {
+		if spec := enabled[0].Spec.Features.Apm.Instrumentation.Webhook; spec != nil && len(spec.ExcludeNamespaces) > 0 {
+			excluded = spec.ExcludeNamespaces
+		}
+	}
+	names := map[string]bool{}
+	for _, ns := range append(append([]string{}, excluded...), operatorNamespace) {
+		if ns != "" {
+			names[ns] = true
+		}
+	}
+	notIn := make([]string, 0, len(names))
+	for ns := range names {
+		notIn = append(notIn, ns)
+	}
+	sort.Strings(notIn)
+	return notIn
+}
+
+// targetNamespaceSelector returns a selector matching exactly the union of the targets'
+// namespace selectors, or nil when that union cannot be expressed as a single label
+// selector (no targets, a target without namespace selector, or differing label selectors).
+func targetNamespaceSelector(targets []monitoringv2alpha1.TargetSpec) *metav1.LabelSelector {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// Every target selects by name only: match the union of the names
+	namesOnly := true
+	var names []string
+	for _, target := range targets {
+		ns := target.NamespaceSelector
+		if len(ns.MatchNames) == 0 || len(ns.MatchLabels) > 0 || len(ns.MatchExpressions) > 0 {
+			namesOnly = false
+			break
+		}
+		for _, name := range ns.MatchNames {
+			if !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if namesOnly {
+		sort.Strings(names)
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      corev1.LabelMetadataName,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   names,
+		}}}
+	}
+
+	// Otherwise only a selector shared by every target can be translated
+	first := targets[0].NamespaceSelector
+	for _, target := range targets[1:] {
+		if !equality.Semantic.DeepEqual(first, target.NamespaceSelector) {
+			return nil
+		}
+	}
+	if len(first.MatchNames) == 0 && len(first.MatchLabels) == 0 && len(first.MatchExpressions) == 0 {
+		return nil
+	}
+	selector := toMetaLabelSelector(first.MatchLabels, first.MatchExpressions).DeepCopy()
+	if len(first.MatchNames) > 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      corev1.LabelMetadataName,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   append([]string{}, first.MatchNames...),
+		})
+	}
+	return selector
+}
+
+// injectedPodNamespaces returns the sorted namespaces of the pods the webhook injected.
+func (r *WhatapAgentReconciler) injectedPodNamespaces(ctx context.Context) ([]string, error) {
+	// The cache only holds pods carrying the injection label (see cmd/main.go)
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.MatchingLabels{monitoringv2alpha1.InjectionLabelKey: "true"}); err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, pod := range pods.Items {
+		if !containsString(namespaces, pod.Namespace) {
+			namespaces = append(namespaces, pod.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
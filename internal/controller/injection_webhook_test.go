@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputePodWebhookSettings(t *testing.T) {
+	older := *restartTestAgent("2.2.68")
+	older.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	timeout := int32(5)
+	older.Spec.Features.Apm.Instrumentation.Webhook = &monitoringv2alpha1.InjectionWebhookSpec{
+		FailurePolicy:     "Fail",
+		TimeoutSeconds:    &timeout,
+		ExcludeNamespaces: []string{"kube-system", "istio-system"},
+		ObjectSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"team": "shop"}},
+	}
+	newer := *restartTestAgent("2.2.68")
+	newer.Name = "team-b"
+	newer.CreationTimestamp = metav1.Now()
+	newer.Spec.Features.Apm.Instrumentation.Targets[0].NamespaceSelector.MatchNames = []string{"billing", "shop"}
+	newer.Spec.Features.Apm.Instrumentation.Webhook = &monitoringv2alpha1.InjectionWebhookSpec{FailurePolicy: "Ignore"}
+
+	settings := computePodWebhookSettings([]monitoringv2alpha1.WhatapAgent{newer, older}, nil, "whatap-monitoring")
+
+	if settings.FailurePolicy != admissionregistrationv1.Fail || *settings.TimeoutSeconds != 5 {
+		t.Errorf("Expected the oldest instance's failurePolicy and timeout, got %s/%d", settings.FailurePolicy, *settings.TimeoutSeconds)
+	}
+	if settings.ObjectSelector.MatchLabels["team"] != "shop" {
+		t.Errorf("Expected the oldest instance's objectSelector, got %+v", settings.ObjectSelector)
+	}
+	want := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"billing", "shop"}},
+		{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"istio-system", "kube-system", "whatap-monitoring"}},
+	}}
+	if !equality.Semantic.DeepEqual(settings.NamespaceSelector, want) {
+		t.Errorf("Unexpected namespaceSelector %+v", settings.NamespaceSelector)
+	}
+}
+
+func TestComputePodWebhookSettings_KeepsUninstrumentedNamespaces(t *testing.T) {
+	cr := *restartTestAgent("2.2.68")
+	cr.Spec.Features.Apm.Instrumentation.Targets[0].NamespaceSelector.MatchNames = []string{"shop"}
+	cr.Spec.Features.Apm.Instrumentation.Targets = append(cr.Spec.Features.Apm.Instrumentation.Targets, monitoringv2alpha1.TargetSpec{
+		Name:              "legacy",
+		Language:          "java",
+		Mode:              monitoringv2alpha1.InstrumentationModeUninstrument,
+		NamespaceSelector: monitoringv2alpha1.NamespaceSelector{MatchNames: []string{"legacy"}},
+	})
+
+	// billing still holds pods injected by a target that has since been removed
+	settings := computePodWebhookSettings([]monitoringv2alpha1.WhatapAgent{cr}, []string{"billing"}, "whatap-monitoring")
+	want := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"billing", "legacy", "shop"}},
+		{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system", "whatap-monitoring"}},
+	}}
+	if !equality.Semantic.DeepEqual(settings.NamespaceSelector, want) {
+		t.Errorf("Unexpected namespaceSelector %+v", settings.NamespaceSelector)
+	}
+}
+
+func TestComputePodWebhookSettings_Defaults(t *testing.T) {
+	settings := computePodWebhookSettings(nil, nil, "whatap-monitoring")
+
+	if settings.FailurePolicy != admissionregistrationv1.Ignore || *settings.TimeoutSeconds != defaultWebhookTimeoutSeconds {
+		t.Errorf("Expected Ignore with the default timeout, got %s/%d", settings.FailurePolicy, *settings.TimeoutSeconds)
+	}
+	want := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system", "whatap-monitoring"}},
+	}}
+	if !equality.Semantic.DeepEqual(settings.NamespaceSelector, want) {
+		t.Errorf("Unexpected namespaceSelector %+v", settings.NamespaceSelector)
+	}
+}
+
+func TestTargetNamespaceSelector(t *testing.T) {
+	byLabel := monitoringv2alpha1.NamespaceSelector{MatchLabels: map[string]string{"apm": "on"}}
+	tests := []struct {
+		name    string
+		targets []monitoringv2alpha1.NamespaceSelector
+		want    *metav1.LabelSelector
+	}{
+		{
+			name:    "no targets",
+			targets: nil,
+			want:    nil,
+		},
+		{
+			name:    "target without namespace selector matches every namespace",
+			targets: []monitoringv2alpha1.NamespaceSelector{{MatchNames: []string{"shop"}}, {}},
+			want:    nil,
+		},
+		{
+			name:    "shared label selector",
+			targets: []monitoringv2alpha1.NamespaceSelector{byLabel, byLabel},
+			want:    &metav1.LabelSelector{MatchLabels: map[string]string{"apm": "on"}},
+		},
+		{
+			name:    "different label selectors",
+			targets: []monitoringv2alpha1.NamespaceSelector{byLabel, {MatchLabels: map[string]string{"apm": "off"}}},
+			want:    nil,
+		},
+		{
+			name:    "names and labels",
+			targets: []monitoringv2alpha1.NamespaceSelector{{MatchNames: []string{"shop"}, MatchLabels: map[string]string{"apm": "on"}}},
+			want: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"apm": "on"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"shop"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var targets []monitoringv2alpha1.TargetSpec
+			for _, ns := range tt.targets {
+				targets = append(targets, monitoringv2alpha1.TargetSpec{Enabled: true, NamespaceSelector: ns})
+			}
+			got := targetNamespaceSelector(targets)
+			if !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -19,9 +22,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// Default images of the agents. spec.imageRegistry replaces their registry.
+const (
+	defaultKubeAgentImage      = "public.ecr.aws/whatap/kube_agent"
+	defaultOpenAgentImage      = "public.ecr.aws/whatap/open_agent"
+	defaultDcgmExporterImage   = "public.ecr.aws/whatap/dcgm-exporter:4.6.0-4.8.3-distroless"
+	defaultDcgmHostEngineImage = "nvcr.io/nvidia/cloud-native/dcgm:4.6.0-1-ubuntu24.04"
+)
+
 var invalidPromLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
 
 func sanitizePromLabelName(s string) string {
@@ -164,7 +176,7 @@ func createOrUpdateMasterAgent(ctx context.Context, r *WhatapAgentReconciler, lo
 		// Use the separate name and version fields
 		imgName := cr.Spec.Features.K8sAgent.AgentImageName
 		if imgName == "" {
-			imgName = "public.ecr.aws/whatap/kube_agent"
+			imgName = cr.Image(defaultKubeAgentImage)
 		}
 
 		ver := cr.Spec.Features.K8sAgent.AgentImageVersion
@@ -190,7 +202,7 @@ func createOrUpdateMasterAgent(ctx context.Context, r *WhatapAgentReconciler, lo
 	// Create deployment with base metadata
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "whatap-master-agent",
+			Name:      cr.ResourceName("whatap-master-agent"),
 			Namespace: r.DefaultNamespace,
 		},
 	}
@@ -290,7 +302,7 @@ func getMasterAgentDeploymentSpec(image string, res *corev1.ResourceRequirements
 	masterSpec := cr.Spec.Features.K8sAgent.MasterAgent
 
 	// Create base labels and merge with custom labels if provided
-	labels := map[string]string{"name": "whatap-master-agent"}
+	labels := map[string]string{"name": cr.ResourceName("whatap-master-agent")}
 	if masterSpec.PodLabels != nil {
 		for k, v := range masterSpec.PodLabels {
 			labels[k] = v
@@ -360,7 +372,7 @@ func getMasterAgentDeploymentSpec(image string, res *corev1.ResourceRequirements
 	return appsv1.DeploymentSpec{
 		Replicas: int32Ptr(1),
 		Selector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{"name": "whatap-master-agent"},
+			MatchLabels: map[string]string{"name": cr.ResourceName("whatap-master-agent")},
 		},
 		Strategy: appsv1.DeploymentStrategy{
 			Type: appsv1.RollingUpdateDeploymentStrategyType,
@@ -412,7 +424,7 @@ func getMasterAgentDeploymentSpec(image string, res *corev1.ResourceRequirements
 					{
 						Name:            "whatap-master-agent",
 						Image:           masterImage,
-						ImagePullPolicy: corev1.PullIfNotPresent,
+						ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
 						Command:         []string{"/bin/entrypoint.sh"},
 						Ports:           []corev1.ContainerPort{{ContainerPort: 6600, Protocol: corev1.ProtocolTCP}},
 						Env:             masterEnvs,
@@ -466,7 +478,7 @@ func createOrUpdateNodeAgent(ctx context.Context, r *WhatapAgentReconciler, logg
 		// Use the separate name and version fields
 		imgName := cr.Spec.Features.K8sAgent.AgentImageName
 		if imgName == "" {
-			imgName = "public.ecr.aws/whatap/kube_agent"
+			imgName = cr.Image(defaultKubeAgentImage)
 		}
 
 		ver := cr.Spec.Features.K8sAgent.AgentImageVersion
@@ -500,7 +512,7 @@ func createOrUpdateNodeAgent(ctx context.Context, r *WhatapAgentReconciler, logg
 		}
 
 		// 1. Create GPU Agent
-		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, "whatap-node-agent-gpu", img, resources, true, gpuSpec.NodeSelector, gpuAffinityTerms); err != nil {
+		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, cr.ResourceName("whatap-node-agent-gpu"), img, resources, true, gpuSpec.NodeSelector, gpuAffinityTerms); err != nil {
 			return err
 		}
 
@@ -529,7 +541,7 @@ func createOrUpdateNodeAgent(ctx context.Context, r *WhatapAgentReconciler, logg
 			}
 		}
 
-		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, "whatap-node-agent", img, resources, false, nil, terms); err != nil {
+		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, cr.ResourceName("whatap-node-agent"), img, resources, false, nil, terms); err != nil {
 			return err
 		}
 	} else {
@@ -537,18 +549,18 @@ func createOrUpdateNodeAgent(ctx context.Context, r *WhatapAgentReconciler, logg
 		// Ensure GPU specific agent is deleted
 		gpuDS := &appsv1.DaemonSet{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      "whatap-node-agent-gpu",
+				Name:      cr.ResourceName("whatap-node-agent-gpu"),
 				Namespace: r.DefaultNamespace,
 			},
 		}
 		if err := r.Client.Delete(ctx, gpuDS); err != nil {
 			if !errors.IsNotFound(err) {
-				logger.Error(err, "Failed to delete whatap-node-agent-gpu", "name", "whatap-node-agent-gpu")
+				logger.Error(err, "Failed to delete GPU node agent", "name", gpuDS.Name)
 			}
 		}
 
 		// Create Normal Agent (with GPU sidecar if enabled globally)
-		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, "whatap-node-agent", img, resources, gpuSpec.Enabled, nil, nil); err != nil {
+		if err := reconcileNodeAgentDaemonSet(ctx, r, logger, cr, cr.ResourceName("whatap-node-agent"), img, resources, gpuSpec.Enabled, nil, nil); err != nil {
 			return err
 		}
 	}
@@ -578,8 +590,14 @@ func getNodeAgentDaemonSetSpec(image string, res *corev1.ResourceRequirements, c
 
 	// Create base labels and merge with custom labels if provided
 	labels := map[string]string{"name": dsName}
+	for k, v := range cr.InstanceLabels() {
+		labels[k] = v
+	}
 	if includeDcgm {
 		labels["whatap-gpu"] = "true"
+		for k, v := range dcgmInstanceLabels(cr) {
+			labels[k] = v
+		}
 	}
 	if nodeSpec.PodLabels != nil {
 		for k, v := range nodeSpec.PodLabels {
@@ -802,7 +820,7 @@ func getNodeAgentDaemonSetSpec(image string, res *corev1.ResourceRequirements, c
 					{
 						Name:            "whatap-node-helper",
 						Image:           helperImage,
-						ImagePullPolicy: corev1.PullIfNotPresent,
+						ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
 						Command:         []string{"/data/agent/node/cadvisor_helper", "-port", "6801"},
 						Ports:           []corev1.ContainerPort{{Name: "helperport", ContainerPort: 6801, Protocol: corev1.ProtocolTCP}},
 						Env:             helperEnvs,
@@ -818,7 +836,7 @@ func getNodeAgentDaemonSetSpec(image string, res *corev1.ResourceRequirements, c
 					{
 						Name:            "whatap-node-agent",
 						Image:           agentImage,
-						ImagePullPolicy: corev1.PullIfNotPresent,
+						ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
 						Command:         []string{"/bin/entrypoint.sh"},
 						Ports:           []corev1.ContainerPort{{Name: "nodeport", ContainerPort: 6600, Protocol: corev1.ProtocolTCP}},
 						Env:             agentEnvs,
@@ -874,7 +892,7 @@ func addDcgmExporterToNodeAgent(podSpec *corev1.PodSpec, cr *monitoringv2alpha1.
 	gpuSpec := cr.Spec.Features.K8sAgent.GpuMonitoring
 
 	// Check if a custom image is specified
-	dcgmImage := "public.ecr.aws/whatap/dcgm-exporter:4.6.0-4.8.3-distroless"
+	dcgmImage := cr.Image(defaultDcgmExporterImage)
 	if gpuSpec.CustomImageFullName != "" {
 		dcgmImage = gpuSpec.CustomImageFullName
 	}
@@ -971,7 +989,7 @@ func addDcgmExporterToNodeAgent(podSpec *corev1.PodSpec, cr *monitoringv2alpha1.
 	dcgmContainer := corev1.Container{
 		Name:            "dcgm-exporter",
 		Image:           dcgmImage,
-		ImagePullPolicy: corev1.PullIfNotPresent,
+		ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
 		Env:             envVars,
 		Ports:           []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9400, Protocol: corev1.ProtocolTCP}},
 		Resources:       gpuSpec.Resources,
@@ -991,7 +1009,7 @@ func addDcgmExporterToNodeAgent(podSpec *corev1.PodSpec, cr *monitoringv2alpha1.
 
 	// Add DCGM host engine sidecar container if enabled
 	if gpuSpec.HostEngine != nil && gpuSpec.HostEngine.Enabled {
-		hostEngineImage := "nvcr.io/nvidia/cloud-native/dcgm:4.6.0-1-ubuntu24.04"
+		hostEngineImage := cr.Image(defaultDcgmHostEngineImage)
 		if gpuSpec.HostEngine.CustomImageFullName != "" {
 			hostEngineImage = gpuSpec.HostEngine.CustomImageFullName
 		}
@@ -999,7 +1017,7 @@ func addDcgmExporterToNodeAgent(podSpec *corev1.PodSpec, cr *monitoringv2alpha1.
 		hostEngineContainer := corev1.Container{
 			Name:            "dcgm-hostengine",
 			Image:           hostEngineImage,
-			ImagePullPolicy: corev1.PullIfNotPresent,
+			ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
 			Ports:           []corev1.ContainerPort{{Name: "he", ContainerPort: hostEnginePort, HostPort: hostEnginePort, Protocol: corev1.ProtocolTCP}},
 			Resources:       gpuSpec.HostEngine.Resources,
 			SecurityContext: &corev1.SecurityContext{
@@ -1043,6 +1061,17 @@ func addDcgmExporterToNodeAgent(podSpec *corev1.PodSpec, cr *monitoringv2alpha1.
 }
 
 // ensureDcgmExporterService creates or updates the service for dcgm-exporter
+// dcgmInstanceLabels identify the dcgm-exporter pods of cr. Unlike InstanceLabels they
+// are set for the default instance too: its dcgm-exporter Service would otherwise select
+// the dcgm-exporter pods of every instance.
+func dcgmInstanceLabels(cr *monitoringv2alpha1.WhatapAgent) map[string]string {
+	name := cr.Name
+	if cr.IsDefaultInstance() {
+		name = monitoringv2alpha1.DefaultWhatapAgentName
+	}
+	return map[string]string{monitoringv2alpha1.InstanceLabelKey: name}
+}
+
 func ensureDcgmExporterService(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
 	// Check if GPU monitoring is enabled
 	if !cr.Spec.Features.K8sAgent.GpuMonitoring.Enabled {
@@ -1062,7 +1091,7 @@ func ensureDcgmExporterService(ctx context.Context, r *WhatapAgentReconciler, lo
 
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "dcgm-exporter-service",
+			Name:      cr.ResourceName("dcgm-exporter-service"),
 			Namespace: serviceNamespace,
 			Labels: map[string]string{
 				"app.kubernetes.io/name":       "dcgm-exporter",
@@ -1104,11 +1133,16 @@ func ensureDcgmExporterService(ctx context.Context, r *WhatapAgentReconciler, lo
 		// Backup existing ClusterIP
 		clusterIP := svc.Spec.ClusterIP
 
+		selector := map[string]string{
+			"whatap-gpu": "true",
+		}
+		for k, v := range dcgmInstanceLabels(cr) {
+			selector[k] = v
+		}
+
 		svc.Spec = corev1.ServiceSpec{
-			Selector: map[string]string{
-				"whatap-gpu": "true",
-			},
-			Type: serviceType,
+			Selector: selector,
+			Type:     serviceType,
 			Ports: []corev1.ServicePort{{
 				Name:       "metrics",
 				Port:       port,
@@ -1145,15 +1179,690 @@ func ensureDcgmExporterService(ctx context.Context, r *WhatapAgentReconciler, lo
 	return nil
 }
 
-// 나머지 설치 함수(스펙이 없음)
+// ---------- Control plane 모니터링 ----------
+
+const (
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+	apiserverMonitorName         = "whatap-apiserver-monitor"
+	etcdMonitorName              = "whatap-etcd-monitor"
+	schedulerMonitorName         = "whatap-scheduler-monitor"
+	controllerManagerMonitorName = "whatap-controller-manager-monitor"
+
+	defaultEtcdPort         = "2379"
+	defaultEtcdCertHostPath = "/etc/kubernetes/pki/etcd"
+)
+
+// controlPlaneMonitor describes a dedicated OpenAgent Deployment that scrapes a
+// single control-plane component. The ServiceAccount, ClusterRole,
+// ClusterRoleBinding and ConfigMap it owns are all named after the Deployment
+// (<name>-sa, <name>-role, <name>-role-binding, <name>-config), so cleanup only
+// needs the base name.
+type controlPlaneMonitor struct {
+	// Name is the Deployment name and the prefix of every child resource
+	Name string
+	// Component is the human-readable component name used in logs and events
+	Component string
+	// Image is the OpenAgent image that runs the scrape
+	Image string
+	// Rules are the ClusterRole rules granted to the monitor's ServiceAccount
+	Rules []rbacv1.PolicyRule
+	// Targets are the OpenAgent targets rendered into scrape_config.yaml
+	Targets []interface{}
+	// Volumes and VolumeMounts are added next to the config and logs volumes
+	Volumes      []corev1.Volume
+	VolumeMounts []corev1.VolumeMount
+	// ControlPlaneOnly pins the pod to control-plane nodes and tolerates their taints
+	ControlPlaneOnly bool
+	// RunAsRoot is required when reading root-owned certificates from a hostPath
+	RunAsRoot bool
+}
+
+// getControlPlaneMonitorImage resolves the image for a control-plane monitor:
+// the component's CustomImageFullName wins, otherwise the OpenAgent image
+// settings (name/version/custom image) are reused.
+func getControlPlaneMonitorImage(spec monitoringv2alpha1.AgentComponentSpec, cr *monitoringv2alpha1.WhatapAgent) string {
+	if spec.CustomImageFullName != "" {
+		return spec.CustomImageFullName
+	}
+	return getOpenAgentImage(cr.Spec.Features.OpenAgent, cr)
+}
+
+// renderOpenAgentScrapeConfig renders targets into the same scrape_config.yaml
+// layout generateScrapeConfig produces for the main OpenAgent.
+func renderOpenAgentScrapeConfig(targets []interface{}) string {
+	config := map[string]interface{}{
+		"features": map[string]interface{}{
+			"openAgent": map[string]interface{}{
+				"enabled": true,
+				"targets": targets,
+			},
+		},
+	}
+	yamlBytes, err := yaml.Marshal(toOrderedYAML(config))
+	if err != nil {
+		return "# Error generating scrape config: " + err.Error()
+	}
+	return string(yamlBytes)
+}
+
+// getApiserverScrapeTargets returns the OpenAgent target that scrapes the
+// "kubernetes" Service endpoints in the default namespace. The monitor's own
+// ServiceAccount token and the cluster CA are used to authenticate.
+func getApiserverScrapeTargets() []interface{} {
+	target := map[string]interface{}{
+		"targetName": "kube-apiserver",
+		"type":       "ServiceMonitor",
+		"enabled":    true,
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []string{"default"},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]string{
+				"component": "apiserver",
+				"provider":  "kubernetes",
+			},
+		},
+		"endpoints": convertEndpoints([]monitoringv2alpha1.OpenAgentEndpoint{
+			{
+				Port:     "https",
+				Path:     "/metrics",
+				Scheme:   "https",
+				Interval: "30s",
+				TLSConfig: &monitoringv2alpha1.TLSConfig{
+					CAFile: serviceAccountCAPath,
+				},
+				Authorization: &monitoringv2alpha1.AuthorizationConfig{
+					Type:            "Bearer",
+					CredentialsFile: serviceAccountTokenPath,
+				},
+			},
+		}),
+	}
+	return []interface{}{toOrderedYAML(target)}
+}
+
 func installApiserverMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
+	return reconcileControlPlaneMonitor(ctx, r, logger, cr, controlPlaneMonitor{
+		Name:      cr.ResourceName(apiserverMonitorName),
+		Component: "Apiserver",
+		Image:     getControlPlaneMonitorImage(cr.Spec.Features.K8sAgent.ApiserverMonitoring, cr),
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"services", "endpoints", "pods", "namespaces"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"discovery.k8s.io"},
+				Resources: []string{"endpointslices"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		},
+		Targets: getApiserverScrapeTargets(),
+	})
+}
+
+// reconcileControlPlaneMonitor creates or updates every resource a
+// control-plane monitor needs: ServiceAccount, ClusterRole, ClusterRoleBinding,
+// scrape ConfigMap and the OpenAgent Deployment itself.
+func reconcileControlPlaneMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent, m controlPlaneMonitor) error {
+	saName := m.Name + "-sa"
+	roleName := m.Name + "-role"
+	configName := m.Name + "-config"
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      saName,
+			Namespace: r.DefaultNamespace,
+		},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, sa, func() error {
+		return controllerutil.SetControllerReference(cr, sa, r.Scheme)
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create/update ServiceAccount", "component", m.Component)
+		return err
+	}
+	logResult(logger, "Whatap", m.Component+" Monitor ServiceAccount", op)
+
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: roleName,
+		},
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		if err := controllerutil.SetControllerReference(cr, role, r.Scheme); err != nil {
+			return err
+		}
+		role.Rules = m.Rules
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create/update ClusterRole", "component", m.Component)
+		return err
+	}
+	logResult(logger, "Whatap", m.Component+" Monitor ClusterRole", op)
+
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: roleName + "-binding",
+		},
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, binding, func() error {
+		if err := controllerutil.SetControllerReference(cr, binding, r.Scheme); err != nil {
+			return err
+		}
+		binding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      saName,
+				Namespace: r.DefaultNamespace,
+			},
+		}
+		binding.RoleRef = rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			Name:     roleName,
+			APIGroup: "rbac.authorization.k8s.io",
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create/update ClusterRoleBinding", "component", m.Component)
+		return err
+	}
+	logResult(logger, "Whatap", m.Component+" Monitor ClusterRoleBinding", op)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configName,
+			Namespace: r.DefaultNamespace,
+		},
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		if err := controllerutil.SetControllerReference(cr, cm, r.Scheme); err != nil {
+			return err
+		}
+		cm.Data = map[string]string{
+			"scrape_config.yaml": renderOpenAgentScrapeConfig(m.Targets),
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create/update ConfigMap", "component", m.Component)
+		return err
+	}
+	logResult(logger, "Whatap", m.Component+" Monitor ConfigMap", op)
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.Name,
+			Namespace: r.DefaultNamespace,
+		},
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, deploy, func() error {
+		if deploy.Labels == nil {
+			deploy.Labels = map[string]string{}
+		}
+		deploy.Labels["app"] = m.Name
+		if err := controllerutil.SetControllerReference(cr, deploy, r.Scheme); err != nil {
+			return err
+		}
+
+		newSpec := getControlPlaneMonitorDeploymentSpec(m, cr)
+		for i := range newSpec.Template.Spec.Containers {
+			applyContainerDefaults(&newSpec.Template.Spec.Containers[i])
+		}
+		if !deploy.CreationTimestamp.IsZero() {
+			mergePodSpec(&newSpec.Template.Spec, &deploy.Spec.Template.Spec)
+		}
+		deploy.Spec = newSpec
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "Failed to create/update Deployment", "component", m.Component)
+		return err
+	}
+	logResult(logger, "Whatap", m.Component+" Monitor Deployment", op)
 	return nil
 }
+
+func getControlPlaneMonitorDeploymentSpec(m controlPlaneMonitor, cr *monitoringv2alpha1.WhatapAgent) appsv1.DeploymentSpec {
+	labels := map[string]string{"app": m.Name}
+
+	securityContext := &corev1.PodSecurityContext{
+		RunAsNonRoot:   boolPtr(true),
+		RunAsUser:      int64Ptr(1001),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	if m.RunAsRoot {
+		securityContext = &corev1.PodSecurityContext{
+			RunAsUser:      int64Ptr(0),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+
+	volumeMounts := append([]corev1.VolumeMount{
+		{Name: "logs-volume", MountPath: "/app/logs"},
+		{Name: "config-volume", MountPath: "/app/scrape_config.yaml", SubPath: "scrape_config.yaml"},
+	}, m.VolumeMounts...)
+	volumes := append([]corev1.Volume{
+		{Name: "logs-volume", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{Name: "config-volume", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: m.Name + "-config"},
+		}}},
+	}, m.Volumes...)
+
+	var affinity *corev1.Affinity
+	var tolerations []corev1.Toleration
+	if m.ControlPlaneOnly {
+		affinity = controlPlaneNodeAffinity()
+		tolerations = controlPlaneTolerations()
+	}
+
+	return appsv1.DeploymentSpec{
+		Replicas: int32Ptr(1),
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": m.Name},
+		},
+		Strategy: appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{
+				MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+				MaxSurge:       &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+			},
+		},
+		RevisionHistoryLimit:    int32Ptr(10),
+		ProgressDeadlineSeconds: int32Ptr(600),
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: labels,
+			},
+			Spec: corev1.PodSpec{
+				RestartPolicy:                 corev1.RestartPolicyAlways,
+				TerminationGracePeriodSeconds: int64Ptr(30),
+				DNSPolicy:                     corev1.DNSClusterFirst,
+				SchedulerName:                 "default-scheduler",
+				ServiceAccountName:            m.Name + "-sa",
+				ImagePullSecrets:              cr.Spec.Features.OpenAgent.ImagePullSecrets,
+				SecurityContext:               securityContext,
+				Affinity:                      affinity,
+				Tolerations:                   tolerations,
+				Containers: []corev1.Container{
+					{
+						Name:            "whatap-open-agent",
+						Image:           m.Image,
+						ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
+						Env: []corev1.EnvVar{
+							getWhatapLicenseEnvVar(cr),
+							getWhatapHostEnvVar(cr),
+							getWhatapPortEnvVar(cr),
+						},
+						VolumeMounts: volumeMounts,
+					},
+				},
+				Volumes: volumes,
+			},
+		},
+	}
+}
+
+// controlPlaneNodeAffinity requires a node labelled as control-plane (or the
+// legacy master role).
+func controlPlaneNodeAffinity() *corev1.Affinity {
+	var terms []corev1.NodeSelectorTerm
+	for _, key := range []string{"node-role.kubernetes.io/control-plane", "node-role.kubernetes.io/master"} {
+		terms = append(terms, corev1.NodeSelectorTerm{
+			MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: key, Operator: corev1.NodeSelectorOpExists},
+			},
+		})
+	}
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: terms,
+			},
+		},
+	}
+}
+
+func controlPlaneTolerations() []corev1.Toleration {
+	return []corev1.Toleration{
+		{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}
+}
+
+// tlsSecretVolumes mounts every Secret referenced by tls at
+// /etc/ssl/certs/<secret>/<key>, the path convertEndpoints writes into the
+// scrape config.
+func tlsSecretVolumes(tls *monitoringv2alpha1.TLSConfig) ([]corev1.Volume, []corev1.VolumeMount) {
+	if tls == nil {
+		return nil, nil
+	}
+	keysBySecret := map[string][]string{}
+	var order []string
+	for _, sel := range []*monitoringv2alpha1.SecretKeySelector{tls.CASecret, tls.CertSecret, tls.KeySecret} {
+		if sel == nil || sel.Name == "" || sel.Key == "" {
+			continue
+		}
+		if _, ok := keysBySecret[sel.Name]; !ok {
+			order = append(order, sel.Name)
+		}
+		if !contains(keysBySecret[sel.Name], sel.Key) {
+			keysBySecret[sel.Name] = append(keysBySecret[sel.Name], sel.Key)
+		}
+	}
+
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for _, name := range order {
+		var items []corev1.KeyToPath
+		for _, key := range keysBySecret[name] {
+			items = append(items, corev1.KeyToPath{Key: key, Path: key})
+		}
+		volumeName := fmt.Sprintf("tls-secret-%s", name)
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: name, Items: items},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: fmt.Sprintf("/etc/ssl/certs/%s", name),
+			ReadOnly:  true,
+		})
+	}
+	return volumes, mounts
+}
+
+// listControlPlanePods returns the static pods labelled component=<component>
+// in kube-system. It bypasses the cache so no pod informer is started.
+func listControlPlanePods(ctx context.Context, r *WhatapAgentReconciler, component string) ([]corev1.Pod, error) {
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace("kube-system"), client.MatchingLabels{"component": component}); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// etcdUsesSecretCerts reports whether the client certificate is supplied
+// through Secrets instead of the control-plane hostPath.
+func etcdUsesSecretCerts(spec monitoringv2alpha1.EtcdMonitoringSpec) bool {
+	return spec.TLSConfig != nil && spec.TLSConfig.CertSecret != nil && spec.TLSConfig.KeySecret != nil
+}
+
+// getEtcdTLSConfig returns the TLS settings used to scrape etcd. Without
+// certificate Secrets the kubeadm healthcheck client certificate is read from
+// the hostPath mounted at the same location inside the pod.
+func getEtcdTLSConfig(spec monitoringv2alpha1.EtcdMonitoringSpec) *monitoringv2alpha1.TLSConfig {
+	if etcdUsesSecretCerts(spec) {
+		return spec.TLSConfig
+	}
+	certPath := spec.CertHostPath
+	if certPath == "" {
+		certPath = defaultEtcdCertHostPath
+	}
+	tls := &monitoringv2alpha1.TLSConfig{
+		CAFile:   certPath + "/ca.crt",
+		CertFile: certPath + "/healthcheck-client.crt",
+		KeyFile:  certPath + "/healthcheck-client.key",
+	}
+	if spec.TLSConfig != nil {
+		tls.InsecureSkipVerify = spec.TLSConfig.InsecureSkipVerify
+		tls.ServerName = spec.TLSConfig.ServerName
+		if spec.TLSConfig.CASecret != nil {
+			tls.CAFile = ""
+			tls.CASecret = spec.TLSConfig.CASecret
+		}
+	}
+	return tls
+}
+
+// getEtcdScrapeTargets returns the OpenAgent target that discovers the etcd
+// static pods (component=etcd in kube-system) and scrapes them over mTLS.
+func getEtcdScrapeTargets(spec monitoringv2alpha1.EtcdMonitoringSpec) []interface{} {
+	port := spec.Port
+	if port == "" {
+		port = defaultEtcdPort
+	}
+	target := map[string]interface{}{
+		"targetName": "etcd",
+		"type":       "PodMonitor",
+		"enabled":    true,
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []string{"kube-system"},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]string{
+				"component": "etcd",
+			},
+		},
+		"endpoints": convertEndpoints([]monitoringv2alpha1.OpenAgentEndpoint{
+			{
+				Port:      port,
+				Path:      "/metrics",
+				Scheme:    "https",
+				Interval:  "30s",
+				TLSConfig: getEtcdTLSConfig(spec),
+			},
+		}),
+	}
+	return []interface{}{toOrderedYAML(target)}
+}
+
 func installEtcdMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
-	return nil
+	spec := cr.Spec.Features.K8sAgent.EtcdMonitoring
+
+	if pods, err := listControlPlanePods(ctx, r, "etcd"); err != nil {
+		logger.Error(err, "Failed to list etcd pods")
+	} else if len(pods) == 0 {
+		logger.Info("No etcd static pods found in kube-system; etcd metrics will not be collected until they appear")
+		r.Recorder.Event(cr, corev1.EventTypeWarning, "EtcdNotFound", "No pods labelled component=etcd found in kube-system")
+	}
+
+	m := controlPlaneMonitor{
+		Name:      cr.ResourceName(etcdMonitorName),
+		Component: "Etcd",
+		Image:     getControlPlaneMonitorImage(spec.AgentComponentSpec, cr),
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "namespaces"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		},
+		Targets: getEtcdScrapeTargets(spec),
+	}
+	m.Volumes, m.VolumeMounts = tlsSecretVolumes(spec.TLSConfig)
+	if !etcdUsesSecretCerts(spec) {
+		certPath := spec.CertHostPath
+		if certPath == "" {
+			certPath = defaultEtcdCertHostPath
+		}
+		m.Volumes = append(m.Volumes, corev1.Volume{
+			Name: "etcd-certs",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: certPath},
+			},
+		})
+		m.VolumeMounts = append(m.VolumeMounts, corev1.VolumeMount{
+			Name:      "etcd-certs",
+			MountPath: certPath,
+			ReadOnly:  true,
+		})
+		m.ControlPlaneOnly = true
+		m.RunAsRoot = true
+	}
+	return reconcileControlPlaneMonitor(ctx, r, logger, cr, m)
+}
+
+// controlPlaneNotFoundError reports that a control-plane component has no
+// discoverable static pods, which is the normal state on managed clusters
+// (EKS, GKE, AKS) where the control plane is hidden from the user.
+type controlPlaneNotFoundError struct {
+	component string
+}
+
+func (e *controlPlaneNotFoundError) Error() string {
+	return fmt.Sprintf("no %s static pods found in kube-system; the control plane may be managed by the provider", e.component)
 }
+
+// controlPlaneUnreachableError reports that the metrics port of a control-plane
+// component does not accept connections on the pod IPs the monitor scrapes. The
+// default kubeadm scheduler and controller-manager bind 127.0.0.1 only; they need
+// --bind-address=0.0.0.0 to be scraped.
+type controlPlaneUnreachableError struct {
+	component string
+	addresses []string
+	err       error
+}
+
+func (e *controlPlaneUnreachableError) Error() string {
+	return fmt.Sprintf("%s metrics are not reachable on %s (%v); the component may listen on 127.0.0.1 only, set --bind-address=0.0.0.0",
+		e.component, strings.Join(e.addresses, ", "), e.err)
+}
+
+// controlPlaneProbeTimeout bounds probeControlPlaneMetrics as a whole
+const controlPlaneProbeTimeout = 2 * time.Second
+
+// probeControlPlaneMetrics tries a TCP connection to port on every running pod of a
+// control-plane component, the addresses the monitor scrapes. The pods are dialed
+// concurrently under one deadline, so a reconcile waits at most controlPlaneProbeTimeout
+// per component. It returns a *controlPlaneUnreachableError when no pod accepts a
+// connection. Pods without an IP yet are not judged.
+func probeControlPlaneMetrics(ctx context.Context, component, port string, pods []corev1.Pod) error {
+	var addresses []string
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+			addresses = append(addresses, net.JoinHostPort(pod.Status.PodIP, port))
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, controlPlaneProbeTimeout)
+	defer cancel()
+	errs := make(chan error, len(addresses))
+	for _, address := range addresses {
+		go func(address string) {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err == nil {
+				conn.Close()
+			}
+			errs <- err
+		}(address)
+	}
+	var lastErr error
+	for range addresses {
+		if lastErr = <-errs; lastErr == nil {
+			// The remaining dials are canceled by the deferred cancel
+			return nil
+		}
+	}
+	return &controlPlaneUnreachableError{component: component, addresses: addresses, err: lastErr}
+}
+
+// getSecureControlPlaneScrapeTargets returns the OpenAgent target for a
+// component that serves /metrics on a secure port and authorizes requests with
+// a ServiceAccount bearer token. Serving certificates are self-signed by
+// default, so verification is skipped.
+func getSecureControlPlaneScrapeTargets(component, port string) []interface{} {
+	target := map[string]interface{}{
+		"targetName": component,
+		"type":       "PodMonitor",
+		"enabled":    true,
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []string{"kube-system"},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]string{
+				"component": component,
+			},
+		},
+		"endpoints": convertEndpoints([]monitoringv2alpha1.OpenAgentEndpoint{
+			{
+				Port:     port,
+				Path:     "/metrics",
+				Scheme:   "https",
+				Interval: "30s",
+				TLSConfig: &monitoringv2alpha1.TLSConfig{
+					InsecureSkipVerify: true,
+				},
+				Authorization: &monitoringv2alpha1.AuthorizationConfig{
+					Type:            "Bearer",
+					CredentialsFile: serviceAccountTokenPath,
+				},
+			},
+		}),
+	}
+	return []interface{}{toOrderedYAML(target)}
+}
+
+// installSecureControlPlaneMonitor discovers the static pods of component and
+// deploys a monitor pinned to control-plane nodes. It returns a
+// *controlPlaneNotFoundError when no pods are found, and a
+// *controlPlaneUnreachableError when their metrics port refuses connections, so
+// the caller can surface them as a condition instead of failing the whole reconcile.
+func installSecureControlPlaneMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent,
+	name, displayName, component, port string, spec monitoringv2alpha1.AgentComponentSpec) error {
+	pods, err := listControlPlanePods(ctx, r, component)
+	if err != nil {
+		return fmt.Errorf("failed to discover %s pods: %w", component, err)
+	}
+	if len(pods) == 0 {
+		return &controlPlaneNotFoundError{component: component}
+	}
+	logger.V(1).Info("Discovered control plane pods", "component", component, "count", len(pods))
+
+	err = reconcileControlPlaneMonitor(ctx, r, logger, cr, controlPlaneMonitor{
+		Name:      name,
+		Component: displayName,
+		Image:     getControlPlaneMonitorImage(spec, cr),
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "namespaces"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		},
+		Targets:          getSecureControlPlaneScrapeTargets(component, port),
+		ControlPlaneOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	// The monitor stays deployed so that it starts scraping once the bind address is fixed
+	return probeControlPlaneMetrics(ctx, component, port, pods)
+}
+
 func installSchedulerMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
-	return nil
+	return installSecureControlPlaneMonitor(ctx, r, logger, cr,
+		cr.ResourceName(schedulerMonitorName), "Scheduler", "kube-scheduler", "10259",
+		cr.Spec.Features.K8sAgent.SchedulerMonitoring)
+}
+
+func installControllerManagerMonitor(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
+	return installSecureControlPlaneMonitor(ctx, r, logger, cr,
+		cr.ResourceName(controllerManagerMonitorName), "ControllerManager", "kube-controller-manager", "10257",
+		cr.Spec.Features.K8sAgent.ControllerManagerMonitoring)
 }
 
 // Helper to convert LabelSelector to interface{}
@@ -1574,8 +2283,7 @@ func generateScrapeConfig(cr *monitoringv2alpha1.WhatapAgent, defaultNamespace s
 			targetMap := make(map[string]interface{})
 
 			// Generate target name: <Namespace>/<Name>
-			targetName := fmt.Sprintf("%s/%s", monitor.Namespace, monitor.Name)
-			targetMap["targetName"] = targetName
+			targetMap["targetName"] = monitorTargetName(monitor.Namespace, monitor.Name)
 			targetMap["type"] = "PodMonitor"
 			targetMap["enabled"] = true
 
@@ -1642,8 +2350,7 @@ func generateScrapeConfig(cr *monitoringv2alpha1.WhatapAgent, defaultNamespace s
 		for _, monitor := range serviceMonitors.Items {
 			targetMap := make(map[string]interface{})
 
-			targetName := fmt.Sprintf("%s/%s", monitor.Namespace, monitor.Name)
-			targetMap["targetName"] = targetName
+			targetMap["targetName"] = monitorTargetName(monitor.Namespace, monitor.Name)
 			targetMap["type"] = "ServiceMonitor"
 			targetMap["enabled"] = true
 
@@ -1710,8 +2417,7 @@ func generateScrapeConfig(cr *monitoringv2alpha1.WhatapAgent, defaultNamespace s
 			targetMap := make(map[string]interface{})
 
 			// Generate target name: <Namespace>/<Name>
-			targetName := fmt.Sprintf("%s/%s", se.Namespace, se.Name)
-			targetMap["targetName"] = targetName
+			targetMap["targetName"] = monitorTargetName(se.Namespace, se.Name)
 			targetMap["type"] = "StaticEndpoints"
 			targetMap["enabled"] = true
 
@@ -1743,6 +2449,11 @@ func generateScrapeConfig(cr *monitoringv2alpha1.WhatapAgent, defaultNamespace s
 		}
 	}
 
+	// Pods and services annotated with prometheus.io/scrape
+	for _, targetMap := range annotationDiscoveryTargets(cr.Spec.Features.OpenAgent.AnnotationDiscovery) {
+		config.Features.OpenAgent.Targets = append(config.Features.OpenAgent.Targets, toOrderedYAML(targetMap))
+	}
+
 	// Auto-add GPU monitoring target if gpuMonitoring is enabled
 	if cr.Spec.Features.K8sAgent.GpuMonitoring.Enabled {
 		// Determine correct agent name
@@ -1750,9 +2461,9 @@ func generateScrapeConfig(cr *monitoringv2alpha1.WhatapAgent, defaultNamespace s
 		hasNodeSelector := len(gpuSpec.NodeSelector) > 0
 		hasAffinity := gpuSpec.Affinity != nil && gpuSpec.Affinity.NodeAffinity != nil && gpuSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil
 
-		targetAgentName := "whatap-node-agent"
+		targetAgentName := cr.ResourceName("whatap-node-agent")
 		if hasNodeSelector || hasAffinity {
-			targetAgentName = "whatap-node-agent-gpu"
+			targetAgentName = cr.ResourceName("whatap-node-agent-gpu")
 		}
 
 		// Prepare agent labels for duplicate check
@@ -2033,14 +2744,15 @@ func collectTLSSecrets(targets []monitoringv2alpha1.OpenAgentTargetSpec) map[str
 }
 
 // collectAllTLSSecrets collects TLS Secrets from inline OpenAgent targets as well
-// as from separate WhatapPodMonitor / WhatapServiceMonitor CRs. The path conversion
-// (caSecret -> caFile) in generateScrapeConfig already covers monitor CRs, so the
+// as from separate WhatapPodMonitor / WhatapServiceMonitor / WhatapStaticEndpoint CRs.
+// The path conversion (caSecret -> caFile) in generateScrapeConfig already covers monitor CRs, so the
 // corresponding Secret volumes must be mounted for those targets too; otherwise the
 // cert files referenced in scrape_config never appear in the pod.
 func collectAllTLSSecrets(
 	targets []monitoringv2alpha1.OpenAgentTargetSpec,
 	podMonitors *monitoringv2alpha1.WhatapPodMonitorList,
 	serviceMonitors *monitoringv2alpha1.WhatapServiceMonitorList,
+	staticEndpoints *monitoringv2alpha1.WhatapStaticEndpointList,
 ) map[string][]string {
 	secrets := make(map[string][]string)
 	for _, target := range targets {
@@ -2056,6 +2768,11 @@ func collectAllTLSSecrets(
 			addTLSSecretsFromEndpoints(secrets, monitor.Spec.Endpoints)
 		}
 	}
+	if staticEndpoints != nil {
+		for _, se := range staticEndpoints.Items {
+			addTLSSecretsFromEndpoints(secrets, se.Spec.Endpoints)
+		}
+	}
 	return secrets
 }
 
@@ -2069,11 +2786,50 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// monitorBelongsTo reports whether a monitor CR with the given labels is
+// handled by cr. Monitors select an instance with the
+// monitoring.whatap.com/instance label; unlabeled monitors belong to the
+// default "whatap" instance.
+func monitorBelongsTo(cr *monitoringv2alpha1.WhatapAgent, labels map[string]string) bool {
+	instance := labels[monitoringv2alpha1.InstanceLabelKey]
+	if instance == "" {
+		instance = monitoringv2alpha1.DefaultWhatapAgentName
+	}
+	return instance == cr.Name
+}
+
+// filterMonitorsForInstance drops, in place, the monitors owned by other instances.
+func filterMonitorsForInstance(cr *monitoringv2alpha1.WhatapAgent, podMonitors *monitoringv2alpha1.WhatapPodMonitorList, serviceMonitors *monitoringv2alpha1.WhatapServiceMonitorList, staticEndpoints *monitoringv2alpha1.WhatapStaticEndpointList) {
+	pms := podMonitors.Items[:0]
+	for _, m := range podMonitors.Items {
+		if monitorBelongsTo(cr, m.Labels) {
+			pms = append(pms, m)
+		}
+	}
+	podMonitors.Items = pms
+
+	sms := serviceMonitors.Items[:0]
+	for _, m := range serviceMonitors.Items {
+		if monitorBelongsTo(cr, m.Labels) {
+			sms = append(sms, m)
+		}
+	}
+	serviceMonitors.Items = sms
+
+	ses := staticEndpoints.Items[:0]
+	for _, m := range staticEndpoints.Items {
+		if monitorBelongsTo(cr, m.Labels) {
+			ses = append(ses, m)
+		}
+	}
+	staticEndpoints.Items = ses
+}
+
 func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent) error {
 	// Create ServiceAccount
 	sa := &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "whatap-open-agent-sa",
+			Name:      cr.ResourceName("whatap-open-agent-sa"),
 			Namespace: r.DefaultNamespace,
 		},
 	}
@@ -2092,7 +2848,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 	// Create ClusterRole
 	cr1 := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "whatap-open-agent-role",
+			Name: cr.ResourceName("whatap-open-agent-role"),
 		},
 	}
 	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, cr1, func() error {
@@ -2126,7 +2882,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 	// Create ClusterRoleBinding
 	crb := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "whatap-open-agent-role-binding",
+			Name: cr.ResourceName("whatap-open-agent-role-binding"),
 		},
 	}
 	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, crb, func() error {
@@ -2136,13 +2892,13 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 		crb.Subjects = []rbacv1.Subject{
 			{
 				Kind:      "ServiceAccount",
-				Name:      "whatap-open-agent-sa",
+				Name:      cr.ResourceName("whatap-open-agent-sa"),
 				Namespace: r.DefaultNamespace,
 			},
 		}
 		crb.RoleRef = rbacv1.RoleRef{
 			Kind:     "ClusterRole",
-			Name:     "whatap-open-agent-role",
+			Name:     cr.ResourceName("whatap-open-agent-role"),
 			APIGroup: "rbac.authorization.k8s.io",
 			// API Group should be rbac.authorization.k8s.io
 		}
@@ -2175,19 +2931,53 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 		return err
 	}
 
+	// Each monitor CR is consumed by exactly one WhatapAgent instance
+	filterMonitorsForInstance(cr, podMonitors, serviceMonitors, staticEndpoints)
+
+	// Prometheus Operator resources are added as if they were WhaTap monitor CRs
+	if err := r.importPrometheusOperatorMonitors(ctx, logger, cr, podMonitors, serviceMonitors, staticEndpoints); err != nil {
+		logger.Error(err, "Failed to import Prometheus Operator resources")
+		return err
+	}
+
+	// One ConfigMap and Deployment per shard; shard 0 keeps the unsharded names. The
+	// shards of a previous shard count stop first so that no target is scraped twice.
+	shards := openAgentShards(cr)
+	if err := r.drainOpenAgentShards(ctx, logger, cr, shards); err != nil {
+		return err
+	}
+	for shard := 0; shard < shards; shard++ {
+		if err := reconcileOpenAgentShard(ctx, r, logger, cr, shard, shards, podMonitors, serviceMonitors, staticEndpoints); err != nil {
+			return err
+		}
+	}
+	return r.cleanupOpenAgentShards(ctx, cr, shards)
+}
+
+// reconcileOpenAgentShard creates or updates the ConfigMap and Deployment of one OpenAgent
+// shard. The scrape config of a shard keeps only the targets whose address hashes to it.
+func reconcileOpenAgentShard(ctx context.Context, r *WhatapAgentReconciler, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent, shard, shards int, podMonitors *monitoringv2alpha1.WhatapPodMonitorList, serviceMonitors *monitoringv2alpha1.WhatapServiceMonitorList, staticEndpoints *monitoringv2alpha1.WhatapStaticEndpointList) error {
 	// Create ConfigMap
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "whatap-open-agent-config",
+			Name:      openAgentShardName(cr, "whatap-open-agent-config", shard),
 			Namespace: r.DefaultNamespace,
 		},
 	}
-	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
 		if err := controllerutil.SetControllerReference(cr, cm, r.Scheme); err != nil {
 			return err
 		}
 		// Generate scrape_config.yaml content from CR
 		scrapeConfig := generateScrapeConfig(cr, r.DefaultNamespace, podMonitors, serviceMonitors, staticEndpoints)
+		if shards > 1 {
+			sharded, err := shardScrapeConfig(scrapeConfig, shard, shards)
+			if err != nil {
+				return err
+			}
+			scrapeConfig = sharded
+		}
+		cm.Labels = setOpenAgentShardLabel(cm.Labels, shard, shards)
 		cm.Data = map[string]string{
 			"scrape_config.yaml": scrapeConfig,
 		}
@@ -2209,7 +2999,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 		// Get a fresh deployment object for each retry
 		deploy := &appsv1.Deployment{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      "whatap-open-agent",
+				Name:      openAgentShardName(cr, "whatap-open-agent", shard),
 				Namespace: r.DefaultNamespace,
 			},
 		}
@@ -2222,7 +3012,8 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 			if deploy.Labels == nil {
 				deploy.Labels = map[string]string{}
 			}
-			deploy.Labels["app"] = "whatap-open-agent"
+			deploy.Labels["app"] = openAgentShardName(cr, "whatap-open-agent", shard)
+			deploy.Labels = setOpenAgentShardLabel(deploy.Labels, shard, shards)
 
 			// Apply custom labels if provided
 			if openAgentSpec.Labels != nil {
@@ -2246,7 +3037,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 			}
 
 			// Create base labels for pod template
-			podLabels := map[string]string{"app": "whatap-open-agent"}
+			podLabels := map[string]string{"app": openAgentShardName(cr, "whatap-open-agent", shard)}
 			if openAgentSpec.PodLabels != nil {
 				for k, v := range openAgentSpec.PodLabels {
 					podLabels[k] = v
@@ -2261,6 +3052,14 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 					podAnnotations[k] = v
 				}
 			}
+			// The scrape config is mounted with subPath and is not refreshed in place:
+			// a change of the shard count restarts the pods so they load their new shard
+			if shards > 1 {
+				if podAnnotations == nil {
+					podAnnotations = make(map[string]string)
+				}
+				podAnnotations[openAgentShardsAnnotation] = strconv.Itoa(shards)
+			}
 
 			// Prepare volumes and volume mounts
 			volumes := []corev1.Volume{
@@ -2275,7 +3074,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 					VolumeSource: corev1.VolumeSource{
 						ConfigMap: &corev1.ConfigMapVolumeSource{
 							LocalObjectReference: corev1.LocalObjectReference{
-								Name: "whatap-open-agent-config",
+								Name: openAgentShardName(cr, "whatap-open-agent-config", shard),
 							},
 						},
 					},
@@ -2295,9 +3094,9 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 			}
 
 			// Add TLS Secret volumes and volume mounts.
-			// Include TLS secrets from separate WhatapPodMonitor/WhatapServiceMonitor CRs,
-			// not just inline targets, so their cert files are actually mounted.
-			tlsSecrets := collectAllTLSSecrets(cr.Spec.Features.OpenAgent.Targets, podMonitors, serviceMonitors)
+			// Include TLS secrets from separate monitor CRs, not just inline targets,
+			// so their cert files are actually mounted.
+			tlsSecrets := collectAllTLSSecrets(cr.Spec.Features.OpenAgent.Targets, podMonitors, serviceMonitors, staticEndpoints)
 			for secretName, secretKeys := range tlsSecrets {
 				volumeName := fmt.Sprintf("tls-secret-%s", secretName)
 
@@ -2337,16 +3136,10 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 				Replicas: int32Ptr(1),
 				Selector: &metav1.LabelSelector{
 					MatchLabels: map[string]string{
-						"app": "whatap-open-agent",
-					},
-				},
-				Strategy: appsv1.DeploymentStrategy{
-					Type: appsv1.RollingUpdateDeploymentStrategyType,
-					RollingUpdate: &appsv1.RollingUpdateDeployment{
-						MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
-						MaxSurge:       &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+						"app": openAgentShardName(cr, "whatap-open-agent", shard),
 					},
 				},
+				Strategy:                openAgentStrategy(shards),
 				RevisionHistoryLimit:    int32Ptr(10),
 				ProgressDeadlineSeconds: int32Ptr(600),
 				Template: corev1.PodTemplateSpec{
@@ -2359,7 +3152,7 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 						TerminationGracePeriodSeconds: int64Ptr(30),
 						DNSPolicy:                     corev1.DNSClusterFirst,
 						SchedulerName:                 "default-scheduler",
-						ServiceAccountName:            "whatap-open-agent-sa",
+						ServiceAccountName:            cr.ResourceName("whatap-open-agent-sa"),
 						// Apply tolerations from CR if specified
 						Tolerations: openAgentSpec.Tolerations,
 						// Scheduling and image settings from CR if specified
@@ -2382,10 +3175,11 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 						}(),
 						Containers: []corev1.Container{
 							{
-								Name:    "whatap-open-agent",
-								Image:   getOpenAgentImage(openAgentSpec),
-								Command: getOpenAgentCommand(openAgentSpec),
-								Args:    getOpenAgentArgs(openAgentSpec),
+								Name:            "whatap-open-agent",
+								Image:           getOpenAgentImage(openAgentSpec, cr),
+								ImagePullPolicy: cr.PullPolicy(corev1.PullIfNotPresent),
+								Command:         getOpenAgentCommand(openAgentSpec),
+								Args:            getOpenAgentArgs(openAgentSpec),
 								Env: append([]corev1.EnvVar{
 									getWhatapLicenseEnvVar(cr),
 									getWhatapHostEnvVar(cr),
@@ -2475,7 +3269,8 @@ func installOpenAgent(ctx context.Context, r *WhatapAgentReconciler, logger logr
 
 // Helper functions to get environment variables for Whatap credentials
 // These functions check if the values are provided in the CR spec, and if not,
-// they use the values from the whatap-credentials secret
+// they use the values from the whatap-credentials secret (prefixed with the CR
+// name for instances other than the default "whatap")
 
 func getWhatapLicenseEnvVar(cr *monitoringv2alpha1.WhatapAgent) corev1.EnvVar {
 	if cr.Spec.License != "" {
@@ -2486,7 +3281,7 @@ func getWhatapLicenseEnvVar(cr *monitoringv2alpha1.WhatapAgent) corev1.EnvVar {
 		ValueFrom: &corev1.EnvVarSource{
 			SecretKeyRef: &corev1.SecretKeySelector{
 				LocalObjectReference: corev1.LocalObjectReference{
-					Name: "whatap-credentials",
+					Name: cr.ResourceName("whatap-credentials"),
 				},
 				Key: "WHATAP_LICENSE",
 			},
@@ -2503,7 +3298,7 @@ func getWhatapHostEnvVar(cr *monitoringv2alpha1.WhatapAgent) corev1.EnvVar {
 		ValueFrom: &corev1.EnvVarSource{
 			SecretKeyRef: &corev1.SecretKeySelector{
 				LocalObjectReference: corev1.LocalObjectReference{
-					Name: "whatap-credentials",
+					Name: cr.ResourceName("whatap-credentials"),
 				},
 				Key: "WHATAP_HOST",
 			},
@@ -2520,7 +3315,7 @@ func getWhatapPortEnvVar(cr *monitoringv2alpha1.WhatapAgent) corev1.EnvVar {
 		ValueFrom: &corev1.EnvVarSource{
 			SecretKeyRef: &corev1.SecretKeySelector{
 				LocalObjectReference: corev1.LocalObjectReference{
-					Name: "whatap-credentials",
+					Name: cr.ResourceName("whatap-credentials"),
 				},
 				Key: "WHATAP_PORT",
 			},
@@ -2548,15 +3343,15 @@ func getOpenAgentArgs(spec monitoringv2alpha1.OpenAgentSpec) []string {
 // getOpenAgentImage returns the image string for the OpenAgent
 // If a full custom image name is provided in the CR, it will use that
 // Otherwise, if custom image name or version is provided, it will use those values
-// Otherwise, it falls back to the default values
-func getOpenAgentImage(spec monitoringv2alpha1.OpenAgentSpec) string {
+// Otherwise, it falls back to the default values (with spec.imageRegistry applied)
+func getOpenAgentImage(spec monitoringv2alpha1.OpenAgentSpec, cr *monitoringv2alpha1.WhatapAgent) string {
 	// Check if a full custom image name is provided
 	if spec.CustomImageFullName != "" {
 		return spec.CustomImageFullName
 	}
 
 	// Otherwise, use the separate name and version fields
-	imageName := "public.ecr.aws/whatap/open_agent"
+	imageName := cr.Image(defaultOpenAgentImage)
 	imageVersion := "latest"
 
 	if spec.ImageName != "" {
@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"net"
 	"testing"
 
+	"github.com/go-logr/logr"
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func TestGetNodeAgentDaemonSetSpec_GpuLabel(t *testing.T) {
@@ -291,3 +297,106 @@ func TestAddDcgmExporterToNodeAgent_EmptyRemoteHostEngineUsesEmbeddedMode(t *tes
 
 	t.Fatal("expected dcgm-exporter container")
 }
+
+func TestResourceNamesPerInstance(t *testing.T) {
+	def := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "whatap"}}
+	tenant := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+
+	if got := def.ResourceName("whatap-master-agent"); got != "whatap-master-agent" {
+		t.Errorf("Expected default instance to keep legacy name, got %q", got)
+	}
+	if got := tenant.ResourceName("whatap-master-agent"); got != "tenant-whatap-master-agent" {
+		t.Errorf("Expected prefixed name, got %q", got)
+	}
+	if env := getWhatapLicenseEnvVar(tenant); env.ValueFrom.SecretKeyRef.Name != "tenant-whatap-credentials" {
+		t.Errorf("Expected per-instance credentials secret, got %q", env.ValueFrom.SecretKeyRef.Name)
+	}
+}
+
+func TestFilterMonitorsForInstance(t *testing.T) {
+	tenant := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	podMonitors := &monitoringv2alpha1.WhatapPodMonitorList{
+		Items: []monitoringv2alpha1.WhatapPodMonitor{
+			{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "mine", Labels: map[string]string{monitoringv2alpha1.InstanceLabelKey: "tenant"}}},
+		},
+	}
+	serviceMonitors := &monitoringv2alpha1.WhatapServiceMonitorList{}
+	staticEndpoints := &monitoringv2alpha1.WhatapStaticEndpointList{}
+
+	filterMonitorsForInstance(tenant, podMonitors, serviceMonitors, staticEndpoints)
+	if len(podMonitors.Items) != 1 || podMonitors.Items[0].Name != "mine" {
+		t.Errorf("Expected only the labeled monitor for tenant, got %+v", podMonitors.Items)
+	}
+}
+
+func TestProbeControlPlaneMetrics(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on 127.0.0.1")
+	}
+	defer listener.Close()
+	_, openPort, _ := net.SplitHostPort(listener.Addr().String())
+
+	// A port nobody listens on, like a scheduler bound to 127.0.0.1 seen from its pod IP
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	running := []corev1.Pod{{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.1"}}}
+	ctx := context.Background()
+
+	if err := probeControlPlaneMetrics(ctx, "kube-scheduler", openPort, running); err != nil {
+		t.Errorf("Expected a listening port to be reachable, got %v", err)
+	}
+	var unreachable *controlPlaneUnreachableError
+	if err := probeControlPlaneMetrics(ctx, "kube-scheduler", closedPort, running); !errors.As(err, &unreachable) {
+		t.Errorf("Expected a closed port to be reported unreachable, got %v", err)
+	}
+	// One reachable pod is enough, whatever the others do
+	mixed := []corev1.Pod{
+		{Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.2"}},
+		running[0],
+	}
+	if err := probeControlPlaneMetrics(ctx, "kube-scheduler", openPort, mixed); err != nil {
+		t.Errorf("Expected one listening pod to make the component reachable, got %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := probeControlPlaneMetrics(canceled, "kube-scheduler", openPort, running); !errors.As(err, &unreachable) {
+		t.Errorf("Expected the probe to stop when its context is done, got %v", err)
+	}
+	pending := []corev1.Pod{{Status: corev1.PodStatus{Phase: corev1.PodPending}}}
+	if err := probeControlPlaneMetrics(ctx, "kube-scheduler", closedPort, pending); err != nil {
+		t.Errorf("Expected pods without an IP not to be judged, got %v", err)
+	}
+}
+
+func TestDcgmExporterServiceSelectsItsInstance(t *testing.T) {
+	for _, name := range []string{monitoringv2alpha1.DefaultWhatapAgentName, "tenant"} {
+		cr := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name)}}
+		cr.Spec.Features.K8sAgent.GpuMonitoring.Enabled = true
+		c := newFakeClient()
+		r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, DefaultNamespace: "whatap-monitoring"}
+
+		if err := ensureDcgmExporterService(context.Background(), r, logr.Discard(), cr); err != nil {
+			t.Fatal(err)
+		}
+		svc := &corev1.Service{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "whatap-monitoring", Name: cr.ResourceName("dcgm-exporter-service")}, svc); err != nil {
+			t.Fatal(err)
+		}
+		if svc.Spec.Selector[monitoringv2alpha1.InstanceLabelKey] != name {
+			t.Errorf("%s: expected the Service to select the dcgm-exporter pods of its instance only, got %v", name, svc.Spec.Selector)
+		}
+		podLabels := getNodeAgentDaemonSetSpec("test-image", &corev1.ResourceRequirements{}, cr, cr.ResourceName("whatap-node-agent"), true).Template.Labels
+		for k, v := range svc.Spec.Selector {
+			if podLabels[k] != v {
+				t.Errorf("%s: expected the dcgm-exporter pods to carry %s=%s, got %v", name, k, v, podLabels)
+			}
+		}
+	}
+}
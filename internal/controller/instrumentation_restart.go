@@ -0,0 +1,472 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+const (
+	restartPolicyOnChange       = "onChange"
+	defaultRestartMaxConcurrent = 1
+	defaultRestartMinInterval   = time.Minute
+
+	// annotationRestartedAt is the pod template annotation `kubectl rollout restart` uses
+	annotationRestartedAt = "kubectl.kubernetes.io/restartedAt"
+	// annotationInstrumentationHash records the target hash a workload was last restarted for
+	annotationInstrumentationHash = "monitoring.whatap.com/instrumentation-hash"
+)
+
+// instrumentedWorkload is a Deployment, StatefulSet or DaemonSet whose pod template
+// matches an instrumentation target.
+type instrumentedWorkload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Target is the rollout key of the target injecting the pod template
+	Target string
+	// Hash is the current hash of that target
+	Hash string
+	// AppliedHash is the target hash the workload was last restarted for
+	AppliedHash string
+	// TemplateLabels are the labels of the pod template
+	TemplateLabels map[string]string
+}
+
+func (w instrumentedWorkload) ref() string {
+	return w.Kind + "/" + w.Namespace + "/" + w.Name
+}
+
+// rolloutTargetKey identifies a target in the recorded rollout state, as "instance/target".
+func rolloutTargetKey(cr *monitoringv2alpha1.WhatapAgent, target monitoringv2alpha1.TargetSpec) string {
+	return cr.Name + "/" + target.Name
+}
+
+// targetHash returns a stable hash of everything in target (and the instrumentation-level
+// init container settings) that ends up in an injected pod. The instance name is part of
+// the hash, so that the same target declared by two instances is told apart.
+func targetHash(cr *monitoringv2alpha1.WhatapAgent, target monitoringv2alpha1.TargetSpec) string {
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+	b, _ := json.Marshal(struct {
+		Instance  string                                        `json:"instance"`
+		Target    monitoringv2alpha1.TargetSpec                 `json:"target"`
+		Mode      string                                        `json:"mode"`
+		Security  *monitoringv2alpha1.InitContainerSecuritySpec `json:"security,omitempty"`
+		Resources *corev1.ResourceRequirements                  `json:"resources,omitempty"`
+	}{cr.Name, target, instrumentation.TargetMode(target), instrumentation.InitContainerSecurity, instrumentation.InitContainerResources})
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// changedTargets compares the current target hashes with the ones recorded in status.
+// A target is changed when its hash differs or it was added after the policy was enabled.
+// The first time the policy is seen (no recorded hashes) nothing is reported as changed,
+// so enabling the policy does not restart every instrumented workload at once. The
+// recorded hashes always hold the instance entry (see currentTargetHashes), so an instance
+// without targets still records that the policy was seen.
+func changedTargets(recorded, current map[string]string) []string {
+	if recorded == nil {
+		return nil
+	}
+	var changed []string
+	for name, hash := range current {
+		if recorded[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// reconcileInstrumentationRestarts rolls out restarts for workloads matched by targets that
+// changed since the last reconcile (restartPolicy onChange) and for workloads still running
+// injected pods of an uninstrumented target, at most MaxConcurrent per MinInterval. It
+// returns the rollout status to store (nil when there is nothing to roll out) and, while
+// restarts are still pending, how long to wait before the next batch.
+func (r *WhatapAgentReconciler) reconcileInstrumentationRestarts(ctx context.Context, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent, now time.Time) (*monitoringv2alpha1.InstrumentationRolloutStatus, time.Duration, error) {
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+	policy := instrumentation.RestartPolicy
+	onChange := policy != nil && policy.Mode == restartPolicyOnChange
+	uninstrumented := uninstrumentedTargets(cr)
+	if !instrumentation.Enabled || (!onChange && len(uninstrumented) == 0) {
+		return nil, 0, nil
+	}
+
+	status := &monitoringv2alpha1.InstrumentationRolloutStatus{}
+	if cr.Status.Rollout != nil {
+		status = cr.Status.Rollout.DeepCopy()
+	}
+
+	current := currentTargetHashes(cr)
+	var changed []string
+	if onChange {
+		changed = changedTargets(status.TargetHashes, current)
+	}
+	if len(changed) > 0 || len(uninstrumented) > 0 {
+		scope, err := r.instrumentationScope(ctx, cr)
+		if err != nil {
+			return cr.Status.Rollout, 0, err
+		}
+		workloads, err := r.listInstrumentedWorkloads(ctx, cr, scope)
+		if err != nil {
+			// keep the recorded hashes so the change is picked up again on retry
+			return cr.Status.Rollout, 0, err
+		}
+		before := len(status.Pending)
+		injected := map[string][]map[string]string{}
+		for _, w := range workloads {
+			if containsString(status.Pending, w.ref()) {
+				continue
+			}
+			switch {
+			case containsString(changed, w.Target):
+			case containsString(uninstrumented, w.Target) && w.AppliedHash != w.Hash:
+				// only restart workloads that still run injected pods
+				hasInjected, err := r.workloadHasInjectedPods(ctx, w, injected)
+				if err != nil {
+					return cr.Status.Rollout, 0, err
+				}
+				if !hasInjected {
+					continue
+				}
+			default:
+				continue
+			}
+			status.Pending = append(status.Pending, w.ref())
+		}
+		if len(status.Pending) > before {
+			// a new rollout starts counting from zero
+			if before == 0 {
+				status.Restarted = 0
+			}
+			logger.Info("Instrumented workloads need a restart", "changedTargets", changed, "uninstrumentedTargets", uninstrumented, "pendingRestarts", len(status.Pending))
+		}
+	}
+	if onChange {
+		status.TargetHashes = current
+	} else {
+		status.TargetHashes = nil
+	}
+
+	if len(status.Pending) == 0 {
+		return status, 0, nil
+	}
+
+	interval := defaultRestartMinInterval
+	if policy != nil && policy.MinInterval != nil {
+		interval = policy.MinInterval.Duration
+	}
+	if status.LastRestartTime != nil {
+		if wait := status.LastRestartTime.Add(interval).Sub(now); wait > 0 {
+			return status, wait, nil
+		}
+	}
+
+	maxConcurrent := defaultRestartMaxConcurrent
+	if policy != nil && policy.MaxConcurrent > 0 {
+		maxConcurrent = int(policy.MaxConcurrent)
+	}
+	if maxConcurrent < 1 {
+		maxConcurrent = defaultRestartMaxConcurrent
+	}
+	batch := status.Pending
+	if len(batch) > maxConcurrent {
+		batch = batch[:maxConcurrent]
+	}
+	var remaining []string
+	remaining = append(remaining, status.Pending[len(batch):]...)
+
+	scope, err := r.instrumentationScope(ctx, cr)
+	if err != nil {
+		return status, 0, err
+	}
+	var restartErr error
+	for _, ref := range batch {
+		restarted, err := r.restartWorkload(ctx, cr, scope, ref, now)
+		if err != nil {
+			// keep it pending and retry with the next batch
+			logger.Error(err, "Failed to restart workload", "workload", ref)
+			remaining = append(remaining, ref)
+			restartErr = err
+			continue
+		}
+		if restarted {
+			logger.Info("Restarted workload for instrumentation change", "workload", ref)
+			r.Recorder.Event(cr, corev1.EventTypeNormal, "RolloutRestart", "Restarted "+ref+" to apply instrumentation changes")
+			status.Restarted++
+		}
+	}
+	status.Pending = remaining
+	status.LastRestartTime = &metav1.Time{Time: now}
+
+	if len(status.Pending) == 0 {
+		return status, 0, restartErr
+	}
+	return status, interval, restartErr
+}
+
+// currentTargetHashes returns the hashes of the enabled targets of cr by rollout key, plus
+// an entry for the instance itself that keeps the map non-empty without targets, so that
+// the first target added later is seen as new rather than as the baseline.
+func currentTargetHashes(cr *monitoringv2alpha1.WhatapAgent) map[string]string {
+	current := map[string]string{cr.Name: targetHash(cr, monitoringv2alpha1.TargetSpec{})}
+	for _, target := range cr.Spec.Features.Apm.Instrumentation.Targets {
+		if target.Enabled {
+			current[rolloutTargetKey(cr, target)] = targetHash(cr, target)
+		}
+	}
+	return current
+}
+
+// uninstrumentedTargets returns the rollout keys of the enabled targets being uninstrumented.
+func uninstrumentedTargets(cr *monitoringv2alpha1.WhatapAgent) []string {
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+	var keys []string
+	for _, target := range instrumentation.Targets {
+		if target.Enabled && instrumentation.TargetMode(target) == monitoringv2alpha1.InstrumentationModeUninstrument {
+			keys = append(keys, rolloutTargetKey(cr, target))
+		}
+	}
+	return keys
+}
+
+// workloadHasInjectedPods reports whether a pod of w still carries the injection
+// annotation. Pods are listed once per namespace through the API reader (legacy injected
+// pods lack the cached label); cache keeps the labels of injected pods per namespace.
+func (r *WhatapAgentReconciler) workloadHasInjectedPods(ctx context.Context, w instrumentedWorkload, cache map[string][]map[string]string) (bool, error) {
+	podLabels, ok := cache[w.Namespace]
+	if !ok {
+		reader := r.APIReader
+		if reader == nil {
+			reader = r.Client
+		}
+		var pods corev1.PodList
+		if err := reader.List(ctx, &pods, client.InNamespace(w.Namespace)); err != nil {
+			return false, fmt.Errorf("failed to list pods in %s: %w", w.Namespace, err)
+		}
+		podLabels = []map[string]string{}
+		for _, pod := range pods.Items {
+			if pod.Annotations[monitoringv2alpha1.AnnotationApmInjected] == "true" {
+				podLabels = append(podLabels, pod.Labels)
+			}
+		}
+		cache[w.Namespace] = podLabels
+	}
+	selector := labels.SelectorFromSet(w.TemplateLabels)
+	for _, l := range podLabels {
+		if selector.Matches(labels.Set(l)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// restartScope decides which instance injects a pod template, the way the pod webhook
+// does: namespaces excluded from the webhook are never injected, and the oldest instance
+// with a matching target wins.
+type restartScope struct {
+	agents   []*monitoringv2alpha1.WhatapAgent
+	excluded []string
+}
+
+// instrumentationScope lists the WhatapAgents for the restarts of cr. cr replaces its
+// listed copy, so that its current spec is used.
+func (r *WhatapAgentReconciler) instrumentationScope(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent) (*restartScope, error) {
+	agents := &monitoringv2alpha1.WhatapAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		return nil, fmt.Errorf("failed to list WhatapAgents: %w", err)
+	}
+	items := []monitoringv2alpha1.WhatapAgent{*cr}
+	for _, agent := range agents.Items {
+		if agent.Name != cr.Name {
+			items = append(items, agent)
+		}
+	}
+	enabled := instrumentingAgents(items)
+	return &restartScope{agents: enabled, excluded: excludedInjectionNamespaces(enabled, r.DefaultNamespace)}, nil
+}
+
+// target returns the enabled target of cr injecting a pod template in the given namespace,
+// or nil when the namespace is excluded, no target of cr matches, or an older instance
+// injects it.
+func (s *restartScope) target(cr *monitoringv2alpha1.WhatapAgent, namespace string, namespaceLabels, podLabels map[string]string) *monitoringv2alpha1.TargetSpec {
+	if containsString(s.excluded, namespace) {
+		return nil
+	}
+	for _, agent := range s.agents {
+		for i, target := range agent.Spec.Features.Apm.Instrumentation.Targets {
+			if target.Enabled && targetMatches(target, namespace, namespaceLabels, podLabels) {
+				if agent.Name != cr.Name {
+					return nil
+				}
+				return &agent.Spec.Features.Apm.Instrumentation.Targets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// listInstrumentedWorkloads lists the Deployments, StatefulSets and DaemonSets whose pod
+// template is injected by an enabled target of cr (see restartScope). The API reader is
+// used so that no cluster-wide StatefulSet informer is started just for this.
+func (r *WhatapAgentReconciler) listInstrumentedWorkloads(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent, scope *restartScope) ([]instrumentedWorkload, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	var namespaces corev1.NamespaceList
+	if err := reader.List(ctx, &namespaces); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	nsLabels := make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		nsLabels[ns.Name] = ns.Labels
+	}
+
+	var workloads []instrumentedWorkload
+	add := func(kind string, meta metav1.ObjectMeta, template corev1.PodTemplateSpec) {
+		if target := scope.target(cr, meta.Namespace, nsLabels[meta.Namespace], template.Labels); target != nil {
+			workloads = append(workloads, instrumentedWorkload{
+				Kind:           kind,
+				Namespace:      meta.Namespace,
+				Name:           meta.Name,
+				Target:         rolloutTargetKey(cr, *target),
+				Hash:           targetHash(cr, *target),
+				AppliedHash:    template.Annotations[annotationInstrumentationHash],
+				TemplateLabels: template.Labels,
+			})
+		}
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := reader.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		add("Deployment", d.ObjectMeta, d.Spec.Template)
+	}
+	var statefulSets appsv1.StatefulSetList
+	if err := reader.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		add("StatefulSet", s.ObjectMeta, s.Spec.Template)
+	}
+	var daemonSets appsv1.DaemonSetList
+	if err := reader.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, ds := range daemonSets.Items {
+		add("DaemonSet", ds.ObjectMeta, ds.Spec.Template)
+	}
+	return workloads, nil
+}
+
+// targetMatches applies the target's namespace and pod selectors the same way the pod
+// webhook does: empty selectors match everything.
+func targetMatches(target monitoringv2alpha1.TargetSpec, namespace string, namespaceLabels, podLabels map[string]string) bool {
+	if len(target.NamespaceSelector.MatchNames) > 0 && !containsString(target.NamespaceSelector.MatchNames, namespace) {
+		return false
+	}
+	nsSelector, err := toLabelsSelector(target.NamespaceSelector.MatchLabels, target.NamespaceSelector.MatchExpressions)
+	if err != nil || !nsSelector.Matches(labels.Set(namespaceLabels)) {
+		return false
+	}
+	podSelector, err := toLabelsSelector(target.PodSelector.MatchLabels, target.PodSelector.MatchExpressions)
+	return err == nil && podSelector.Matches(labels.Set(podLabels))
+}
+
+func toLabelsSelector(matchLabels map[string]string, expressions []monitoringv2alpha1.LabelSelectorRequirement) (labels.Selector, error) {
+	return metav1.LabelSelectorAsSelector(toMetaLabelSelector(matchLabels, expressions))
+}
+
+func toMetaLabelSelector(matchLabels map[string]string, expressions []monitoringv2alpha1.LabelSelectorRequirement) *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{MatchLabels: matchLabels}
+	for _, e := range expressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      e.Key,
+			Operator: metav1.LabelSelectorOperator(e.Operator),
+			Values:   e.Values,
+		})
+	}
+	return selector
+}
+
+// restartWorkload triggers a rolling restart of the workload identified by ref
+// ("Kind/namespace/name") by patching its pod template annotations, like
+// `kubectl rollout restart`. It returns false when the workload no longer exists,
+// is no longer injected by a target of cr or was already restarted for the current
+// target hash.
+func (r *WhatapAgentReconciler) restartWorkload(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent, scope *restartScope, ref string, now time.Time) (bool, error) {
+	parts := strings.SplitN(ref, "/", 3)
+	if len(parts) != 3 {
+		return false, nil
+	}
+	kind, key := parts[0], client.ObjectKey{Namespace: parts[1], Name: parts[2]}
+
+	var obj client.Object
+	var template *corev1.PodTemplateSpec
+	switch kind {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		obj, template = d, &d.Spec.Template
+	case "StatefulSet":
+		s := &appsv1.StatefulSet{}
+		obj, template = s, &s.Spec.Template
+	case "DaemonSet":
+		ds := &appsv1.DaemonSet{}
+		obj, template = ds, &ds.Spec.Template
+	default:
+		return false, nil
+	}
+
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	if err := reader.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var nsLabels map[string]string
+	var ns corev1.Namespace
+	if err := reader.Get(ctx, client.ObjectKey{Name: key.Namespace}, &ns); err == nil {
+		nsLabels = ns.Labels
+	}
+	target := scope.target(cr, key.Namespace, nsLabels, template.Labels)
+	if target == nil {
+		return false, nil
+	}
+	hash := targetHash(cr, *target)
+	if template.Annotations[annotationInstrumentationHash] == hash {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[annotationRestartedAt] = now.Format(time.RFC3339)
+	template.Annotations[annotationInstrumentationHash] = hash
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
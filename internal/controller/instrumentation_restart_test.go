@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+func restartTestAgent(version string) *monitoringv2alpha1.WhatapAgent {
+	return &monitoringv2alpha1.WhatapAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "whatap"},
+		Spec: monitoringv2alpha1.WhatapAgentSpec{
+			Features: monitoringv2alpha1.FeaturesSpec{
+				Apm: monitoringv2alpha1.ApmSpec{
+					Instrumentation: monitoringv2alpha1.InstrumentationSpec{
+						Enabled: true,
+						RestartPolicy: &monitoringv2alpha1.RestartPolicySpec{
+							Mode:          restartPolicyOnChange,
+							MaxConcurrent: 1,
+							MinInterval:   &metav1.Duration{Duration: time.Minute},
+						},
+						Targets: []monitoringv2alpha1.TargetSpec{{
+							Name:              "java",
+							Enabled:           true,
+							Language:          "java",
+							WhatapApmVersions: map[string]string{"java": version},
+							NamespaceSelector: monitoringv2alpha1.NamespaceSelector{MatchNames: []string{"shop"}},
+							PodSelector:       monitoringv2alpha1.PodSelector{MatchLabels: map[string]string{"app": "orders"}},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func restartTestDeployment(namespace, name, app string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": app}}},
+		},
+	}
+}
+
+func TestReconcileInstrumentationRestarts(t *testing.T) {
+	c := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		restartTestDeployment("shop", "orders", "orders"),
+		restartTestDeployment("shop", "orders-canary", "orders"),
+		restartTestDeployment("shop", "cart", "cart"),
+		restartTestDeployment("other", "orders", "orders"),
+	)
+	r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+	now := time.Now()
+
+	// Enabling the policy only records a baseline.
+	cr := restartTestAgent("2.2.60")
+	status, wait, err := r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, now)
+	if err != nil || wait != 0 || len(status.Pending) != 0 || status.TargetHashes["whatap/java"] == "" {
+		t.Fatalf("Expected a baseline without restarts, got %+v wait=%v err=%v", status, wait, err)
+	}
+
+	// Bumping the version restarts the two matching deployments, one per interval.
+	cr = restartTestAgent("2.2.68")
+	cr.Status.Rollout = status
+	status, wait, err = r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Restarted != 1 || len(status.Pending) != 1 || wait != time.Minute {
+		t.Fatalf("Expected one restart and one pending, got %+v wait=%v", status, wait)
+	}
+
+	cr.Status.Rollout = status
+	status, wait, _ = r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, now.Add(30*time.Second))
+	if status.Restarted != 1 || wait != 30*time.Second {
+		t.Fatalf("Expected the next batch to wait for the interval, got %+v wait=%v", status, wait)
+	}
+
+	cr.Status.Rollout = status
+	status, wait, _ = r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, now.Add(time.Minute))
+	if status.Restarted != 2 || len(status.Pending) != 0 || wait != 0 {
+		t.Fatalf("Expected the rollout to complete, got %+v wait=%v", status, wait)
+	}
+
+	for _, key := range []client.ObjectKey{{Namespace: "shop", Name: "orders"}, {Namespace: "shop", Name: "orders-canary"}} {
+		var d appsv1.Deployment
+		if err := c.Get(ctx, key, &d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Spec.Template.Annotations[annotationInstrumentationHash] != status.TargetHashes["whatap/java"] || d.Spec.Template.Annotations[annotationRestartedAt] == "" {
+			t.Errorf("Expected %s to be restarted, got annotations %v", key, d.Spec.Template.Annotations)
+		}
+	}
+	for _, key := range []client.ObjectKey{{Namespace: "shop", Name: "cart"}, {Namespace: "other", Name: "orders"}} {
+		var d appsv1.Deployment
+		if err := c.Get(ctx, key, &d); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := d.Spec.Template.Annotations[annotationRestartedAt]; ok {
+			t.Errorf("Expected %s not to be restarted", key)
+		}
+	}
+}
+
+func TestTargetMatches(t *testing.T) {
+	target := monitoringv2alpha1.TargetSpec{
+		NamespaceSelector: monitoringv2alpha1.NamespaceSelector{
+			MatchExpressions: []monitoringv2alpha1.LabelSelectorRequirement{{Key: "env", Operator: "In", Values: []string{"prod"}}},
+		},
+		PodSelector: monitoringv2alpha1.PodSelector{
+			MatchExpressions: []monitoringv2alpha1.LabelSelectorRequirement{{Key: "sidecar", Operator: "DoesNotExist"}},
+		},
+	}
+	if !targetMatches(target, "shop", map[string]string{"env": "prod"}, map[string]string{"app": "orders"}) {
+		t.Errorf("Expected target to match")
+	}
+	if targetMatches(target, "shop", map[string]string{"env": "dev"}, map[string]string{"app": "orders"}) {
+		t.Errorf("Expected namespace expression to reject env=dev")
+	}
+	if targetMatches(target, "shop", map[string]string{"env": "prod"}, map[string]string{"sidecar": "true"}) {
+		t.Errorf("Expected pod expression to reject sidecar pods")
+	}
+}
+
+func TestReconcileInstrumentationRestarts_Uninstrument(t *testing.T) {
+	injectedPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "shop",
+		Name:        "orders-abc",
+		Labels:      map[string]string{"app": "orders"},
+		Annotations: map[string]string{monitoringv2alpha1.AnnotationApmInjected: "true"},
+	}}
+	c := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		restartTestDeployment("shop", "orders", "orders"),
+		restartTestDeployment("shop", "orders-canary", "orders-canary"),
+		injectedPod,
+	)
+	r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	// Uninstrumenting restarts workloads without any restartPolicy.
+	cr := restartTestAgent("2.2.68")
+	cr.Spec.Features.Apm.Instrumentation.RestartPolicy = nil
+	cr.Spec.Features.Apm.Instrumentation.Targets[0].PodSelector = monitoringv2alpha1.PodSelector{}
+	cr.Spec.Features.Apm.Instrumentation.Mode = monitoringv2alpha1.InstrumentationModeUninstrument
+
+	status, _, err := r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// orders-canary never ran an injected pod and must not be restarted.
+	if status.Restarted != 1 || len(status.Pending) != 0 {
+		t.Fatalf("Expected only the injected workload to restart, got %+v", status)
+	}
+	var d appsv1.Deployment
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "shop", Name: "orders"}, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Spec.Template.Annotations[annotationRestartedAt] == "" {
+		t.Errorf("Expected shop/orders to be restarted")
+	}
+
+	// Already restarted for the uninstrument hash: nothing left to do.
+	cr.Status.Rollout = status
+	status, _, _ = r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, time.Now().Add(time.Hour))
+	if status.Restarted != 1 || len(status.Pending) != 0 {
+		t.Errorf("Expected no further restarts, got %+v", status)
+	}
+}
+
+func TestReconcileInstrumentationRestarts_FirstTarget(t *testing.T) {
+	c := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		restartTestDeployment("shop", "orders", "orders"),
+	)
+	r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	// The baseline of an instance without targets survives the status round trip
+	cr := restartTestAgent("2.2.68")
+	targets := cr.Spec.Features.Apm.Instrumentation.Targets
+	cr.Spec.Features.Apm.Instrumentation.Targets = nil
+	status, _, err := r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, time.Now())
+	if err != nil || len(status.TargetHashes) == 0 {
+		t.Fatalf("Expected a baseline, got %+v err=%v", status, err)
+	}
+
+	// Adding the first target restarts its workloads
+	cr.Spec.Features.Apm.Instrumentation.Targets = targets
+	cr.Status.Rollout = status
+	status, _, err = r.reconcileInstrumentationRestarts(ctx, logr.Discard(), cr, time.Now())
+	if err != nil || status.Restarted != 1 {
+		t.Fatalf("Expected shop/orders to be restarted, got %+v err=%v", status, err)
+	}
+}
+
+func TestRestartScope(t *testing.T) {
+	older := restartTestAgent("2.2.68")
+	older.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := restartTestAgent("2.2.68")
+	newer.Name = "team-b"
+	newer.CreationTimestamp = metav1.Now()
+	// An empty selector matches every namespace the webhook sees
+	newer.Spec.Features.Apm.Instrumentation.Targets[0].NamespaceSelector = monitoringv2alpha1.NamespaceSelector{}
+	newer.Spec.Features.Apm.Instrumentation.Targets[0].PodSelector = monitoringv2alpha1.PodSelector{}
+
+	r := &WhatapAgentReconciler{Client: newFakeClient(older, newer), Scheme: testScheme, DefaultNamespace: "whatap-monitoring"}
+	scope, err := r.instrumentationScope(context.Background(), newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := map[string]string{"app": "orders"}
+	if target := scope.target(newer, "kube-system", nil, app); target != nil {
+		t.Errorf("Expected kube-system to be excluded, got %+v", target)
+	}
+	if target := scope.target(newer, "whatap-monitoring", nil, app); target != nil {
+		t.Errorf("Expected the operator namespace to be excluded, got %+v", target)
+	}
+	// shop/orders is injected by the older instance
+	if target := scope.target(newer, "shop", nil, app); target != nil {
+		t.Errorf("Expected shop/orders to belong to the older instance, got %+v", target)
+	}
+	if target := scope.target(older, "shop", nil, app); target == nil {
+		t.Errorf("Expected shop/orders to belong to the older instance")
+	}
+	if target := scope.target(newer, "shop", nil, map[string]string{"app": "cart"}); target == nil {
+		t.Errorf("Expected shop/cart to belong to the newer instance")
+	}
+
+	// The same target declared by two instances has a hash per instance
+	same := older.DeepCopy()
+	same.Name = "team-c"
+	if targetHash(older, older.Spec.Features.Apm.Instrumentation.Targets[0]) == targetHash(same, same.Spec.Features.Apm.Instrumentation.Targets[0]) {
+		t.Errorf("Expected identical targets of different instances to hash differently")
+	}
+}
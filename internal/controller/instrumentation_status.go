@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// maxOutdatedPods caps TargetInstrumentationStatus.OutdatedPods to keep the status small
+const maxOutdatedPods = 10
+
+// InstrumentationStatusReconciler maintains WhatapAgent.Status.Instrumentation from the
+// pods the injection webhook labelled. It runs separately from WhatapAgentReconciler so
+// that pod churn does not trigger a full reconcile of every agent component.
+type InstrumentationStatusReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *InstrumentationStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	whatapAgent := &monitoringv2alpha1.WhatapAgent{}
+	if err := r.Get(ctx, req.NamespacedName, whatapAgent); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !whatapAgent.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// The cache only holds pods carrying the injection label (see cmd/main.go)
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.HasLabels{monitoringv2alpha1.InjectionLabelKey}); err != nil {
+		return ctrl.Result{}, err
+	}
+	summary := buildInstrumentationStatus(whatapAgent, pods.Items)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, req.NamespacedName, whatapAgent); err != nil {
+			return err
+		}
+		if instrumentationStatusEqual(whatapAgent.Status.Instrumentation, summary) {
+			return nil
+		}
+		if summary != nil {
+			summary.LastUpdateTime = &metav1.Time{Time: time.Now()}
+		}
+		whatapAgent.Status.Instrumentation = summary
+		return r.Status().Update(ctx, whatapAgent)
+	})
+	if err != nil {
+		logger.Error(err, "Failed to update instrumentation status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// instrumentationStatusEqual compares two summaries ignoring LastUpdateTime.
+func instrumentationStatusEqual(a, b *monitoringv2alpha1.InstrumentationStatus) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return equality.Semantic.DeepEqual(a.Targets, b.Targets)
+}
+
+// buildInstrumentationStatus summarizes the pods that belong to cr per target and namespace.
+// It returns nil when instrumentation is disabled and no pod is attributed to cr.
+func buildInstrumentationStatus(cr *monitoringv2alpha1.WhatapAgent, pods []corev1.Pod) *monitoringv2alpha1.InstrumentationStatus {
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+
+	// Targets keep their spec order; targets only known from pod annotations
+	// (removed from the spec, or forced by annotation) are appended by name.
+	byName := map[string]*monitoringv2alpha1.TargetInstrumentationStatus{}
+	var order []string
+	namespaces := map[string]map[string]*monitoringv2alpha1.NamespaceInstrumentationStatus{}
+	getTarget := func(name, language string) *monitoringv2alpha1.TargetInstrumentationStatus {
+		if t, ok := byName[name]; ok {
+			return t
+		}
+		t := &monitoringv2alpha1.TargetInstrumentationStatus{Name: name, Language: language}
+		byName[name] = t
+		namespaces[name] = map[string]*monitoringv2alpha1.NamespaceInstrumentationStatus{}
+		order = append(order, name)
+		return t
+	}
+	if instrumentation.Enabled {
+		for _, target := range instrumentation.Targets {
+			if !target.Enabled {
+				continue
+			}
+			t := getTarget(target.Name, target.Language)
+			t.DesiredVersion = target.WhatapApmVersions[target.Language]
+			if t.DesiredVersion == "" {
+				t.DesiredVersion = "latest"
+			}
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if !podBelongsTo(cr, pod) || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		name := pod.Annotations[monitoringv2alpha1.AnnotationApmTarget]
+		if name == "" {
+			continue
+		}
+		t := getTarget(name, pod.Annotations[monitoringv2alpha1.AnnotationApmLanguage])
+		ns, ok := namespaces[name][pod.Namespace]
+		if !ok {
+			ns = &monitoringv2alpha1.NamespaceInstrumentationStatus{Namespace: pod.Namespace}
+			namespaces[name][pod.Namespace] = ns
+		}
+
+		t.Matched++
+		ns.Matched++
+		if pod.Labels[monitoringv2alpha1.InjectionLabelKey] != "true" {
+			t.Skipped++
+			ns.Skipped++
+			continue
+		}
+		t.Injected++
+		ns.Injected++
+		version := pod.Annotations[monitoringv2alpha1.AnnotationApmVersion]
+		if t.Versions == nil {
+			t.Versions = map[string]int32{}
+		}
+		t.Versions[version]++
+		if t.DesiredVersion != "" && version != t.DesiredVersion {
+			t.Outdated++
+			ns.Outdated++
+			t.OutdatedPods = append(t.OutdatedPods, pod.Namespace+"/"+pod.Name)
+		}
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+	summary := &monitoringv2alpha1.InstrumentationStatus{}
+	for _, name := range order {
+		t := byName[name]
+		for _, ns := range namespaces[name] {
+			t.Namespaces = append(t.Namespaces, *ns)
+		}
+		sort.Slice(t.Namespaces, func(i, j int) bool { return t.Namespaces[i].Namespace < t.Namespaces[j].Namespace })
+		sort.Strings(t.OutdatedPods)
+		if len(t.OutdatedPods) > maxOutdatedPods {
+			t.OutdatedPods = t.OutdatedPods[:maxOutdatedPods]
+		}
+		summary.Targets = append(summary.Targets, *t)
+	}
+	return summary
+}
+
+// podBelongsTo reports whether pod was handled by cr. Pods without an instance
+// annotation are attributed to the default instance.
+func podBelongsTo(cr *monitoringv2alpha1.WhatapAgent, pod *corev1.Pod) bool {
+	instance, ok := pod.Annotations[monitoringv2alpha1.AnnotationApmInstance]
+	if !ok {
+		return cr.IsDefaultInstance()
+	}
+	return instance == cr.Name
+}
+
+// findAgentForPod maps a labelled pod to the WhatapAgent that handled it.
+func (r *InstrumentationStatusReconciler) findAgentForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetAnnotations()[monitoringv2alpha1.AnnotationApmInstance]
+	if name == "" {
+		name = monitoringv2alpha1.DefaultWhatapAgentName
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InstrumentationStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hasInjectionLabel := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := obj.GetLabels()[monitoringv2alpha1.InjectionLabelKey]
+		return ok
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("whatapagent-instrumentation-status").
+		For(&monitoringv2alpha1.WhatapAgent{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.findAgentForPod),
+			builder.WithPredicates(hasInjectionLabel),
+		).
+		Complete(r)
+}
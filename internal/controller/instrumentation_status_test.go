@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+func labelledPod(namespace, name, instance, target, injected, version string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{monitoringv2alpha1.InjectionLabelKey: injected},
+			Annotations: map[string]string{
+				monitoringv2alpha1.AnnotationApmInjected: injected,
+				monitoringv2alpha1.AnnotationApmTarget:   target,
+				monitoringv2alpha1.AnnotationApmLanguage: "java",
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if instance != "" {
+		pod.Annotations[monitoringv2alpha1.AnnotationApmInstance] = instance
+	}
+	if version != "" {
+		pod.Annotations[monitoringv2alpha1.AnnotationApmVersion] = version
+	}
+	return pod
+}
+
+func TestBuildInstrumentationStatus(t *testing.T) {
+	cr := restartTestAgent("2.2.68")
+	finished := labelledPod("shop", "job", "whatap", "java", "true", "2.2.60")
+	finished.Status.Phase = corev1.PodSucceeded
+
+	pods := []corev1.Pod{
+		labelledPod("shop", "orders-1", "whatap", "java", "true", "2.2.68"),
+		labelledPod("shop", "orders-2", "", "java", "true", "2.2.60"), // legacy pod without instance annotation
+		labelledPod("billing", "invoice-1", "whatap", "java", "false", ""),
+		labelledPod("shop", "other", "tenant", "java", "true", "2.2.60"), // another instance
+		labelledPod("shop", "forced", "whatap", "annotation-java", "true", "latest"),
+		finished,
+	}
+
+	summary := buildInstrumentationStatus(cr, pods)
+	if summary == nil || len(summary.Targets) != 2 {
+		t.Fatalf("Expected the spec target and the forced target, got %+v", summary)
+	}
+
+	java := summary.Targets[0]
+	if java.Name != "java" || java.DesiredVersion != "2.2.68" {
+		t.Fatalf("Expected the spec target first, got %+v", java)
+	}
+	want := monitoringv2alpha1.InstrumentationCounts{Matched: 3, Injected: 2, Skipped: 1, Outdated: 1}
+	if java.InstrumentationCounts != want {
+		t.Errorf("Expected counts %+v, got %+v", want, java.InstrumentationCounts)
+	}
+	if java.Versions["2.2.68"] != 1 || java.Versions["2.2.60"] != 1 {
+		t.Errorf("Unexpected versions %v", java.Versions)
+	}
+	if len(java.OutdatedPods) != 1 || java.OutdatedPods[0] != "shop/orders-2" {
+		t.Errorf("Unexpected outdated pods %v", java.OutdatedPods)
+	}
+	if len(java.Namespaces) != 2 || java.Namespaces[0].Namespace != "billing" || java.Namespaces[0].Skipped != 1 || java.Namespaces[1].Injected != 2 {
+		t.Errorf("Unexpected namespace breakdown %+v", java.Namespaces)
+	}
+
+	forced := summary.Targets[1]
+	if forced.Name != "annotation-java" || forced.Injected != 1 || forced.Outdated != 0 {
+		t.Errorf("Unexpected forced target summary %+v", forced)
+	}
+}
+
+func TestBuildInstrumentationStatus_Disabled(t *testing.T) {
+	cr := restartTestAgent("2.2.68")
+	cr.Spec.Features.Apm.Instrumentation.Enabled = false
+	if summary := buildInstrumentationStatus(cr, nil); summary != nil {
+		t.Errorf("Expected no summary, got %+v", summary)
+	}
+}
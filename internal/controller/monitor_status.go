@@ -0,0 +1,361 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+const (
+	conditionAccepted = "Accepted"
+	conditionInvalid  = "Invalid"
+
+	// monitorStatusResync refreshes the matched counts, which change with the pods and
+	// services of the cluster rather than with the monitor.
+	monitorStatusResync = 5 * time.Minute
+)
+
+// MonitorStatusReconciler writes the status of WhatapPodMonitor, WhatapServiceMonitor
+// and WhatapStaticEndpoint CRs: whether the OpenAgent of their instance picks them up
+// (Accepted), whether they can be scraped as configured (Invalid), what they match and
+// their target name in the OpenAgent scrape config.
+type MonitorStatusReconciler struct {
+	client.Client
+	// APIReader lists pods and services, which the manager cache does not hold
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
+	// DefaultNamespace is where the OpenAgent runs and mounts the TLS Secrets from
+	DefaultNamespace string
+}
+
+// monitorResult is the evaluated status of a monitor CR.
+type monitorResult struct {
+	instance   string
+	targetName string
+	matched    int32
+	accepted   metav1.Condition
+	invalid    metav1.Condition
+}
+
+func invalidCondition(reason, message string) metav1.Condition {
+	return metav1.Condition{Type: conditionInvalid, Status: metav1.ConditionTrue, Reason: reason, Message: message}
+}
+
+func validCondition() metav1.Condition {
+	return metav1.Condition{Type: conditionInvalid, Status: metav1.ConditionFalse, Reason: "Valid", Message: "Monitor is valid"}
+}
+
+// monitorTargetName is the name of the target generateScrapeConfig emits for a monitor CR.
+func monitorTargetName(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+func (r *MonitorStatusReconciler) reconcilePodMonitor(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	monitor := &monitoringv2alpha1.WhatapPodMonitor{}
+	if err := r.Get(ctx, req.NamespacedName, monitor); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	result, err := r.evaluate(ctx, monitor, monitor.Spec.Endpoints)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if result.invalid.Status == metav1.ConditionFalse {
+		if result.matched, result.invalid, err = r.countMatches(ctx, monitor.Namespace, monitor.Spec.NamespaceSelector, monitor.Spec.Selector, &corev1.PodList{}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	err = r.updateStatus(ctx, req.NamespacedName, &monitoringv2alpha1.WhatapPodMonitor{}, func(obj client.Object) bool {
+		m := obj.(*monitoringv2alpha1.WhatapPodMonitor)
+		status := m.Status.DeepCopy()
+		status.MatchedPods = result.matched
+		applyMonitorResult(&status.ObservedGeneration, &status.Conditions, &status.Instance, &status.TargetName, m.Generation, result)
+		if equality.Semantic.DeepEqual(*status, m.Status) {
+			return false
+		}
+		m.Status = *status
+		return true
+	})
+	return reconcile.Result{RequeueAfter: monitorStatusResync}, err
+}
+
+func (r *MonitorStatusReconciler) reconcileServiceMonitor(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	monitor := &monitoringv2alpha1.WhatapServiceMonitor{}
+	if err := r.Get(ctx, req.NamespacedName, monitor); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	result, err := r.evaluate(ctx, monitor, monitor.Spec.Endpoints)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if result.invalid.Status == metav1.ConditionFalse {
+		if result.matched, result.invalid, err = r.countMatches(ctx, monitor.Namespace, monitor.Spec.NamespaceSelector, monitor.Spec.Selector, &corev1.ServiceList{}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	err = r.updateStatus(ctx, req.NamespacedName, &monitoringv2alpha1.WhatapServiceMonitor{}, func(obj client.Object) bool {
+		m := obj.(*monitoringv2alpha1.WhatapServiceMonitor)
+		status := m.Status.DeepCopy()
+		status.MatchedServices = result.matched
+		applyMonitorResult(&status.ObservedGeneration, &status.Conditions, &status.Instance, &status.TargetName, m.Generation, result)
+		if equality.Semantic.DeepEqual(*status, m.Status) {
+			return false
+		}
+		m.Status = *status
+		return true
+	})
+	return reconcile.Result{RequeueAfter: monitorStatusResync}, err
+}
+
+func (r *MonitorStatusReconciler) reconcileStaticEndpoint(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	se := &monitoringv2alpha1.WhatapStaticEndpoint{}
+	if err := r.Get(ctx, req.NamespacedName, se); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	result, err := r.evaluate(ctx, se, se.Spec.Endpoints)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	for i, endpoint := range se.Spec.Endpoints {
+		if endpoint.Address == "" {
+			if result.invalid.Status == metav1.ConditionFalse {
+				result.invalid = invalidCondition("MissingAddress", fmt.Sprintf("endpoints[%d] has no address", i))
+			}
+			continue
+		}
+		result.matched++
+	}
+	err = r.updateStatus(ctx, req.NamespacedName, &monitoringv2alpha1.WhatapStaticEndpoint{}, func(obj client.Object) bool {
+		m := obj.(*monitoringv2alpha1.WhatapStaticEndpoint)
+		status := m.Status.DeepCopy()
+		status.MatchedEndpoints = result.matched
+		applyMonitorResult(&status.ObservedGeneration, &status.Conditions, &status.Instance, &status.TargetName, m.Generation, result)
+		if equality.Semantic.DeepEqual(*status, m.Status) {
+			return false
+		}
+		m.Status = *status
+		return true
+	})
+	return reconcile.Result{RequeueAfter: monitorStatusResync}, err
+}
+
+// applyMonitorResult writes the fields the three monitor statuses share.
+func applyMonitorResult(observedGeneration *int64, conditions *[]metav1.Condition, instance, targetName *string, generation int64, result monitorResult) {
+	*observedGeneration = generation
+	*instance = result.instance
+	*targetName = result.targetName
+	apimeta.SetStatusCondition(conditions, result.accepted)
+	apimeta.SetStatusCondition(conditions, result.invalid)
+}
+
+// evaluate resolves the instance of monitor and checks what all monitor kinds have in
+// common: the endpoints and the TLS Secrets they reference.
+func (r *MonitorStatusReconciler) evaluate(ctx context.Context, monitor client.Object, endpoints []monitoringv2alpha1.OpenAgentEndpoint) (monitorResult, error) {
+	result := monitorResult{invalid: validCondition()}
+	result.instance = monitor.GetLabels()[monitoringv2alpha1.InstanceLabelKey]
+	if result.instance == "" {
+		result.instance = monitoringv2alpha1.DefaultWhatapAgentName
+	}
+
+	agent := &monitoringv2alpha1.WhatapAgent{}
+	err := r.Get(ctx, types.NamespacedName{Name: result.instance}, agent)
+	switch {
+	case apierrors.IsNotFound(err):
+		result.accepted = notReadyCondition(conditionAccepted, "InstanceNotFound", fmt.Sprintf("WhatapAgent %q does not exist", result.instance))
+	case err != nil:
+		return result, err
+	case !agent.Spec.Features.OpenAgent.Enabled:
+		result.accepted = notReadyCondition(conditionAccepted, "OpenAgentDisabled", fmt.Sprintf("OpenAgent of WhatapAgent %q is disabled", result.instance))
+	default:
+		result.targetName = monitorTargetName(monitor.GetNamespace(), monitor.GetName())
+		result.accepted = metav1.Condition{
+			Type:    conditionAccepted,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Accepted",
+			Message: fmt.Sprintf("Scraped by the OpenAgent of WhatapAgent %q", result.instance),
+		}
+	}
+
+	if len(endpoints) == 0 {
+		result.invalid = invalidCondition("NoEndpoints", "No endpoints to scrape")
+		return result, nil
+	}
+	result.invalid, err = r.checkTLSSecrets(ctx, endpoints)
+	return result, err
+}
+
+// checkTLSSecrets checks that the TLS Secrets referenced by endpoints exist, with their
+// keys, in the namespace the OpenAgent mounts them from.
+func (r *MonitorStatusReconciler) checkTLSSecrets(ctx context.Context, endpoints []monitoringv2alpha1.OpenAgentEndpoint) (metav1.Condition, error) {
+	secrets := map[string][]string{}
+	addTLSSecretsFromEndpoints(secrets, endpoints)
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: r.DefaultNamespace, Name: name}, secret)
+		if apierrors.IsNotFound(err) {
+			return invalidCondition("TLSSecretNotFound", fmt.Sprintf("Secret %s/%s not found", r.DefaultNamespace, name)), nil
+		}
+		if err != nil {
+			return metav1.Condition{}, err
+		}
+		for _, key := range secrets[name] {
+			if _, ok := secret.Data[key]; !ok {
+				return invalidCondition("TLSSecretKeyMissing", fmt.Sprintf("Secret %s/%s has no key %q", r.DefaultNamespace, name, key)), nil
+			}
+		}
+	}
+	return validCondition(), nil
+}
+
+// countMatches counts the objects of list (pods or services) selected by selector in the
+// namespaces of nsSelector. A monitor without namespaceSelector selects its own namespace.
+// Pods are only counted while running.
+func (r *MonitorStatusReconciler) countMatches(ctx context.Context, namespace string, nsSelector *monitoringv2alpha1.NamespaceSelector, selector metav1.LabelSelector, list client.ObjectList) (int32, metav1.Condition, error) {
+	objSelector, err := metav1.LabelSelectorAsSelector(&selector)
+	if err != nil {
+		return 0, invalidCondition("InvalidSelector", err.Error()), nil
+	}
+	nsLabels := labels.Everything()
+	if nsSelector != nil {
+		if nsLabels, err = toLabelsSelector(nsSelector.MatchLabels, nsSelector.MatchExpressions); err != nil {
+			return 0, invalidCondition("InvalidNamespaceSelector", err.Error()), nil
+		}
+	}
+	namespaces, err := r.selectNamespaces(ctx, namespace, nsSelector, nsLabels)
+	if err != nil {
+		return 0, metav1.Condition{}, err
+	}
+
+	var matched int32
+	for _, ns := range namespaces {
+		if err := r.APIReader.List(ctx, list, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: objSelector}); err != nil {
+			return 0, metav1.Condition{}, err
+		}
+		switch l := list.(type) {
+		case *corev1.PodList:
+			for _, pod := range l.Items {
+				if pod.Status.Phase == corev1.PodRunning {
+					matched++
+				}
+			}
+		default:
+			matched += int32(apimeta.LenList(list))
+		}
+	}
+	return matched, validCondition(), nil
+}
+
+// selectNamespaces returns the namespaces selected by nsSelector: the namespaces in
+// matchNames (or all when empty) whose labels match nsLabels.
+func (r *MonitorStatusReconciler) selectNamespaces(ctx context.Context, namespace string, nsSelector *monitoringv2alpha1.NamespaceSelector, nsLabels labels.Selector) ([]string, error) {
+	if nsSelector == nil {
+		return []string{namespace}, nil
+	}
+	nsList := &corev1.NamespaceList{}
+	if err := r.List(ctx, nsList); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ns := range nsList.Items {
+		if len(nsSelector.MatchNames) > 0 && !containsString(nsSelector.MatchNames, ns.Name) {
+			continue
+		}
+		if nsLabels.Matches(labels.Set(ns.Labels)) {
+			names = append(names, ns.Name)
+		}
+	}
+	return names, nil
+}
+
+// updateStatus applies mutate to a fresh copy of the monitor at key and writes its status
+// when mutate reports a change.
+func (r *MonitorStatusReconciler) updateStatus(ctx context.Context, key types.NamespacedName, obj client.Object, mutate func(client.Object) bool) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		if !mutate(obj) {
+			return nil
+		}
+		return r.Status().Update(ctx, obj)
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to update monitor status", "monitor", key)
+		return err
+	}
+	return nil
+}
+
+// monitorsOf maps a WhatapAgent to its monitors of the kind of list.
+func (r *MonitorStatusReconciler) monitorsOf(list client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		agent, ok := obj.(*monitoringv2alpha1.WhatapAgent)
+		if !ok {
+			return nil
+		}
+		monitors := list.DeepCopyObject().(client.ObjectList)
+		if err := r.List(ctx, monitors); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		_ = apimeta.EachListItem(monitors, func(o runtime.Object) error {
+			m := o.(client.Object)
+			if monitorBelongsTo(agent, m.GetLabels()) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(m)})
+			}
+			return nil
+		})
+		return requests
+	}
+}
+
+// SetupWithManager registers one controller per monitor kind. Status updates do not
+// change the generation, so they do not trigger the controllers again.
+func (r *MonitorStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	monitorChanged := builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))
+	agentChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	for _, c := range []struct {
+		name      string
+		monitor   client.Object
+		list      client.ObjectList
+		reconcile reconcile.Func
+	}{
+		{"whatappodmonitor-status", &monitoringv2alpha1.WhatapPodMonitor{}, &monitoringv2alpha1.WhatapPodMonitorList{}, r.reconcilePodMonitor},
+		{"whatapservicemonitor-status", &monitoringv2alpha1.WhatapServiceMonitor{}, &monitoringv2alpha1.WhatapServiceMonitorList{}, r.reconcileServiceMonitor},
+		{"whatapstaticendpoint-status", &monitoringv2alpha1.WhatapStaticEndpoint{}, &monitoringv2alpha1.WhatapStaticEndpointList{}, r.reconcileStaticEndpoint},
+	} {
+		err := ctrl.NewControllerManagedBy(mgr).
+			Named(c.name).
+			For(c.monitor, monitorChanged).
+			Watches(&monitoringv2alpha1.WhatapAgent{}, handler.EnqueueRequestsFromMapFunc(r.monitorsOf(c.list)), agentChanged).
+			Complete(c.reconcile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+func monitorStatusTestReconciler(objs ...client.Object) *MonitorStatusReconciler {
+	c := newFakeClient(objs...)
+	return &MonitorStatusReconciler{Client: c, APIReader: c, Scheme: testScheme, DefaultNamespace: "whatap-monitoring"}
+}
+
+func monitorStatusTestAgent(name string, openAgent bool) *monitoringv2alpha1.WhatapAgent {
+	agent := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	agent.Spec.Features.OpenAgent.Enabled = openAgent
+	return agent
+}
+
+func monitorStatusTestPod(namespace, name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": "orders"}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func requireCondition(t *testing.T, conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus, reason string) {
+	t.Helper()
+	c := apimeta.FindStatusCondition(conditions, conditionType)
+	if c == nil || c.Status != status || c.Reason != reason {
+		t.Errorf("Expected %s=%s (%s), got %+v", conditionType, status, reason, c)
+	}
+}
+
+func TestReconcilePodMonitorStatus(t *testing.T) {
+	monitor := &monitoringv2alpha1.WhatapPodMonitor{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "orders", Generation: 3},
+		Spec: monitoringv2alpha1.WhatapPodMonitorSpec{
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "orders"}},
+			Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{{Port: "metrics"}},
+		},
+	}
+	r := monitorStatusTestReconciler(
+		monitorStatusTestAgent(monitoringv2alpha1.DefaultWhatapAgentName, true),
+		monitor,
+		monitorStatusTestPod("shop", "orders-1", corev1.PodRunning),
+		monitorStatusTestPod("shop", "orders-2", corev1.PodRunning),
+		monitorStatusTestPod("shop", "orders-3", corev1.PodSucceeded),
+		monitorStatusTestPod("other", "orders-1", corev1.PodRunning),
+	)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "shop", Name: "orders"}
+
+	if _, err := r.reconcilePodMonitor(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	got := &monitoringv2alpha1.WhatapPodMonitor{}
+	if err := r.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	st := got.Status
+	if st.ObservedGeneration != 3 || st.MatchedPods != 2 || st.TargetName != "shop/orders" || st.Instance != "whatap" {
+		t.Errorf("Unexpected status %+v", st)
+	}
+	requireCondition(t, st.Conditions, conditionAccepted, metav1.ConditionTrue, "Accepted")
+	requireCondition(t, st.Conditions, conditionInvalid, metav1.ConditionFalse, "Valid")
+
+	// An invalid selector is reported and nothing is counted
+	got.Spec.Selector = metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bogus"}}}
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reconcilePodMonitor(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := r.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.MatchedPods != 0 {
+		t.Errorf("Expected no matches for an invalid selector, got %d", got.Status.MatchedPods)
+	}
+	requireCondition(t, got.Status.Conditions, conditionInvalid, metav1.ConditionTrue, "InvalidSelector")
+}
+
+func TestReconcileServiceMonitorStatus(t *testing.T) {
+	monitor := &monitoringv2alpha1.WhatapServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "orders", Labels: map[string]string{monitoringv2alpha1.InstanceLabelKey: "tenant"}},
+		Spec: monitoringv2alpha1.WhatapServiceMonitorSpec{
+			Selector:          metav1.LabelSelector{MatchLabels: map[string]string{"app": "orders"}},
+			NamespaceSelector: &monitoringv2alpha1.NamespaceSelector{MatchLabels: map[string]string{"team": "shop"}},
+			Endpoints:         []monitoringv2alpha1.OpenAgentEndpoint{{Port: "metrics"}},
+		},
+	}
+	service := func(namespace string) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "orders", Labels: map[string]string{"app": "orders"}}}
+	}
+	r := monitorStatusTestReconciler(
+		monitorStatusTestAgent("tenant", false),
+		monitor,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"team": "shop"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop-canary", Labels: map[string]string{"team": "shop"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		service("shop"), service("shop-canary"), service("other"),
+	)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "shop", Name: "orders"}
+
+	if _, err := r.reconcileServiceMonitor(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	got := &monitoringv2alpha1.WhatapServiceMonitor{}
+	if err := r.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.MatchedServices != 2 || got.Status.Instance != "tenant" || got.Status.TargetName != "" {
+		t.Errorf("Unexpected status %+v", got.Status)
+	}
+	requireCondition(t, got.Status.Conditions, conditionAccepted, metav1.ConditionFalse, "OpenAgentDisabled")
+}
+
+func TestReconcileStaticEndpointStatus(t *testing.T) {
+	se := &monitoringv2alpha1.WhatapStaticEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "infra", Name: "db", Labels: map[string]string{monitoringv2alpha1.InstanceLabelKey: "missing"}},
+		Spec: monitoringv2alpha1.WhatapStaticEndpointSpec{
+			Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{
+				{Address: "10.0.0.5:9104", TLSConfig: &monitoringv2alpha1.TLSConfig{
+					CASecret: &monitoringv2alpha1.SecretKeySelector{Name: "db-tls", Key: "ca.crt"},
+				}},
+				{Address: "10.0.0.6:9104"},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "db-tls"},
+		Data:       map[string][]byte{"tls.crt": []byte("cert")},
+	}
+	r := monitorStatusTestReconciler(se, secret)
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "infra", Name: "db"}
+
+	if _, err := r.reconcileStaticEndpoint(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	got := &monitoringv2alpha1.WhatapStaticEndpoint{}
+	if err := r.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.MatchedEndpoints != 2 {
+		t.Errorf("Expected 2 endpoints, got %d", got.Status.MatchedEndpoints)
+	}
+	requireCondition(t, got.Status.Conditions, conditionAccepted, metav1.ConditionFalse, "InstanceNotFound")
+	requireCondition(t, got.Status.Conditions, conditionInvalid, metav1.ConditionTrue, "TLSSecretKeyMissing")
+}
+
+func TestCollectAllTLSSecretsIncludesStaticEndpoints(t *testing.T) {
+	staticEndpoints := &monitoringv2alpha1.WhatapStaticEndpointList{Items: []monitoringv2alpha1.WhatapStaticEndpoint{{
+		Spec: monitoringv2alpha1.WhatapStaticEndpointSpec{Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{{
+			Address:   "10.0.0.5:9104",
+			TLSConfig: &monitoringv2alpha1.TLSConfig{CASecret: &monitoringv2alpha1.SecretKeySelector{Name: "db-tls", Key: "ca.crt"}},
+		}}},
+	}}}
+	secrets := collectAllTLSSecrets(nil, nil, nil, staticEndpoints)
+	if keys := secrets["db-tls"]; len(keys) != 1 || keys[0] != "ca.crt" {
+		t.Errorf("Expected the static endpoint TLS Secret to be mounted, got %v", secrets)
+	}
+}
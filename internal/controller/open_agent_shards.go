@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+const (
+	// openAgentShardLabel carries the shard index on the ConfigMap and Deployment of a shard
+	openAgentShardLabel = "monitoring.whatap.com/open-agent-shard"
+	// openAgentShardsAnnotation carries the shard count on the pod template, so that
+	// changing it restarts the pods with their new scrape config
+	openAgentShardsAnnotation = "monitoring.whatap.com/open-agent-shards"
+	// openAgentShardHashLabel is a temporary label: Prometheus drops __tmp labels after relabeling
+	openAgentShardHashLabel = "__tmp_whatap_shard"
+	// openAgentDrainPoll is how often the reconciler checks whether the shards of the
+	// previous shard count have stopped
+	openAgentDrainPoll = 5 * time.Second
+)
+
+// openAgentShardsDrainingError reports that OpenAgent pods started with another shard
+// count are still running. The shards of the new count are configured once they are gone.
+type openAgentShardsDrainingError struct {
+	pods int
+}
+
+func (e *openAgentShardsDrainingError) Error() string {
+	return fmt.Sprintf("waiting for %d OpenAgent pods of the previous shard count to stop", e.pods)
+}
+
+// openAgentShards returns the number of OpenAgent shards (1 when unset).
+func openAgentShards(cr *monitoringv2alpha1.WhatapAgent) int {
+	if cr.Spec.Features.OpenAgent.Shards > 1 {
+		return int(cr.Spec.Features.OpenAgent.Shards)
+	}
+	return 1
+}
+
+// openAgentShardName returns the name of an OpenAgent resource of shard. Shard 0 keeps the
+// unsharded name so that enabling sharding does not replace the running agent.
+func openAgentShardName(cr *monitoringv2alpha1.WhatapAgent, name string, shard int) string {
+	if shard == 0 {
+		return cr.ResourceName(name)
+	}
+	return cr.ResourceName(fmt.Sprintf("%s-shard-%d", name, shard))
+}
+
+// setOpenAgentShardLabel sets the shard label on labels, or removes it when unsharded.
+func setOpenAgentShardLabel(labels map[string]string, shard, shards int) map[string]string {
+	if shards <= 1 {
+		delete(labels, openAgentShardLabel)
+		return labels
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[openAgentShardLabel] = strconv.Itoa(shard)
+	return labels
+}
+
+// openAgentStrategy returns the Deployment strategy of the OpenAgent. A rolling update runs
+// the old and the new pod side by side, which for shards means two configs overlapping:
+// shards are recreated instead so that a shard never runs two configs at once.
+func openAgentStrategy(shards int) appsv1.DeploymentStrategy {
+	if shards > 1 {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+		},
+	}
+}
+
+// shardScrapeConfig restricts the scrape config generated by generateScrapeConfig to shard:
+// every target gets a hashmod of __address__ and __param_target over shards, and keeps the
+// targets hashing to shard. Probes share the address of their prober and differ only in
+// their target parameter, which is empty for other targets. The relabelings run last,
+// after the address has been finalized.
+func shardScrapeConfig(config string, shard, shards int) (string, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(config), &doc); err != nil {
+		return "", err
+	}
+	shardRelabelConfigs := []interface{}{
+		toOrderedYAML(map[string]interface{}{
+			"source_labels": []string{"__address__", "__param_target"},
+			"modulus":       shards,
+			"target_label":  openAgentShardHashLabel,
+			"action":        "hashmod",
+		}),
+		toOrderedYAML(map[string]interface{}{
+			"source_labels": []string{openAgentShardHashLabel},
+			"regex":         strconv.Itoa(shard),
+			"action":        "keep",
+		}),
+	}
+
+	features := mapSliceValue(doc, "features")
+	openAgent := mapSliceValue(features, "openAgent")
+	targets, _ := mapSliceValue(openAgent, "targets").([]interface{})
+	for i, t := range targets {
+		target, ok := t.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		found := false
+		for j := range target {
+			if target[j].Key == "relabelConfigs" {
+				existing, _ := target[j].Value.([]interface{})
+				target[j].Value = append(existing, shardRelabelConfigs...)
+				found = true
+			}
+		}
+		if !found {
+			target = append(target, yaml.MapItem{Key: "relabelConfigs", Value: shardRelabelConfigs})
+		}
+		targets[i] = target
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// mapSliceValue returns the value of key in v when v is a yaml.MapSlice.
+func mapSliceValue(v interface{}, key string) interface{} {
+	ms, ok := v.(yaml.MapSlice)
+	if !ok {
+		return nil
+	}
+	for _, item := range ms {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// deployedOpenAgentShards returns the shard count the pods of an OpenAgent Deployment were
+// started with (1 for the unsharded Deployment).
+func deployedOpenAgentShards(deploy *appsv1.Deployment) int {
+	if shards, err := strconv.Atoi(deploy.Spec.Template.Annotations[openAgentShardsAnnotation]); err == nil {
+		return shards
+	}
+	return 1
+}
+
+// drainOpenAgentShards stops the OpenAgent Deployments of cr started with a shard count other
+// than shards. Targets move between shards when the count changes, so the shards of the new
+// count only get their configs once no pod of the old count runs anymore: during the
+// change targets are briefly not scraped, but never scraped twice. It returns an
+// *openAgentShardsDrainingError while old pods are still stopping.
+func (r *WhatapAgentReconciler) drainOpenAgentShards(ctx context.Context, logger logr.Logger, cr *monitoringv2alpha1.WhatapAgent, shards int) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(r.DefaultNamespace)); err != nil {
+		return err
+	}
+	// Pods are not in the manager cache
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	running := 0
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		_, sharded := deploy.Labels[openAgentShardLabel]
+		if !metav1.IsControlledBy(deploy, cr) || (!sharded && deploy.Name != openAgentShardName(cr, "whatap-open-agent", 0)) {
+			continue
+		}
+		if deployedOpenAgentShards(deploy) == shards {
+			continue
+		}
+		if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != 0 {
+			deploy.Spec.Replicas = int32Ptr(0)
+			if err := r.Update(ctx, deploy); err != nil {
+				return err
+			}
+			logger.Info("Stopped OpenAgent shard before changing the shard count", "name", deploy.Name, "shards", shards)
+		}
+		if deploy.Spec.Selector == nil {
+			continue
+		}
+		pods := &corev1.PodList{}
+		if err := reader.List(ctx, pods, client.InNamespace(deploy.Namespace), client.MatchingLabels(deploy.Spec.Selector.MatchLabels)); err != nil {
+			return err
+		}
+		running += len(pods.Items)
+	}
+	if running > 0 {
+		return &openAgentShardsDrainingError{pods: running}
+	}
+	return nil
+}
+
+// cleanupOpenAgentShards deletes the ConfigMaps and Deployments of the shards of cr with an
+// index of shards or more, left over after the shard count was lowered.
+func (r *WhatapAgentReconciler) cleanupOpenAgentShards(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent, shards int) error {
+	logger := log.FromContext(ctx)
+	for _, list := range []client.ObjectList{&appsv1.DeploymentList{}, &corev1.ConfigMapList{}} {
+		if err := r.List(ctx, list, client.InNamespace(r.DefaultNamespace), client.HasLabels{openAgentShardLabel}); err != nil {
+			return err
+		}
+		var objs []client.Object
+		switch l := list.(type) {
+		case *appsv1.DeploymentList:
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		case *corev1.ConfigMapList:
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		}
+		for _, obj := range objs {
+			shard, err := strconv.Atoi(obj.GetLabels()[openAgentShardLabel])
+			if err != nil || shard < shards || shard == 0 || !metav1.IsControlledBy(obj, cr) {
+				continue
+			}
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "Failed to delete OpenAgent shard", "name", obj.GetName())
+				return err
+			}
+			logger.Info("Deleted OpenAgent shard", "name", obj.GetName())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+func TestShardScrapeConfig(t *testing.T) {
+	cr := &monitoringv2alpha1.WhatapAgent{}
+	cr.Spec.Features.OpenAgent.Enabled = true
+	podMonitors := &monitoringv2alpha1.WhatapPodMonitorList{Items: []monitoringv2alpha1.WhatapPodMonitor{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "orders"},
+		Spec: monitoringv2alpha1.WhatapPodMonitorSpec{
+			JobLabel:  "app",
+			Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{{Port: "metrics"}},
+		},
+	}}}
+	staticEndpoints := &monitoringv2alpha1.WhatapStaticEndpointList{Items: []monitoringv2alpha1.WhatapStaticEndpoint{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "infra", Name: "db"},
+		Spec: monitoringv2alpha1.WhatapStaticEndpointSpec{
+			Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{{Address: "10.0.0.5:9104"}},
+		},
+	}}}
+	config := generateScrapeConfig(cr, "whatap-monitoring", podMonitors, nil, staticEndpoints)
+
+	sharded, err := shardScrapeConfig(config, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both targets keep their own relabelings and get the shard ones
+	if n := strings.Count(sharded, "action: hashmod"); n != 2 {
+		t.Errorf("Expected a hashmod relabeling per target, got %d:\n%s", n, sharded)
+	}
+	for _, want := range []string{"modulus: 3", "- __param_target", "regex: \"2\"", "target_label: job", "targetName: infra/db"} {
+		if !strings.Contains(sharded, want) {
+			t.Errorf("Expected the sharded config to contain %q:\n%s", want, sharded)
+		}
+	}
+	// The job relabeling runs before the shard ones
+	if strings.Index(sharded, "target_label: job") > strings.Index(sharded, "action: hashmod") {
+		t.Errorf("Expected the shard relabelings to run last:\n%s", sharded)
+	}
+}
+
+func TestCleanupOpenAgentShards(t *testing.T) {
+	cr := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: monitoringv2alpha1.DefaultWhatapAgentName, UID: "uid-whatap"}}
+	other := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "tenant", UID: "uid-tenant"}}
+
+	shardMeta := func(owner *monitoringv2alpha1.WhatapAgent, name string, shard int) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace: "whatap-monitoring",
+			Name:      openAgentShardName(owner, name, shard),
+			Labels:    setOpenAgentShardLabel(nil, shard, 3),
+		}
+	}
+	var objs []client.Object
+	for _, owner := range []*monitoringv2alpha1.WhatapAgent{cr, other} {
+		for shard := 0; shard < 3; shard++ {
+			d := &appsv1.Deployment{ObjectMeta: shardMeta(owner, "whatap-open-agent", shard)}
+			cm := &corev1.ConfigMap{ObjectMeta: shardMeta(owner, "whatap-open-agent-config", shard)}
+			for _, obj := range []client.Object{d, cm} {
+				if err := controllerutil.SetControllerReference(owner, obj, testScheme); err != nil {
+					t.Fatal(err)
+				}
+				objs = append(objs, obj)
+			}
+		}
+	}
+	c := newFakeClient(objs...)
+	r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, DefaultNamespace: "whatap-monitoring"}
+
+	if err := r.cleanupOpenAgentShards(context.Background(), cr, 2); err != nil {
+		t.Fatal(err)
+	}
+	exists := func(name string, obj client.Object) bool {
+		return c.Get(context.Background(), types.NamespacedName{Namespace: "whatap-monitoring", Name: name}, obj) == nil
+	}
+	if !exists("whatap-open-agent", &appsv1.Deployment{}) || !exists("whatap-open-agent-shard-1", &appsv1.Deployment{}) {
+		t.Errorf("Expected shards 0 and 1 to be kept")
+	}
+	if exists("whatap-open-agent-shard-2", &appsv1.Deployment{}) || exists("whatap-open-agent-config-shard-2", &corev1.ConfigMap{}) {
+		t.Errorf("Expected the Deployment and ConfigMap of shard 2 to be deleted")
+	}
+	if !exists("tenant-whatap-open-agent-shard-2", &appsv1.Deployment{}) {
+		t.Errorf("Expected the shard of another instance to be kept")
+	}
+}
+
+func TestDrainOpenAgentShards(t *testing.T) {
+	cr := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: monitoringv2alpha1.DefaultWhatapAgentName, UID: "uid-whatap"}}
+	// The unsharded Deployment runs one pod, which moves to shard 0 of two shards
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "whatap-open-agent"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "whatap-open-agent"}},
+		},
+	}
+	if err := controllerutil.SetControllerReference(cr, deploy, testScheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "whatap-open-agent-abc", Labels: map[string]string{"app": "whatap-open-agent"}}}
+	c := newFakeClient(deploy, pod)
+	r := &WhatapAgentReconciler{Client: c, Scheme: testScheme, DefaultNamespace: "whatap-monitoring"}
+	ctx := context.Background()
+
+	// The same shard count leaves the Deployment alone
+	if err := r.drainOpenAgentShards(ctx, logr.Discard(), cr, 1); err != nil {
+		t.Fatalf("Expected no drain without a shard count change, got %v", err)
+	}
+
+	var draining *openAgentShardsDrainingError
+	if err := r.drainOpenAgentShards(ctx, logr.Discard(), cr, 2); !errors.As(err, &draining) {
+		t.Fatalf("Expected to wait for the running pod, got %v", err)
+	}
+	got := &appsv1.Deployment{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(deploy), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Spec.Replicas == nil || *got.Spec.Replicas != 0 {
+		t.Errorf("Expected the old shard to be scaled down, got %v", got.Spec.Replicas)
+	}
+
+	if err := c.Delete(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.drainOpenAgentShards(ctx, logr.Discard(), cr, 2); err != nil {
+		t.Errorf("Expected the drain to finish once the old pods are gone, got %v", err)
+	}
+}
@@ -0,0 +1,703 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// prometheusOperatorKinds are the imported kinds, in the order they are added to the
+// scrape config.
+var prometheusOperatorKinds = []monitoringv2alpha1.PrometheusOperatorKind{
+	monitoringv2alpha1.PrometheusOperatorServiceMonitor,
+	monitoringv2alpha1.PrometheusOperatorPodMonitor,
+	monitoringv2alpha1.PrometheusOperatorProbe,
+}
+
+func prometheusOperatorGVK(kind monitoringv2alpha1.PrometheusOperatorKind) schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: string(kind)}
+}
+
+// detectPrometheusOperatorKinds returns the Prometheus Operator kinds whose CRD is
+// installed in the cluster.
+func detectPrometheusOperatorKinds(mapper apimeta.RESTMapper) (map[monitoringv2alpha1.PrometheusOperatorKind]bool, error) {
+	installed := map[monitoringv2alpha1.PrometheusOperatorKind]bool{}
+	for _, kind := range prometheusOperatorKinds {
+		gvk := prometheusOperatorGVK(kind)
+		_, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if apimeta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		installed[kind] = true
+	}
+	return installed, nil
+}
+
+// installedPrometheusOperatorKinds re-checks which Prometheus Operator CRDs are installed,
+// so CRDs installed after the controller started are imported too, and starts watching
+// the kinds whose CRD appeared since the last check.
+func (r *WhatapAgentReconciler) installedPrometheusOperatorKinds() (map[monitoringv2alpha1.PrometheusOperatorKind]bool, error) {
+	if r.restMapper == nil {
+		return map[monitoringv2alpha1.PrometheusOperatorKind]bool{}, nil
+	}
+	installed, err := detectPrometheusOperatorKinds(r.restMapper)
+	if err != nil {
+		return nil, err
+	}
+	r.prometheusOperatorMu.Lock()
+	defer r.prometheusOperatorMu.Unlock()
+	if r.prometheusOperatorWatched == nil {
+		r.prometheusOperatorWatched = map[monitoringv2alpha1.PrometheusOperatorKind]bool{}
+	}
+	for _, kind := range prometheusOperatorKinds {
+		if !installed[kind] || r.prometheusOperatorWatched[kind] || r.watchPrometheusOperatorKind == nil {
+			continue
+		}
+		if err := r.watchPrometheusOperatorKind(kind); err != nil {
+			return nil, fmt.Errorf("failed to watch Prometheus Operator %ss: %w", kind, err)
+		}
+		r.prometheusOperatorWatched[kind] = true
+	}
+	return installed, nil
+}
+
+// prometheusOperatorCompatKinds returns the kinds compat imports.
+func prometheusOperatorCompatKinds(compat *monitoringv2alpha1.PrometheusOperatorCompatSpec) []monitoringv2alpha1.PrometheusOperatorKind {
+	if len(compat.Kinds) == 0 {
+		return prometheusOperatorKinds
+	}
+	return compat.Kinds
+}
+
+// prometheusOperatorCompatCondition reports the kinds imported by prometheusOperatorCompat
+// and the ones skipped because their CRD is not installed.
+func (r *WhatapAgentReconciler) prometheusOperatorCompatCondition(compat *monitoringv2alpha1.PrometheusOperatorCompatSpec) metav1.Condition {
+	installed, err := r.installedPrometheusOperatorKinds()
+	if err != nil {
+		return notReadyCondition(conditionPrometheusOperatorImported, "DiscoveryFailed", err.Error())
+	}
+	var imported, missing []string
+	for _, kind := range prometheusOperatorCompatKinds(compat) {
+		if installed[kind] {
+			imported = append(imported, string(kind))
+		} else {
+			missing = append(missing, string(kind))
+		}
+	}
+	if len(missing) > 0 {
+		return notReadyCondition(conditionPrometheusOperatorImported, "CRDNotInstalled",
+			fmt.Sprintf("Prometheus Operator CRD not installed, skipping %s", strings.Join(missing, ", ")))
+	}
+	return readyCondition(conditionPrometheusOperatorImported, "Importing "+strings.Join(imported, ", "))
+}
+
+// findPrometheusOperatorAgents maps a Prometheus Operator resource to the WhatapAgents
+// importing Prometheus Operator resources.
+func (r *WhatapAgentReconciler) findPrometheusOperatorAgents(ctx context.Context, obj client.Object) []reconcile.Request {
+	agents := &monitoringv2alpha1.WhatapAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, agent := range agents.Items {
+		if compat := agent.Spec.Features.OpenAgent.PrometheusOperatorCompat; compat != nil && compat.Enabled {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&agent)})
+		}
+	}
+	return requests
+}
+
+// importPrometheusOperatorMonitors appends the Prometheus Operator resources selected by
+// the prometheusOperatorCompat settings of cr, translated into WhaTap monitor CRs, to
+// the monitor lists of cr. A WhaTap CR of the same kind and namespace/name wins.
+func (r *WhatapAgentReconciler) importPrometheusOperatorMonitors(
+	ctx context.Context,
+	logger logr.Logger,
+	cr *monitoringv2alpha1.WhatapAgent,
+	podMonitors *monitoringv2alpha1.WhatapPodMonitorList,
+	serviceMonitors *monitoringv2alpha1.WhatapServiceMonitorList,
+	staticEndpoints *monitoringv2alpha1.WhatapStaticEndpointList,
+) error {
+	compat := cr.Spec.Features.OpenAgent.PrometheusOperatorCompat
+	if compat == nil || !compat.Enabled {
+		return nil
+	}
+	filter, err := r.newPrometheusOperatorFilter(ctx, cr, compat)
+	if err != nil {
+		return err
+	}
+
+	installed, err := r.installedPrometheusOperatorKinds()
+	if err != nil {
+		return err
+	}
+
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	for _, kind := range prometheusOperatorKinds {
+		if !filter.kinds[kind] {
+			continue
+		}
+		if !installed[kind] {
+			logger.Info("Prometheus Operator CRD not installed, skipping import", "kind", kind)
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(prometheusOperatorGVK(kind).GroupVersion().WithKind(string(kind) + "List"))
+		if err := reader.List(ctx, list); err != nil {
+			return fmt.Errorf("failed to list Prometheus Operator %ss: %w", kind, err)
+		}
+		for i := range list.Items {
+			u := &list.Items[i]
+			if !filter.matches(u) {
+				continue
+			}
+			var warnings []string
+			switch kind {
+			case monitoringv2alpha1.PrometheusOperatorServiceMonitor:
+				var monitors []monitoringv2alpha1.WhatapServiceMonitor
+				monitors, warnings, err = serviceMonitorFromPrometheusOperator(u, r.DefaultNamespace)
+				for _, m := range monitors {
+					if !hasServiceMonitor(serviceMonitors, m.Namespace, u.GetName()) {
+						serviceMonitors.Items = append(serviceMonitors.Items, m)
+					}
+				}
+			case monitoringv2alpha1.PrometheusOperatorPodMonitor:
+				var monitors []monitoringv2alpha1.WhatapPodMonitor
+				monitors, warnings, err = podMonitorFromPrometheusOperator(u, r.DefaultNamespace)
+				for _, m := range monitors {
+					if !hasPodMonitor(podMonitors, m.Namespace, u.GetName()) {
+						podMonitors.Items = append(podMonitors.Items, m)
+					}
+				}
+			case monitoringv2alpha1.PrometheusOperatorProbe:
+				var se *monitoringv2alpha1.WhatapStaticEndpoint
+				se, warnings, err = staticEndpointFromPrometheusOperatorProbe(u, r.DefaultNamespace)
+				if se != nil && !hasStaticEndpoint(staticEndpoints, se.Namespace, se.Name) {
+					staticEndpoints.Items = append(staticEndpoints.Items, *se)
+				}
+			}
+			if err != nil {
+				logger.Error(err, "Failed to import Prometheus Operator resource", "kind", kind, "namespace", u.GetNamespace(), "name", u.GetName())
+				continue
+			}
+			for _, w := range warnings {
+				logger.Info("Prometheus Operator resource imported partially", "kind", kind, "namespace", u.GetNamespace(), "name", u.GetName(), "reason", w)
+			}
+		}
+	}
+	return nil
+}
+
+// prometheusOperatorFilter selects the Prometheus Operator resources an instance imports.
+type prometheusOperatorFilter struct {
+	instance   *monitoringv2alpha1.WhatapAgent
+	kinds      map[monitoringv2alpha1.PrometheusOperatorKind]bool
+	selector   labels.Selector
+	namespaces map[string]bool // nil: every namespace
+}
+
+func (r *WhatapAgentReconciler) newPrometheusOperatorFilter(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent, compat *monitoringv2alpha1.PrometheusOperatorCompatSpec) (*prometheusOperatorFilter, error) {
+	filter := &prometheusOperatorFilter{instance: cr, kinds: map[monitoringv2alpha1.PrometheusOperatorKind]bool{}, selector: labels.Everything()}
+	for _, kind := range prometheusOperatorCompatKinds(compat) {
+		filter.kinds[kind] = true
+	}
+	if compat.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(compat.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheusOperatorCompat.selector: %w", err)
+		}
+		filter.selector = selector
+	}
+	if nsSelector := compat.NamespaceSelector; nsSelector != nil {
+		selector, err := toLabelsSelector(nsSelector.MatchLabels, nsSelector.MatchExpressions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheusOperatorCompat.namespaceSelector: %w", err)
+		}
+		namespaces := &corev1.NamespaceList{}
+		if err := r.List(ctx, namespaces); err != nil {
+			return nil, err
+		}
+		filter.namespaces = map[string]bool{}
+		for _, ns := range namespaces.Items {
+			if len(nsSelector.MatchNames) > 0 && !containsString(nsSelector.MatchNames, ns.Name) {
+				continue
+			}
+			if selector.Matches(labels.Set(ns.Labels)) {
+				filter.namespaces[ns.Name] = true
+			}
+		}
+	}
+	return filter, nil
+}
+
+func (f *prometheusOperatorFilter) matches(obj client.Object) bool {
+	if instance := obj.GetLabels()[monitoringv2alpha1.InstanceLabelKey]; instance != "" && instance != f.instance.Name {
+		return false
+	}
+	if f.namespaces != nil && !f.namespaces[obj.GetNamespace()] {
+		return false
+	}
+	return f.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func hasServiceMonitor(list *monitoringv2alpha1.WhatapServiceMonitorList, namespace, name string) bool {
+	for _, m := range list.Items {
+		if m.Namespace == namespace && m.Name == name && m.Labels[prometheusOperatorSourceLabel] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPodMonitor(list *monitoringv2alpha1.WhatapPodMonitorList, namespace, name string) bool {
+	for _, m := range list.Items {
+		if m.Namespace == namespace && m.Name == name && m.Labels[prometheusOperatorSourceLabel] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStaticEndpoint(list *monitoringv2alpha1.WhatapStaticEndpointList, namespace, name string) bool {
+	for _, m := range list.Items {
+		if m.Namespace == namespace && m.Name == name && m.Labels[prometheusOperatorSourceLabel] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// prometheusOperatorSourceLabel marks the monitors translated from a Prometheus Operator
+// resource with its kind. They only exist in memory.
+const prometheusOperatorSourceLabel = "monitoring.whatap.com/prometheus-operator-kind"
+
+// The prom* types mirror the parts of the monitoring.coreos.com/v1 API the OpenAgent
+// can express.
+type promSecretKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type promSecretOrConfigMap struct {
+	Secret    *promSecretKey `json:"secret,omitempty"`
+	ConfigMap *promSecretKey `json:"configMap,omitempty"`
+}
+
+type promTLSConfig struct {
+	CA                 promSecretOrConfigMap `json:"ca,omitempty"`
+	Cert               promSecretOrConfigMap `json:"cert,omitempty"`
+	KeySecret          *promSecretKey        `json:"keySecret,omitempty"`
+	CAFile             string                `json:"caFile,omitempty"`
+	CertFile           string                `json:"certFile,omitempty"`
+	KeyFile            string                `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool                  `json:"insecureSkipVerify,omitempty"`
+	ServerName         string                `json:"serverName,omitempty"`
+}
+
+type promBasicAuth struct {
+	Username promSecretKey `json:"username"`
+	Password promSecretKey `json:"password"`
+}
+
+type promAuthorization struct {
+	Type        string         `json:"type,omitempty"`
+	Credentials *promSecretKey `json:"credentials,omitempty"`
+}
+
+type promRelabelConfig struct {
+	SourceLabels []string `json:"sourceLabels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	TargetLabel  string   `json:"targetLabel,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Modulus      uint64   `json:"modulus,omitempty"`
+	Replacement  *string  `json:"replacement,omitempty"`
+	Action       string   `json:"action,omitempty"`
+}
+
+// promEndpoint is a ServiceMonitor endpoint or a PodMonitor podMetricsEndpoint.
+type promEndpoint struct {
+	Port              string              `json:"port,omitempty"`
+	PortNumber        int32               `json:"portNumber,omitempty"`
+	TargetPort        *intstr.IntOrString `json:"targetPort,omitempty"`
+	Path              string              `json:"path,omitempty"`
+	Scheme            string              `json:"scheme,omitempty"`
+	Params            map[string][]string `json:"params,omitempty"`
+	Interval          string              `json:"interval,omitempty"`
+	ScrapeTimeout     string              `json:"scrapeTimeout,omitempty"`
+	HonorLabels       bool                `json:"honorLabels,omitempty"`
+	TLSConfig         *promTLSConfig      `json:"tlsConfig,omitempty"`
+	BasicAuth         *promBasicAuth      `json:"basicAuth,omitempty"`
+	Authorization     *promAuthorization  `json:"authorization,omitempty"`
+	BearerTokenSecret *promSecretKey      `json:"bearerTokenSecret,omitempty"`
+	BearerTokenFile   string              `json:"bearerTokenFile,omitempty"`
+	ProxyURL          string              `json:"proxyUrl,omitempty"`
+	Relabelings       []promRelabelConfig `json:"relabelings,omitempty"`
+	MetricRelabelings []promRelabelConfig `json:"metricRelabelings,omitempty"`
+}
+
+type promMonitorSpec struct {
+	JobLabel          string               `json:"jobLabel,omitempty"`
+	Selector          metav1.LabelSelector `json:"selector"`
+	NamespaceSelector struct {
+		Any        bool     `json:"any,omitempty"`
+		MatchNames []string `json:"matchNames,omitempty"`
+	} `json:"namespaceSelector,omitempty"`
+	Endpoints           []promEndpoint `json:"endpoints,omitempty"`
+	PodMetricsEndpoints []promEndpoint `json:"podMetricsEndpoints,omitempty"`
+	TargetLabels        []string       `json:"targetLabels,omitempty"`
+	PodTargetLabels     []string       `json:"podTargetLabels,omitempty"`
+}
+
+// warnings lists the fields of spec the OpenAgent cannot express.
+func (spec *promMonitorSpec) warnings() []string {
+	var warnings []string
+	if len(spec.TargetLabels) > 0 {
+		warnings = append(warnings, fmt.Sprintf("targetLabels %v are not supported", spec.TargetLabels))
+	}
+	if len(spec.PodTargetLabels) > 0 {
+		warnings = append(warnings, fmt.Sprintf("podTargetLabels %v are not supported", spec.PodTargetLabels))
+	}
+	return warnings
+}
+
+type promProbeSpec struct {
+	JobName       string `json:"jobName,omitempty"`
+	Module        string `json:"module,omitempty"`
+	Interval      string `json:"interval,omitempty"`
+	ScrapeTimeout string `json:"scrapeTimeout,omitempty"`
+	Prober        *struct {
+		URL    string `json:"url"`
+		Scheme string `json:"scheme,omitempty"`
+		Path   string `json:"path,omitempty"`
+	} `json:"prober,omitempty"`
+	Targets struct {
+		StaticConfig *struct {
+			Static      []string            `json:"static,omitempty"`
+			Labels      map[string]string   `json:"labels,omitempty"`
+			Relabelings []promRelabelConfig `json:"relabelingConfigs,omitempty"`
+		} `json:"staticConfig,omitempty"`
+		Ingress *json.RawMessage `json:"ingress,omitempty"`
+	} `json:"targets,omitempty"`
+	TLSConfig         *promTLSConfig      `json:"tlsConfig,omitempty"`
+	BasicAuth         *promBasicAuth      `json:"basicAuth,omitempty"`
+	Authorization     *promAuthorization  `json:"authorization,omitempty"`
+	BearerTokenSecret *promSecretKey      `json:"bearerTokenSecret,omitempty"`
+	MetricRelabelings []promRelabelConfig `json:"metricRelabelings,omitempty"`
+}
+
+func decodePrometheusOperatorSpec(u *unstructured.Unstructured, spec interface{}) error {
+	raw, err := json.Marshal(u.Object["spec"])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, spec)
+}
+
+// importedObjectMeta is the metadata of the monitor translated from u.
+func importedObjectMeta(u *unstructured.Unstructured, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:  u.GetNamespace(),
+		Name:       name,
+		Generation: u.GetGeneration(),
+		Labels:     map[string]string{prometheusOperatorSourceLabel: u.GetKind()},
+	}
+}
+
+// serviceMonitorFromPrometheusOperator translates a ServiceMonitor.
+func serviceMonitorFromPrometheusOperator(u *unstructured.Unstructured, defaultNamespace string) ([]monitoringv2alpha1.WhatapServiceMonitor, []string, error) {
+	var spec promMonitorSpec
+	if err := decodePrometheusOperatorSpec(u, &spec); err != nil {
+		return nil, nil, err
+	}
+	var monitors []monitoringv2alpha1.WhatapServiceMonitor
+	groups, warnings := translatePromEndpoints(u, spec.Endpoints, defaultNamespace)
+	warnings = append(spec.warnings(), warnings...)
+	for _, g := range groups {
+		monitors = append(monitors, monitoringv2alpha1.WhatapServiceMonitor{
+			ObjectMeta: importedObjectMeta(u, g.name),
+			Spec: monitoringv2alpha1.WhatapServiceMonitorSpec{
+				Selector:          spec.Selector,
+				NamespaceSelector: promNamespaceSelector(spec.NamespaceSelector.Any, spec.NamespaceSelector.MatchNames),
+				Endpoints:         g.endpoints,
+				RelabelConfigs:    g.relabelConfigs,
+				JobLabel:          spec.JobLabel,
+			},
+		})
+	}
+	return monitors, warnings, nil
+}
+
+// podMonitorFromPrometheusOperator translates a PodMonitor.
+func podMonitorFromPrometheusOperator(u *unstructured.Unstructured, defaultNamespace string) ([]monitoringv2alpha1.WhatapPodMonitor, []string, error) {
+	var spec promMonitorSpec
+	if err := decodePrometheusOperatorSpec(u, &spec); err != nil {
+		return nil, nil, err
+	}
+	var monitors []monitoringv2alpha1.WhatapPodMonitor
+	groups, warnings := translatePromEndpoints(u, spec.PodMetricsEndpoints, defaultNamespace)
+	warnings = append(spec.warnings(), warnings...)
+	for _, g := range groups {
+		monitors = append(monitors, monitoringv2alpha1.WhatapPodMonitor{
+			ObjectMeta: importedObjectMeta(u, g.name),
+			Spec: monitoringv2alpha1.WhatapPodMonitorSpec{
+				Selector:          spec.Selector,
+				NamespaceSelector: promNamespaceSelector(spec.NamespaceSelector.Any, spec.NamespaceSelector.MatchNames),
+				Endpoints:         g.endpoints,
+				RelabelConfigs:    g.relabelConfigs,
+				JobLabel:          spec.JobLabel,
+			},
+		})
+	}
+	return monitors, warnings, nil
+}
+
+// staticEndpointFromPrometheusOperatorProbe translates a Probe with static targets into a
+// static endpoint scraping the prober once per target, the way Prometheus does.
+func staticEndpointFromPrometheusOperatorProbe(u *unstructured.Unstructured, defaultNamespace string) (*monitoringv2alpha1.WhatapStaticEndpoint, []string, error) {
+	var spec promProbeSpec
+	if err := decodePrometheusOperatorSpec(u, &spec); err != nil {
+		return nil, nil, err
+	}
+	if spec.Prober == nil || spec.Prober.URL == "" {
+		return nil, nil, fmt.Errorf("probe has no prober url")
+	}
+	if spec.Targets.StaticConfig == nil || len(spec.Targets.StaticConfig.Static) == 0 {
+		return nil, nil, fmt.Errorf("only probes with targets.staticConfig are supported")
+	}
+	var warnings []string
+	if spec.Targets.Ingress != nil {
+		warnings = append(warnings, "targets.ingress is not supported")
+	}
+	path := spec.Prober.Path
+	if path == "" {
+		path = "/probe"
+	}
+	base, w := translatePromEndpoint(u, promEndpoint{
+		Scheme:            spec.Prober.Scheme,
+		Path:              path,
+		Interval:          spec.Interval,
+		ScrapeTimeout:     spec.ScrapeTimeout,
+		TLSConfig:         spec.TLSConfig,
+		BasicAuth:         spec.BasicAuth,
+		Authorization:     spec.Authorization,
+		BearerTokenSecret: spec.BearerTokenSecret,
+		MetricRelabelings: spec.MetricRelabelings,
+	}, defaultNamespace)
+	warnings = append(warnings, w...)
+
+	se := &monitoringv2alpha1.WhatapStaticEndpoint{ObjectMeta: importedObjectMeta(u, u.GetName())}
+	for _, target := range spec.Targets.StaticConfig.Static {
+		endpoint := *base.DeepCopy()
+		endpoint.Address = spec.Prober.URL
+		endpoint.Params = map[string][]string{"target": {target}}
+		if spec.Module != "" {
+			endpoint.Params["module"] = []string{spec.Module}
+		}
+		se.Spec.Endpoints = append(se.Spec.Endpoints, endpoint)
+	}
+	// 대상 주소를 instance 라벨로, staticConfig.labels 를 고정 라벨로 붙인다 (Prometheus Operator 와 동일).
+	relabelConfigs := []monitoringv2alpha1.MetricRelabelConfig{
+		{SourceLabels: []string{"__param_target"}, TargetLabel: "instance", Action: "replace"},
+	}
+	if spec.JobName != "" {
+		relabelConfigs = append(relabelConfigs, monitoringv2alpha1.MetricRelabelConfig{TargetLabel: "job", Replacement: spec.JobName, Action: "replace"})
+	}
+	keys := make([]string, 0, len(spec.Targets.StaticConfig.Labels))
+	for key := range spec.Targets.StaticConfig.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		relabelConfigs = append(relabelConfigs, monitoringv2alpha1.MetricRelabelConfig{
+			TargetLabel: key,
+			Replacement: spec.Targets.StaticConfig.Labels[key],
+			Action:      "replace",
+		})
+	}
+	se.Spec.RelabelConfigs = append(relabelConfigs, translatePromRelabelConfigs(spec.Targets.StaticConfig.Relabelings)...)
+	return se, warnings, nil
+}
+
+// promNamespaceSelector translates a Prometheus Operator namespaceSelector. An empty one
+// selects the namespace of the monitor, like a WhaTap monitor without namespaceSelector.
+func promNamespaceSelector(anyNamespace bool, matchNames []string) *monitoringv2alpha1.NamespaceSelector {
+	switch {
+	case anyNamespace:
+		return &monitoringv2alpha1.NamespaceSelector{}
+	case len(matchNames) > 0:
+		return &monitoringv2alpha1.NamespaceSelector{MatchNames: matchNames}
+	}
+	return nil
+}
+
+// promEndpointGroup is a set of endpoints sharing the same target relabelings.
+type promEndpointGroup struct {
+	name           string
+	relabelConfigs []monitoringv2alpha1.MetricRelabelConfig
+	endpoints      []monitoringv2alpha1.OpenAgentEndpoint
+}
+
+// translatePromEndpoints translates the endpoints of a ServiceMonitor/PodMonitor. The
+// relabelings of a Prometheus Operator endpoint apply to that endpoint only, while a WhaTap
+// monitor has one set per target, so endpoints with different relabelings become separate
+// monitors named <name>-<index>.
+func translatePromEndpoints(u *unstructured.Unstructured, endpoints []promEndpoint, defaultNamespace string) ([]promEndpointGroup, []string) {
+	var warnings []string
+	var groups []promEndpointGroup
+	split := false
+	for i := 1; i < len(endpoints); i++ {
+		if !reflect.DeepEqual(endpoints[i].Relabelings, endpoints[0].Relabelings) {
+			split = true
+			break
+		}
+	}
+	for i, ep := range endpoints {
+		endpoint, w := translatePromEndpoint(u, ep, defaultNamespace)
+		warnings = append(warnings, w...)
+		if endpoint == nil {
+			continue
+		}
+		if split || len(groups) == 0 {
+			name := u.GetName()
+			if split {
+				name = fmt.Sprintf("%s-%d", name, i)
+			}
+			groups = append(groups, promEndpointGroup{name: name, relabelConfigs: translatePromRelabelConfigs(ep.Relabelings)})
+		}
+		groups[len(groups)-1].endpoints = append(groups[len(groups)-1].endpoints, *endpoint)
+	}
+	return groups, warnings
+}
+
+// translatePromEndpoint translates one endpoint. TLS Secrets can only be mounted from
+// the OpenAgent namespace; references to other namespaces are dropped with a warning.
+// Basic auth and authorization Secrets are read by the OpenAgent from the namespace of
+// the resource. It returns nil for a ServiceMonitor endpoint selected by targetPort only:
+// targetPort is a container port of the backing pods, while a WhaTap ServiceMonitor
+// endpoint only selects a Service port by name.
+func translatePromEndpoint(u *unstructured.Unstructured, ep promEndpoint, defaultNamespace string) (*monitoringv2alpha1.OpenAgentEndpoint, []string) {
+	var warnings []string
+	if ep.Port == "" && ep.TargetPort != nil && u.GetKind() == string(monitoringv2alpha1.PrometheusOperatorServiceMonitor) {
+		return nil, []string{fmt.Sprintf("endpoint with targetPort %s is skipped: set port to the name of the Service port", ep.TargetPort.String())}
+	}
+	if ep.HonorLabels {
+		warnings = append(warnings, "honorLabels is not supported")
+	}
+	if ep.ScrapeTimeout != "" {
+		warnings = append(warnings, "scrapeTimeout is not supported")
+	}
+	if ep.BearerTokenFile != "" {
+		warnings = append(warnings, "bearerTokenFile is not supported, use authorization.credentials")
+	}
+	if ep.ProxyURL != "" {
+		warnings = append(warnings, "proxyUrl is not supported")
+	}
+	namespace := u.GetNamespace()
+	secretRef := func(sel *promSecretKey) *monitoringv2alpha1.SecretKeySelector {
+		if sel == nil || sel.Name == "" {
+			return nil
+		}
+		return &monitoringv2alpha1.SecretKeySelector{Name: sel.Name, Key: sel.Key, Namespace: namespace}
+	}
+	tlsSecret := func(field string, ref promSecretOrConfigMap) *monitoringv2alpha1.SecretKeySelector {
+		if ref.ConfigMap != nil {
+			warnings = append(warnings, fmt.Sprintf("tlsConfig.%s.configMap is not supported", field))
+		}
+		if ref.Secret == nil {
+			return nil
+		}
+		if namespace != defaultNamespace {
+			warnings = append(warnings, fmt.Sprintf("tlsConfig.%s Secret %s is outside namespace %s and cannot be mounted", field, ref.Secret.Name, defaultNamespace))
+			return nil
+		}
+		return &monitoringv2alpha1.SecretKeySelector{Name: ref.Secret.Name, Key: ref.Secret.Key}
+	}
+
+	endpoint := &monitoringv2alpha1.OpenAgentEndpoint{
+		Port:                 ep.Port,
+		Path:                 ep.Path,
+		Scheme:               ep.Scheme,
+		Interval:             ep.Interval,
+		Params:               ep.Params,
+		MetricRelabelConfigs: translatePromRelabelConfigs(ep.MetricRelabelings),
+	}
+	// The deprecated targetPort of a PodMonitor is a container port, like port
+	if endpoint.Port == "" && ep.TargetPort != nil {
+		endpoint.Port = ep.TargetPort.String()
+	}
+	if endpoint.Port == "" && ep.PortNumber != 0 {
+		endpoint.Port = fmt.Sprint(ep.PortNumber)
+	}
+	if tls := ep.TLSConfig; tls != nil {
+		endpoint.TLSConfig = &monitoringv2alpha1.TLSConfig{
+			InsecureSkipVerify: tls.InsecureSkipVerify,
+			ServerName:         tls.ServerName,
+			CAFile:             tls.CAFile,
+			CertFile:           tls.CertFile,
+			KeyFile:            tls.KeyFile,
+			CASecret:           tlsSecret("ca", tls.CA),
+			CertSecret:         tlsSecret("cert", tls.Cert),
+			KeySecret:          tlsSecret("keySecret", promSecretOrConfigMap{Secret: tls.KeySecret}),
+		}
+	}
+	if ep.BasicAuth != nil {
+		endpoint.BasicAuth = &monitoringv2alpha1.BasicAuthConfig{
+			Username: secretRef(&ep.BasicAuth.Username),
+			Password: secretRef(&ep.BasicAuth.Password),
+		}
+	}
+	switch {
+	case ep.Authorization != nil:
+		endpoint.Authorization = &monitoringv2alpha1.AuthorizationConfig{
+			Type:              ep.Authorization.Type,
+			CredentialsSecret: secretRef(ep.Authorization.Credentials),
+		}
+	case ep.BearerTokenSecret != nil && ep.BearerTokenSecret.Name != "":
+		endpoint.Authorization = &monitoringv2alpha1.AuthorizationConfig{
+			Type:              "Bearer",
+			CredentialsSecret: secretRef(ep.BearerTokenSecret),
+		}
+	}
+	return endpoint, warnings
+}
+
+func translatePromRelabelConfigs(configs []promRelabelConfig) []monitoringv2alpha1.MetricRelabelConfig {
+	var result []monitoringv2alpha1.MetricRelabelConfig
+	for _, rc := range configs {
+		c := monitoringv2alpha1.MetricRelabelConfig{
+			SourceLabels: rc.SourceLabels,
+			Separator:    rc.Separator,
+			Regex:        rc.Regex,
+			Modulus:      rc.Modulus,
+			TargetLabel:  rc.TargetLabel,
+			Action:       strings.ToLower(rc.Action),
+		}
+		if rc.Replacement != nil {
+			c.Replacement = *rc.Replacement
+		}
+		result = append(result, c)
+	}
+	return result
+}
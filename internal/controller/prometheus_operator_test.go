@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+func prometheusOperatorObject(kind monitoringv2alpha1.PrometheusOperatorKind, namespace, name string, objLabels map[string]string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(prometheusOperatorGVK(kind))
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetLabels(objLabels)
+	return u
+}
+
+func TestServiceMonitorFromPrometheusOperator(t *testing.T) {
+	u := prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "shop", "orders", nil, map[string]interface{}{
+		"jobLabel":          "app.kubernetes.io/name",
+		"selector":          map[string]interface{}{"matchLabels": map[string]interface{}{"app": "orders"}},
+		"namespaceSelector": map[string]interface{}{"any": true},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"port":     "metrics",
+				"interval": "30s",
+				"scheme":   "https",
+				"tlsConfig": map[string]interface{}{
+					"ca":         map[string]interface{}{"secret": map[string]interface{}{"name": "orders-ca", "key": "ca.crt"}},
+					"serverName": "orders.shop.svc",
+				},
+				"bearerTokenSecret": map[string]interface{}{"name": "scrape-token", "key": "token"},
+				"metricRelabelings": []interface{}{
+					map[string]interface{}{"sourceLabels": []interface{}{"__name__"}, "regex": "go_.*", "action": "Drop"},
+				},
+			},
+			map[string]interface{}{
+				"port":          "admin",
+				"honorLabels":   true,
+				"scrapeTimeout": "10s",
+				"relabelings": []interface{}{
+					map[string]interface{}{"targetLabel": "endpoint", "replacement": "admin"},
+				},
+			},
+			// targetPort is a container port, not a Service port
+			map[string]interface{}{"targetPort": 9090},
+		},
+		"targetLabels": []interface{}{"team"},
+	})
+
+	monitors, warnings, err := serviceMonitorFromPrometheusOperator(u, "whatap-monitoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The endpoints have different relabelings, so each becomes its own target
+	if len(monitors) != 2 || monitors[0].Name != "orders-0" || monitors[1].Name != "orders-1" {
+		t.Fatalf("Expected one monitor per endpoint, got %+v", monitors)
+	}
+	first := monitors[0]
+	if first.Spec.JobLabel != "app.kubernetes.io/name" || first.Spec.NamespaceSelector == nil || len(first.Spec.NamespaceSelector.MatchNames) != 0 {
+		t.Errorf("Unexpected spec %+v", first.Spec)
+	}
+	ep := first.Spec.Endpoints[0]
+	if ep.Port != "metrics" || ep.Interval != "30s" || ep.TLSConfig.ServerName != "orders.shop.svc" || ep.TLSConfig.CASecret != nil {
+		t.Errorf("Unexpected endpoint %+v", ep)
+	}
+	if ep.Authorization == nil || ep.Authorization.Type != "Bearer" || ep.Authorization.CredentialsSecret.Namespace != "shop" {
+		t.Errorf("Expected the bearer token Secret of the monitor namespace, got %+v", ep.Authorization)
+	}
+	if ep.MetricRelabelConfigs[0].Action != "drop" {
+		t.Errorf("Expected the action to be lowercased, got %+v", ep.MetricRelabelConfigs)
+	}
+	// The CA Secret lives outside the OpenAgent namespace and cannot be mounted; the
+	// other dropped fields are reported too
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{"targetLabels", "orders-ca", "honorLabels", "scrapeTimeout", "targetPort 9090"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected a warning for %s, got %v", want, warnings)
+		}
+	}
+
+	second := monitors[1]
+	if second.Spec.Endpoints[0].Port != "admin" || second.Spec.RelabelConfigs[0].Replacement != "admin" {
+		t.Errorf("Unexpected second monitor %+v", second.Spec)
+	}
+}
+
+func TestPodMonitorFromPrometheusOperatorTargetPort(t *testing.T) {
+	u := prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorPodMonitor, "shop", "orders", nil, map[string]interface{}{
+		"selector":            map[string]interface{}{"matchLabels": map[string]interface{}{"app": "orders"}},
+		"podMetricsEndpoints": []interface{}{map[string]interface{}{"targetPort": "http-metrics", "proxyUrl": "http://proxy:3128"}},
+	})
+	monitors, warnings, err := podMonitorFromPrometheusOperator(u, "whatap-monitoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The targetPort of a PodMonitor is a container port, like port
+	if len(monitors) != 1 || monitors[0].Spec.Endpoints[0].Port != "http-metrics" {
+		t.Fatalf("Expected the container port to be kept, got %+v", monitors)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "proxyUrl") {
+		t.Errorf("Expected a warning for proxyUrl, got %v", warnings)
+	}
+}
+
+func TestStaticEndpointFromPrometheusOperatorProbe(t *testing.T) {
+	u := prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorProbe, "infra", "websites", nil, map[string]interface{}{
+		"module": "http_2xx",
+		"prober": map[string]interface{}{"url": "blackbox-exporter.infra.svc:9115"},
+		"targets": map[string]interface{}{
+			"staticConfig": map[string]interface{}{
+				"static": []interface{}{"https://example.com", "https://whatap.io"},
+				"labels": map[string]interface{}{"env": "prod"},
+			},
+		},
+	})
+	se, warnings, err := staticEndpointFromPrometheusOperatorProbe(u, "whatap-monitoring")
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("Unexpected result: %v %v", err, warnings)
+	}
+	if len(se.Spec.Endpoints) != 2 {
+		t.Fatalf("Expected one endpoint per target, got %+v", se.Spec.Endpoints)
+	}
+	ep := se.Spec.Endpoints[1]
+	if ep.Address != "blackbox-exporter.infra.svc:9115" || ep.Path != "/probe" ||
+		ep.Params["target"][0] != "https://whatap.io" || ep.Params["module"][0] != "http_2xx" {
+		t.Errorf("Unexpected endpoint %+v", ep)
+	}
+	if len(se.Spec.RelabelConfigs) != 2 || se.Spec.RelabelConfigs[1].TargetLabel != "env" {
+		t.Errorf("Unexpected relabel configs %+v", se.Spec.RelabelConfigs)
+	}
+
+	u.Object["spec"] = map[string]interface{}{"prober": map[string]interface{}{"url": "x"}}
+	if _, _, err := staticEndpointFromPrometheusOperatorProbe(u, "whatap-monitoring"); err == nil {
+		t.Errorf("Expected an error for a probe without static targets")
+	}
+}
+
+func TestImportPrometheusOperatorMonitors(t *testing.T) {
+	spec := func(app string) map[string]interface{} {
+		return map[string]interface{}{
+			"selector":  map[string]interface{}{"matchLabels": map[string]interface{}{"app": app}},
+			"endpoints": []interface{}{map[string]interface{}{"port": "metrics"}},
+		}
+	}
+	// Only the ServiceMonitor CRD is installed
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(prometheusOperatorGVK(monitoringv2alpha1.PrometheusOperatorServiceMonitor), apimeta.RESTScopeNamespace)
+	c := newFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"whatap": "on"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "shop", "orders", map[string]string{"release": "shop"}, spec("orders")),
+		prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "shop", "cart", map[string]string{"release": "shop"}, spec("cart")),
+		prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "shop", "unreleased", nil, spec("unreleased")),
+		prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "shop", "tenant", map[string]string{"release": "shop", monitoringv2alpha1.InstanceLabelKey: "tenant"}, spec("tenant")),
+		prometheusOperatorObject(monitoringv2alpha1.PrometheusOperatorServiceMonitor, "kube-system", "coredns", map[string]string{"release": "shop"}, spec("coredns")),
+	)
+	r := &WhatapAgentReconciler{
+		Client:           c,
+		Scheme:           testScheme,
+		DefaultNamespace: "whatap-monitoring",
+		restMapper:       mapper,
+	}
+	cr := &monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: monitoringv2alpha1.DefaultWhatapAgentName}}
+	cr.Spec.Features.OpenAgent.PrometheusOperatorCompat = &monitoringv2alpha1.PrometheusOperatorCompatSpec{
+		Enabled:           true,
+		NamespaceSelector: &monitoringv2alpha1.NamespaceSelector{MatchLabels: map[string]string{"whatap": "on"}},
+		Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"release": "shop"}},
+	}
+
+	// A WhaTap CR of the same name takes precedence over the imported one
+	own := monitoringv2alpha1.WhatapServiceMonitor{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "cart"}}
+	serviceMonitors := &monitoringv2alpha1.WhatapServiceMonitorList{Items: []monitoringv2alpha1.WhatapServiceMonitor{own}}
+	podMonitors := &monitoringv2alpha1.WhatapPodMonitorList{}
+	staticEndpoints := &monitoringv2alpha1.WhatapStaticEndpointList{}
+	if err := r.importPrometheusOperatorMonitors(context.Background(), logr.Discard(), cr, podMonitors, serviceMonitors, staticEndpoints); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range serviceMonitors.Items {
+		names = append(names, m.Namespace+"/"+m.Name)
+	}
+	if strings.Join(names, ",") != "shop/cart,shop/orders" {
+		t.Errorf("Expected shop/orders to be imported next to the WhaTap CR, got %v", names)
+	}
+
+	// The imported monitors end up in the scrape config like WhaTap CRs
+	cr.Spec.Features.OpenAgent.Enabled = true
+	config := generateScrapeConfig(cr, r.DefaultNamespace, podMonitors, serviceMonitors, staticEndpoints)
+	if !strings.Contains(config, "targetName: shop/orders") {
+		t.Errorf("Expected the imported monitor in the scrape config:\n%s", config)
+	}
+
+	// The kinds without a CRD are reported in the status, and picked up and watched
+	// once their CRD is installed
+	cond := r.prometheusOperatorCompatCondition(cr.Spec.Features.OpenAgent.PrometheusOperatorCompat)
+	if cond.Status != metav1.ConditionFalse || cond.Reason != "CRDNotInstalled" || !strings.Contains(cond.Message, "PodMonitor, Probe") {
+		t.Errorf("Expected the PodMonitor and Probe kinds to be reported as skipped, got %+v", cond)
+	}
+	var watched []monitoringv2alpha1.PrometheusOperatorKind
+	r.watchPrometheusOperatorKind = func(kind monitoringv2alpha1.PrometheusOperatorKind) error {
+		watched = append(watched, kind)
+		return nil
+	}
+	mapper.Add(prometheusOperatorGVK(monitoringv2alpha1.PrometheusOperatorPodMonitor), apimeta.RESTScopeNamespace)
+	mapper.Add(prometheusOperatorGVK(monitoringv2alpha1.PrometheusOperatorProbe), apimeta.RESTScopeNamespace)
+	cond = r.prometheusOperatorCompatCondition(cr.Spec.Features.OpenAgent.PrometheusOperatorCompat)
+	if cond.Status != metav1.ConditionTrue {
+		t.Errorf("Expected every kind to be imported, got %+v", cond)
+	}
+	if len(watched) != 3 {
+		t.Errorf("Expected every installed kind to be watched once, got %v", watched)
+	}
+	if _, err := r.installedPrometheusOperatorKinds(); err != nil || len(watched) != 3 {
+		t.Errorf("Expected no duplicate watches, got %v %v", watched, err)
+	}
+}
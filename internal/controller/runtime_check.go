@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// RuntimeCheckReconciler reports the result of the runtime version checks the injection
+// webhook adds to pods (TargetSpec.RuntimeCheck): a Warning event on the pod when the
+// agent was disabled or the runtime version could not be detected.
+type RuntimeCheckReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// reported remembers the checks already reported per pod, so a pod update does not
+	// repeat its events
+	reportedMu sync.Mutex
+	reported   map[types.NamespacedName]reportedChecks
+}
+
+type reportedChecks struct {
+	uid        types.UID
+	containers map[string]struct{}
+}
+
+func (r *RuntimeCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if !strings.HasPrefix(status.Name, monitoringv2alpha1.RuntimeCheckContainerPrefix+"-") || status.State.Terminated == nil {
+			continue
+		}
+		reason, ok := runtimeCheckEventReason(status.State.Terminated.Message)
+		if !ok || !r.markReported(pod, status.Name) {
+			continue
+		}
+		log := ctrl.LoggerFrom(ctx)
+		log.Info("Runtime version check", "pod", req.NamespacedName, "result", status.State.Terminated.Message)
+		r.Recorder.Event(pod, corev1.EventTypeWarning, reason, strings.TrimSpace(status.State.Terminated.Message))
+	}
+	return ctrl.Result{}, nil
+}
+
+// runtimeCheckEventReason returns the event reason for the termination message of a
+// runtime check, and false for checks that need no event.
+func runtimeCheckEventReason(message string) (string, bool) {
+	switch {
+	case strings.HasPrefix(message, monitoringv2alpha1.RuntimeCheckDisabled+":"):
+		return "ApmAgentDisabled", true
+	case strings.HasPrefix(message, monitoringv2alpha1.RuntimeCheckUnknown+":"):
+		return "RuntimeVersionUnknown", true
+	}
+	return "", false
+}
+
+// markReported records that the check container of pod was reported. It returns false
+// if it already was.
+func (r *RuntimeCheckReconciler) markReported(pod *corev1.Pod, container string) bool {
+	r.reportedMu.Lock()
+	defer r.reportedMu.Unlock()
+	if r.reported == nil {
+		r.reported = map[types.NamespacedName]reportedChecks{}
+	}
+	key := client.ObjectKeyFromObject(pod)
+	entry, ok := r.reported[key]
+	if !ok || entry.uid != pod.UID {
+		entry = reportedChecks{uid: pod.UID, containers: map[string]struct{}{}}
+		r.reported[key] = entry
+	}
+	if _, done := entry.containers[container]; done {
+		return false
+	}
+	entry.containers[container] = struct{}{}
+	return true
+}
+
+func (r *RuntimeCheckReconciler) forget(key types.NamespacedName) {
+	r.reportedMu.Lock()
+	defer r.reportedMu.Unlock()
+	delete(r.reported, key)
+}
+
+// SetupWithManager sets up the controller with the Manager. Only pods the webhook
+// injected are cached (see cmd/main.go).
+func (r *RuntimeCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	injected := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[monitoringv2alpha1.InjectionLabelKey] == "true"
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("whatapagent-runtime-check").
+		For(&corev1.Pod{}, builder.WithPredicates(injected)).
+		Complete(r)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func runtimeCheckStatus(name, message string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+	}
+}
+
+func TestRuntimeCheckReconciler(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "orders-0", UID: "uid-1"},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+			{Name: "whatap-agent-init"},
+			runtimeCheckStatus("whatap-runtime-check-0", "disabled: java 1.6.0_45 of container app is older than 8"),
+			runtimeCheckStatus("whatap-runtime-check-1", "enabled: java 17.0.2 of container sidecar"),
+			runtimeCheckStatus("whatap-runtime-check-2", "unknown: no java found in container batch"),
+		}},
+	}
+	c := newFakeClient(pod)
+	recorder := record.NewFakeRecorder(10)
+	r := &RuntimeCheckReconciler{Client: c, Scheme: testScheme, Recorder: recorder}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(pod)}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+	}
+	expected := []string{
+		"Warning ApmAgentDisabled disabled: java 1.6.0_45 of container app is older than 8",
+		"Warning RuntimeVersionUnknown unknown: no java found in container batch",
+	}
+	for _, want := range expected {
+		select {
+		case got := <-recorder.Events:
+			if got != want {
+				t.Errorf("Expected event %q, got %q", want, got)
+			}
+		default:
+			t.Fatalf("Missing event %q", want)
+		}
+	}
+	select {
+	case got := <-recorder.Events:
+		t.Errorf("Unexpected event %q", got)
+	default:
+	}
+
+	// A deleted pod is forgotten
+	if err := c.Delete(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if _, ok := r.reported[req.NamespacedName]; ok {
+		t.Errorf("Expected the deleted pod to be forgotten")
+	}
+}
@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition types reported on WhatapAgentStatus.
+const (
+	conditionAvailable                        = "Available"
+	conditionMasterAgentReady                 = "MasterAgentReady"
+	conditionNodeAgentReady                   = "NodeAgentReady"
+	conditionGpuMonitoringReady               = "GpuMonitoringReady"
+	conditionOpenAgentReady                   = "OpenAgentReady"
+	conditionWebhookReady                     = "WebhookReady"
+	conditionInstrumentationReady             = "InstrumentationReady"
+	conditionApiserverMonitoringReady         = "ApiserverMonitoringReady"
+	conditionEtcdMonitoringReady              = "EtcdMonitoringReady"
+	conditionSchedulerMonitoringReady         = "SchedulerMonitoringReady"
+	conditionControllerManagerMonitoringReady = "ControllerManagerMonitoringReady"
+	conditionPrometheusOperatorImported       = "PrometheusOperatorImported"
+)
+
+// componentConditionTypes lists every per-component condition so the ones
+// belonging to disabled components can be pruned from the status.
+var componentConditionTypes = []string{
+	conditionMasterAgentReady,
+	conditionNodeAgentReady,
+	conditionGpuMonitoringReady,
+	conditionOpenAgentReady,
+	conditionWebhookReady,
+	conditionInstrumentationReady,
+	conditionApiserverMonitoringReady,
+	conditionEtcdMonitoringReady,
+	conditionSchedulerMonitoringReady,
+	conditionControllerManagerMonitoringReady,
+	conditionPrometheusOperatorImported,
+}
+
+// deploymentCondition derives a readiness condition from the Deployment status.
+// The rollout must be observed by the deployment controller, fully updated and
+// every desired replica must be available.
+func deploymentCondition(conditionType string, deploy *appsv1.Deployment) metav1.Condition {
+	desired := int32(1)
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
+	}
+	st := deploy.Status
+	switch {
+	case st.ObservedGeneration < deploy.Generation:
+		return notReadyCondition(conditionType, "Progressing",
+			fmt.Sprintf("Deployment %s rollout not yet observed", deploy.Name))
+	case st.UpdatedReplicas < desired:
+		return notReadyCondition(conditionType, "Progressing",
+			fmt.Sprintf("Deployment %s: %d/%d replicas updated", deploy.Name, st.UpdatedReplicas, desired))
+	case st.AvailableReplicas < desired || st.UnavailableReplicas > 0:
+		return notReadyCondition(conditionType, "ReplicasUnavailable",
+			fmt.Sprintf("Deployment %s: %d/%d replicas available", deploy.Name, st.AvailableReplicas, desired))
+	}
+	return readyCondition(conditionType,
+		fmt.Sprintf("Deployment %s: %d/%d replicas available", deploy.Name, st.AvailableReplicas, desired))
+}
+
+// daemonSetCondition derives a readiness condition from the DaemonSet status.
+func daemonSetCondition(conditionType string, ds *appsv1.DaemonSet) metav1.Condition {
+	st := ds.Status
+	switch {
+	case st.ObservedGeneration < ds.Generation:
+		return notReadyCondition(conditionType, "Progressing",
+			fmt.Sprintf("DaemonSet %s rollout not yet observed", ds.Name))
+	case st.UpdatedNumberScheduled < st.DesiredNumberScheduled:
+		return notReadyCondition(conditionType, "Progressing",
+			fmt.Sprintf("DaemonSet %s: %d/%d pods updated", ds.Name, st.UpdatedNumberScheduled, st.DesiredNumberScheduled))
+	case st.NumberUnavailable > 0 || st.NumberReady < st.DesiredNumberScheduled:
+		return notReadyCondition(conditionType, "PodsUnavailable",
+			fmt.Sprintf("DaemonSet %s: %d/%d pods ready, %d unavailable", ds.Name, st.NumberReady, st.DesiredNumberScheduled, st.NumberUnavailable))
+	}
+	return readyCondition(conditionType,
+		fmt.Sprintf("DaemonSet %s: %d/%d pods ready", ds.Name, st.NumberReady, st.DesiredNumberScheduled))
+}
+
+func readyCondition(conditionType, message string) metav1.Condition {
+	return metav1.Condition{Type: conditionType, Status: metav1.ConditionTrue, Reason: "Ready", Message: message}
+}
+
+func notReadyCondition(conditionType, reason, message string) metav1.Condition {
+	return metav1.Condition{Type: conditionType, Status: metav1.ConditionFalse, Reason: reason, Message: message}
+}
+
+// mergeConditions combines several conditions of the same type into one,
+// keeping the first failure (or the last success when all are ready).
+func mergeConditions(conditionType string, conditions ...metav1.Condition) metav1.Condition {
+	var messages []string
+	for _, c := range conditions {
+		if c.Status != metav1.ConditionTrue {
+			c.Type = conditionType
+			return c
+		}
+		messages = append(messages, c.Message)
+	}
+	return readyCondition(conditionType, strings.Join(messages, "; "))
+}
+
+func (r *WhatapAgentReconciler) deploymentReadiness(ctx context.Context, conditionType, name string) metav1.Condition {
+	deploy := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: r.DefaultNamespace}, deploy); err != nil {
+		return notReadyCondition(conditionType, "NotFound", fmt.Sprintf("Deployment %s: %v", name, err))
+	}
+	return deploymentCondition(conditionType, deploy)
+}
+
+func (r *WhatapAgentReconciler) daemonSetReadiness(ctx context.Context, conditionType, name string) (metav1.Condition, bool) {
+	ds := &appsv1.DaemonSet{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: r.DefaultNamespace}, ds); err != nil {
+		return notReadyCondition(conditionType, "NotFound", fmt.Sprintf("DaemonSet %s: %v", name, err)), false
+	}
+	return daemonSetCondition(conditionType, ds), true
+}
+
+// webhookReadiness checks that the webhook Service exists and every webhook in
+// the MutatingWebhookConfiguration carries a CA bundle.
+func (r *WhatapAgentReconciler) webhookReadiness(ctx context.Context) metav1.Condition {
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, client.ObjectKey{Name: webhookServiceName, Namespace: r.DefaultNamespace}, svc); err != nil {
+		return notReadyCondition(conditionWebhookReady, "ServiceNotFound", fmt.Sprintf("Service %s: %v", webhookServiceName, err))
+	}
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := r.Get(ctx, client.ObjectKey{Name: webhookConfigurationName}, mwc); err != nil {
+		return notReadyCondition(conditionWebhookReady, "NotFound", fmt.Sprintf("MutatingWebhookConfiguration %s: %v", webhookConfigurationName, err))
+	}
+	if len(mwc.Webhooks) == 0 {
+		return notReadyCondition(conditionWebhookReady, "NoWebhooks", "MutatingWebhookConfiguration has no webhooks")
+	}
+	for _, wh := range mwc.Webhooks {
+		if len(wh.ClientConfig.CABundle) == 0 {
+			return notReadyCondition(conditionWebhookReady, "CABundleMissing", fmt.Sprintf("webhook %s has no CA bundle", wh.Name))
+		}
+	}
+	return readyCondition(conditionWebhookReady, fmt.Sprintf("%d webhook(s) configured", len(mwc.Webhooks)))
+}
+
+// componentConditions computes a readiness condition for every enabled
+// component from the live state of the workloads it owns. overrides replace the
+// computed condition of the same type (e.g. control plane not found).
+func (r *WhatapAgentReconciler) componentConditions(ctx context.Context, cr *monitoringv2alpha1.WhatapAgent, overrides []metav1.Condition) []metav1.Condition {
+	k8s := cr.Spec.Features.K8sAgent
+	var conditions []metav1.Condition
+
+	if k8s.MasterAgent.Enabled {
+		conditions = append(conditions, r.deploymentReadiness(ctx, conditionMasterAgentReady, cr.ResourceName("whatap-master-agent")))
+	}
+	if k8s.NodeAgent.Enabled {
+		nodeCond, _ := r.daemonSetReadiness(ctx, conditionNodeAgentReady, cr.ResourceName("whatap-node-agent"))
+		// The GPU DaemonSet only exists when GPU nodes are split out by selector/affinity
+		gpuCond, hasGpuDS := r.daemonSetReadiness(ctx, conditionGpuMonitoringReady, cr.ResourceName("whatap-node-agent-gpu"))
+		if hasGpuDS {
+			conditions = append(conditions, mergeConditions(conditionNodeAgentReady, nodeCond, gpuCond))
+		} else {
+			conditions = append(conditions, nodeCond)
+		}
+		if k8s.GpuMonitoring.Enabled {
+			if !hasGpuDS {
+				gpuCond = nodeCond
+			}
+			gpuCond.Type = conditionGpuMonitoringReady
+			conditions = append(conditions, gpuCond)
+		}
+	}
+	if cr.Spec.Features.OpenAgent.Enabled {
+		var shardConds []metav1.Condition
+		for shard := 0; shard < openAgentShards(cr); shard++ {
+			shardConds = append(shardConds, r.deploymentReadiness(ctx, conditionOpenAgentReady, openAgentShardName(cr, "whatap-open-agent", shard)))
+		}
+		conditions = append(conditions, mergeConditions(conditionOpenAgentReady, shardConds...))
+		if compat := cr.Spec.Features.OpenAgent.PrometheusOperatorCompat; compat != nil && compat.Enabled {
+			conditions = append(conditions, r.prometheusOperatorCompatCondition(compat))
+		}
+	}
+	if k8s.ApiserverMonitoring.Enabled {
+		conditions = append(conditions, r.deploymentReadiness(ctx, conditionApiserverMonitoringReady, cr.ResourceName(apiserverMonitorName)))
+	}
+	if k8s.EtcdMonitoring.Enabled {
+		conditions = append(conditions, r.deploymentReadiness(ctx, conditionEtcdMonitoringReady, cr.ResourceName(etcdMonitorName)))
+	}
+	if k8s.SchedulerMonitoring.Enabled {
+		conditions = append(conditions, r.deploymentReadiness(ctx, conditionSchedulerMonitoringReady, cr.ResourceName(schedulerMonitorName)))
+	}
+	if k8s.ControllerManagerMonitoring.Enabled {
+		conditions = append(conditions, r.deploymentReadiness(ctx, conditionControllerManagerMonitoringReady, cr.ResourceName(controllerManagerMonitorName)))
+	}
+
+	webhookCond := r.webhookReadiness(ctx)
+	conditions = append(conditions, webhookCond)
+
+	inst := cr.Spec.Features.Apm.Instrumentation
+	if inst.Enabled {
+		enabledTargets := 0
+		for _, t := range inst.Targets {
+			if t.Enabled {
+				enabledTargets++
+			}
+		}
+		// Without targets nothing is injected, so there is nothing to report
+		if enabledTargets > 0 {
+			if webhookCond.Status != metav1.ConditionTrue {
+				conditions = append(conditions, notReadyCondition(conditionInstrumentationReady, "WebhookNotReady", webhookCond.Message))
+			} else {
+				conditions = append(conditions, readyCondition(conditionInstrumentationReady,
+					fmt.Sprintf("%d instrumentation target(s) active", enabledTargets)))
+			}
+		}
+	}
+
+	for _, o := range overrides {
+		replaced := false
+		for i := range conditions {
+			if conditions[i].Type == o.Type {
+				conditions[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			conditions = append(conditions, o)
+		}
+	}
+	return conditions
+}
+
+// availableCondition aggregates the component conditions. Components whose
+// control plane cannot be discovered and Prometheus Operator kinds without a CRD
+// are informational and do not block availability.
+func availableCondition(conditions []metav1.Condition) metav1.Condition {
+	var notReady []string
+	for _, c := range conditions {
+		if c.Status != metav1.ConditionTrue && c.Reason != "ControlPlaneNotFound" && c.Reason != "CRDNotInstalled" {
+			notReady = append(notReady, fmt.Sprintf("%s (%s)", c.Type, c.Message))
+		}
+	}
+	if len(notReady) > 0 {
+		return metav1.Condition{
+			Type:    conditionAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ComponentsNotReady",
+			Message: "Not ready: " + strings.Join(notReady, ", "),
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Installed",
+		Message: "WhatapAgent installed successfully",
+	}
+}
+
+// applyComponentConditions writes conditions and the aggregated Available
+// condition into status, removing conditions of components no longer enabled.
+func applyComponentConditions(status *monitoringv2alpha1.WhatapAgentStatus, conditions []metav1.Condition) {
+	present := map[string]bool{}
+	for _, c := range conditions {
+		present[c.Type] = true
+		apimeta.SetStatusCondition(&status.Conditions, c)
+	}
+	for _, t := range componentConditionTypes {
+		if !present[t] {
+			apimeta.RemoveStatusCondition(&status.Conditions, t)
+		}
+	}
+	apimeta.SetStatusCondition(&status.Conditions, availableCondition(conditions))
+}
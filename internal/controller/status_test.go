@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDaemonSetCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     appsv1.DaemonSetStatus
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "all pods ready",
+			status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Ready",
+		},
+		{
+			name:       "crash looping pod",
+			status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 2, NumberUnavailable: 1},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "PodsUnavailable",
+		},
+		{
+			name:       "rollout in progress",
+			status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberReady: 3},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: "Progressing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := &appsv1.DaemonSet{Status: tt.status}
+			ds.Name = "whatap-node-agent"
+			got := daemonSetCondition(conditionNodeAgentReady, ds)
+			if got.Status != tt.wantStatus || got.Reason != tt.wantReason {
+				t.Errorf("got %s/%s, want %s/%s (%s)", got.Status, got.Reason, tt.wantStatus, tt.wantReason, got.Message)
+			}
+		})
+	}
+}
+
+func TestDeploymentCondition(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		Spec:   appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+		Status: appsv1.DeploymentStatus{UpdatedReplicas: 1, AvailableReplicas: 0, UnavailableReplicas: 1},
+	}
+	if got := deploymentCondition(conditionOpenAgentReady, deploy); got.Status != metav1.ConditionFalse {
+		t.Errorf("Expected unavailable deployment to be not ready, got %+v", got)
+	}
+
+	deploy.Status.AvailableReplicas = 1
+	deploy.Status.UnavailableReplicas = 0
+	if got := deploymentCondition(conditionOpenAgentReady, deploy); got.Status != metav1.ConditionTrue {
+		t.Errorf("Expected available deployment to be ready, got %+v", got)
+	}
+
+	deploy.Generation = 2
+	deploy.Status.ObservedGeneration = 1
+	if got := deploymentCondition(conditionOpenAgentReady, deploy); got.Reason != "Progressing" {
+		t.Errorf("Expected unobserved generation to be Progressing, got %+v", got)
+	}
+}
+
+func TestApplyComponentConditions(t *testing.T) {
+	status := &monitoringv2alpha1.WhatapAgentStatus{
+		Conditions: []metav1.Condition{
+			{Type: conditionMasterAgentReady, Status: metav1.ConditionTrue, Reason: "Ready"},
+		},
+	}
+	applyComponentConditions(status, []metav1.Condition{
+		readyCondition(conditionWebhookReady, "ok"),
+		notReadyCondition(conditionNodeAgentReady, "PodsUnavailable", "1 unavailable"),
+		notReadyCondition(conditionSchedulerMonitoringReady, "ControlPlaneNotFound", "managed"),
+	})
+
+	if apimeta.FindStatusCondition(status.Conditions, conditionMasterAgentReady) != nil {
+		t.Errorf("Expected condition of disabled component to be removed")
+	}
+	available := apimeta.FindStatusCondition(status.Conditions, conditionAvailable)
+	if available == nil || available.Status != metav1.ConditionFalse {
+		t.Fatalf("Expected Available=False, got %+v", available)
+	}
+
+	applyComponentConditions(status, []metav1.Condition{
+		readyCondition(conditionWebhookReady, "ok"),
+		notReadyCondition(conditionSchedulerMonitoringReady, "ControlPlaneNotFound", "managed"),
+	})
+	available = apimeta.FindStatusCondition(status.Conditions, conditionAvailable)
+	if available == nil || available.Status != metav1.ConditionTrue {
+		t.Errorf("Expected ControlPlaneNotFound not to block availability, got %+v", available)
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := failureBackoff(tt.failures); got != tt.want {
+			t.Errorf("failureBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	r := &WhatapAgentReconciler{}
+	key := types.NamespacedName{Name: "whatap"}
+	r.recordReconcileFailure(key)
+	if got := r.recordReconcileFailure(key); got != 20*time.Second {
+		t.Errorf("Expected second consecutive failure to back off 20s, got %v", got)
+	}
+	r.resetReconcileFailures(key)
+	if got := r.recordReconcileFailure(key); got != 10*time.Second {
+		t.Errorf("Expected backoff to reset after success, got %v", got)
+	}
+}
@@ -2,12 +2,17 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	"github.com/whatap/whatap-operator/internal/config"
+	"github.com/whatap/whatap-operator/internal/registry"
+	"github.com/whatap/whatap-operator/internal/webhookcert"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -16,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -28,13 +34,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	webhookServiceName       = "whatap-admission-controller"
-	webhookSecretName        = "whatap-webhook-certificate"
 	webhookConfigurationName = "whatap-webhook"
 	whatapFinalizer          = "whatapagent.finalizers.monitoring.whatap.com"
+
+	// Requeue delay after a reconcile in which some component failed; doubles on
+	// every consecutive failure up to failureBackoffMax.
+	failureBackoffBase = 10 * time.Second
+	failureBackoffMax  = 5 * time.Minute
 )
 
 // WhatapAgentReconciler reconciles a WhatapAgent object
@@ -43,41 +54,116 @@ type WhatapAgentReconciler struct {
 	Scheme           *runtime.Scheme
 	Recorder         record.EventRecorder
 	DefaultNamespace string
-	// from main.go
-	WebhookCABundle []byte
-	CaKey           []byte
-	ServerCert      []byte
-	ServerKey       []byte
+	// APIReader reads directly from the API server; used for one-off lookups
+	// (e.g. control-plane pods) that should not start a cluster-wide informer
+	APIReader client.Reader
+	// Certs provides the webhook serving certificate and CA bundle (from main.go)
+	Certs *webhookcert.Manager
+	// Registry resolves APM init image digests for spec.pinImageDigests; an anonymous
+	// registry client is used when nil
+	Registry registry.Resolver
+
+	// restMapper is re-checked on every reconcile for the Prometheus Operator CRDs
+	// (see prometheusOperatorCompat)
+	restMapper apimeta.RESTMapper
+	// watchPrometheusOperatorKind starts watching a Prometheus Operator kind
+	watchPrometheusOperatorKind func(monitoringv2alpha1.PrometheusOperatorKind) error
+
+	prometheusOperatorMu      sync.Mutex
+	prometheusOperatorWatched map[monitoringv2alpha1.PrometheusOperatorKind]bool
+
+	failuresMu sync.Mutex
+	failures   map[types.NamespacedName]int
+}
+
+// nodeAgentConflict returns the older WhatapAgent whose node agent can be
+// scheduled on the same nodes as whatapAgent's, or nil if there is none.
+func (r *WhatapAgentReconciler) nodeAgentConflict(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) (*monitoringv2alpha1.WhatapAgent, error) {
+	agents := &monitoringv2alpha1.WhatapAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		return nil, err
+	}
+	for i := range agents.Items {
+		other := &agents.Items[i]
+		if other.Name == whatapAgent.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if other.IsOlderThan(whatapAgent) && monitoringv2alpha1.NodeAgentsOverlap(whatapAgent, other) {
+			return other, nil
+		}
+	}
+	return nil, nil
+}
+
+// failureBackoff returns the requeue delay after the n-th consecutive failure.
+func failureBackoff(n int) time.Duration {
+	delay := failureBackoffBase
+	for i := 1; i < n && delay < failureBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > failureBackoffMax {
+		delay = failureBackoffMax
+	}
+	return delay
+}
+
+// recordReconcileFailure bumps the consecutive failure count for key and
+// returns the delay before the next attempt.
+func (r *WhatapAgentReconciler) recordReconcileFailure(key types.NamespacedName) time.Duration {
+	r.failuresMu.Lock()
+	defer r.failuresMu.Unlock()
+	if r.failures == nil {
+		r.failures = map[types.NamespacedName]int{}
+	}
+	r.failures[key]++
+	return failureBackoff(r.failures[key])
+}
+
+func (r *WhatapAgentReconciler) resetReconcileFailures(key types.NamespacedName) {
+	r.failuresMu.Lock()
+	defer r.failuresMu.Unlock()
+	delete(r.failures, key)
 }
 
-func (r *WhatapAgentReconciler) ensureWebhookTLSSecret(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+func (r *WhatapAgentReconciler) ensureWebhookTLSSecret(ctx context.Context) error {
+	if err := r.Certs.Ready(); err != nil {
+		return err
+	}
+	if !r.Certs.SelfSigned() {
+		// Issued and rotated by cert-manager
+		return nil
+	}
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      webhookSecretName,
+			Name:      r.Certs.SecretName,
 			Namespace: r.DefaultNamespace,
 		},
 	}
 
 	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
-		// Set WhatapAgent instance as the owner and controller
-		if err := controllerutil.SetControllerReference(whatapAgent, secret, r.Scheme); err != nil {
-			return err
+		// The Secret holds the CA the running operator serves with and outlives every
+		// WhatapAgent: it is never owned by one, so garbage collection cannot delete it
+		// while the webhook still uses it. Owner references of earlier versions are dropped.
+		owners := secret.OwnerReferences[:0]
+		for _, ref := range secret.OwnerReferences {
+			if ref.APIVersion != monitoringv2alpha1.GroupVersion.String() || ref.Kind != "WhatapAgent" {
+				owners = append(owners, ref)
+			}
 		}
-		secret.Data = map[string][]byte{
-			"cert.pem": r.WebhookCABundle, // CA 번들
-			"key.pem":  r.CaKey,
-			"tls.crt":  r.ServerCert,
-			"tls.key":  r.ServerKey,
+		secret.OwnerReferences = owners
+		// The certificate manager rotates the contents; only recreate a deleted Secret here
+		if secret.Data == nil {
+			secret.Data = r.Certs.Bundle().SecretData()
 		}
 		return nil
 	})
 	return err
 }
 
-func (r *WhatapAgentReconciler) cleanupMasterAgent(ctx context.Context) error {
+func (r *WhatapAgentReconciler) cleanupMasterAgent(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
 	logger := log.FromContext(ctx)
 	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-master-agent", Namespace: r.DefaultNamespace},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-master-agent"), Namespace: r.DefaultNamespace},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, deployment); err == nil {
 		if err := r.Delete(ctx, deployment); err != nil {
@@ -90,27 +176,29 @@ func (r *WhatapAgentReconciler) cleanupMasterAgent(ctx context.Context) error {
 	return nil
 }
 
-func (r *WhatapAgentReconciler) cleanupNodeAgent(ctx context.Context) error {
+func (r *WhatapAgentReconciler) cleanupNodeAgent(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
 	logger := log.FromContext(ctx)
-	ds := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-node-agent", Namespace: r.DefaultNamespace},
-	}
-	if err := r.Get(ctx, types.NamespacedName{Name: ds.Name, Namespace: ds.Namespace}, ds); err == nil {
-		if err := r.Delete(ctx, ds); err != nil {
-			if client.IgnoreNotFound(err) != nil {
-				logger.Error(err, "Failed to delete Node Agent DaemonSet")
-				return err
+	for _, name := range []string{"whatap-node-agent", "whatap-node-agent-gpu"} {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName(name), Namespace: r.DefaultNamespace},
+		}
+		if err := r.Get(ctx, types.NamespacedName{Name: ds.Name, Namespace: ds.Namespace}, ds); err == nil {
+			if err := r.Delete(ctx, ds); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					logger.Error(err, "Failed to delete Node Agent DaemonSet", "name", ds.Name)
+					return err
+				}
 			}
 		}
 	}
 	return nil
 }
 
-func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
+func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
 	logger := log.FromContext(ctx)
 	// Delete OpenAgent Deployment
 	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-open-agent", Namespace: r.DefaultNamespace},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-open-agent"), Namespace: r.DefaultNamespace},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, deployment); err == nil {
 		if err := r.Delete(ctx, deployment); err != nil {
@@ -123,7 +211,7 @@ func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
 
 	// Delete OpenAgent ConfigMap
 	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-open-agent-config", Namespace: r.DefaultNamespace},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-open-agent-config"), Namespace: r.DefaultNamespace},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}, cm); err == nil {
 		if err := r.Delete(ctx, cm); err != nil {
@@ -134,9 +222,15 @@ func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
 		}
 	}
 
+	// Delete the Deployments and ConfigMaps of the other shards
+	if err := r.cleanupOpenAgentShards(ctx, whatapAgent, 1); err != nil {
+		logger.Error(err, "Failed to delete OpenAgent shards")
+		return err
+	}
+
 	// Delete OpenAgent ServiceAccount
 	sa := &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-open-agent-sa", Namespace: r.DefaultNamespace},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-open-agent-sa"), Namespace: r.DefaultNamespace},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: sa.Name, Namespace: sa.Namespace}, sa); err == nil {
 		if err := r.Delete(ctx, sa); err != nil {
@@ -149,7 +243,7 @@ func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
 
 	// Delete OpenAgent ClusterRole
 	clusterRole := &rbacv1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-open-agent-role"},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-open-agent-role")},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: clusterRole.Name}, clusterRole); err == nil {
 		if err := r.Delete(ctx, clusterRole); err != nil {
@@ -162,7 +256,7 @@ func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
 
 	// Delete OpenAgent ClusterRoleBinding
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{Name: "whatap-open-agent-role-binding"},
+		ObjectMeta: metav1.ObjectMeta{Name: whatapAgent.ResourceName("whatap-open-agent-role-binding")},
 	}
 	if err := r.Get(ctx, types.NamespacedName{Name: clusterRoleBinding.Name}, clusterRoleBinding); err == nil {
 		if err := r.Delete(ctx, clusterRoleBinding); err != nil {
@@ -175,25 +269,93 @@ func (r *WhatapAgentReconciler) cleanupOpenAgent(ctx context.Context) error {
 	return nil
 }
 
-func (r *WhatapAgentReconciler) cleanupAgents(ctx context.Context) error {
+// cleanupControlPlaneMonitor removes every resource created by
+// reconcileControlPlaneMonitor for the monitor named name.
+func (r *WhatapAgentReconciler) cleanupControlPlaneMonitor(ctx context.Context, name, component string) error {
+	logger := log.FromContext(ctx)
+	objs := []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.DefaultNamespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name + "-config", Namespace: r.DefaultNamespace}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name + "-sa", Namespace: r.DefaultNamespace}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name + "-role"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name + "-role-binding"}},
+	}
+	for _, obj := range objs {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			continue
+		}
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "Failed to delete "+component+" Monitor resource", "name", obj.GetName())
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *WhatapAgentReconciler) cleanupApiserverMonitor(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+	return r.cleanupControlPlaneMonitor(ctx, whatapAgent.ResourceName(apiserverMonitorName), "Apiserver")
+}
+
+func (r *WhatapAgentReconciler) cleanupEtcdMonitor(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+	return r.cleanupControlPlaneMonitor(ctx, whatapAgent.ResourceName(etcdMonitorName), "Etcd")
+}
+
+func (r *WhatapAgentReconciler) cleanupSchedulerMonitor(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+	return r.cleanupControlPlaneMonitor(ctx, whatapAgent.ResourceName(schedulerMonitorName), "Scheduler")
+}
+
+func (r *WhatapAgentReconciler) cleanupControllerManagerMonitor(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+	return r.cleanupControlPlaneMonitor(ctx, whatapAgent.ResourceName(controllerManagerMonitorName), "ControllerManager")
+}
+
+func (r *WhatapAgentReconciler) cleanupAgents(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
 	logger := log.FromContext(ctx)
 	logger.Info("Cleaning up Whatap agents and resources")
 
 	// Delete Master Agent
-	if err := r.cleanupMasterAgent(ctx); err != nil {
+	if err := r.cleanupMasterAgent(ctx, whatapAgent); err != nil {
 		// Logged in helper
 	}
 
 	// Delete Node Agent
-	if err := r.cleanupNodeAgent(ctx); err != nil {
+	if err := r.cleanupNodeAgent(ctx, whatapAgent); err != nil {
 		// Logged in helper
 	}
 
 	// Delete OpenAgent resources
-	if err := r.cleanupOpenAgent(ctx); err != nil {
+	if err := r.cleanupOpenAgent(ctx, whatapAgent); err != nil {
+		// Logged in helper
+	}
+
+	// Delete control plane monitors
+	if err := r.cleanupApiserverMonitor(ctx, whatapAgent); err != nil {
+		// Logged in helper
+	}
+	if err := r.cleanupEtcdMonitor(ctx, whatapAgent); err != nil {
+		// Logged in helper
+	}
+	if err := r.cleanupSchedulerMonitor(ctx, whatapAgent); err != nil {
+		// Logged in helper
+	}
+	if err := r.cleanupControllerManagerMonitor(ctx, whatapAgent); err != nil {
 		// Logged in helper
 	}
 
+	// The webhook Service and configuration are shared by every instance; keep
+	// them while another WhatapAgent still exists. The certificate Secret is
+	// kept in any case (see ensureWebhookTLSSecret).
+	agents := &monitoringv2alpha1.WhatapAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		logger.Error(err, "Failed to list WhatapAgents, keeping shared webhook resources")
+		return nil
+	}
+	for _, other := range agents.Items {
+		if other.Name != whatapAgent.Name && other.DeletionTimestamp.IsZero() {
+			logger.Info("Other WhatapAgent instances remain, keeping shared webhook resources", "instance", other.Name)
+			return nil
+		}
+	}
+
 	// Delete MutatingWebhookConfiguration
 	if err := r.Delete(ctx, &admissionregistrationv1.MutatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{Name: webhookConfigurationName},
@@ -214,16 +376,6 @@ func (r *WhatapAgentReconciler) cleanupAgents(ctx context.Context) error {
 		}
 	}
 
-	// Delete Webhook Secret
-	if err := r.Delete(ctx, &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{Name: webhookSecretName, Namespace: r.DefaultNamespace},
-	}); err != nil {
-		// Ignore NotFound errors
-		if client.IgnoreNotFound(err) != nil {
-			logger.Error(err, "Failed to delete Webhook Secret")
-		}
-	}
-
 	logger.Info("Cleanup completed")
 	return nil
 }
@@ -241,8 +393,9 @@ func (r *WhatapAgentReconciler) ensureWebhookService(ctx context.Context, whatap
 	}
 
 	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
-		// Set WhatapAgent instance as the owner and controller
-		if err := controllerutil.SetControllerReference(whatapAgent, svc, r.Scheme); err != nil {
+		// Shared by every WhatapAgent instance: each one is recorded as a
+		// (non-controller) owner so garbage collection waits for the last one
+		if err := controllerutil.SetOwnerReference(whatapAgent, svc, r.Scheme); err != nil {
 			return err
 		}
 		// Apply labels
@@ -268,15 +421,26 @@ func (r *WhatapAgentReconciler) ensureWebhookService(ctx context.Context, whatap
 	return err
 }
 func (r *WhatapAgentReconciler) ensureMutatingWebhookConfiguration(ctx context.Context, whatapAgent *monitoringv2alpha1.WhatapAgent) error {
+	agents := &monitoringv2alpha1.WhatapAgentList{}
+	if err := r.List(ctx, agents); err != nil {
+		return err
+	}
+	injectedNamespaces, err := r.injectedPodNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	podWebhook := computePodWebhookSettings(agents.Items, injectedNamespaces, r.DefaultNamespace)
+
 	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: webhookConfigurationName,
 		},
 	}
 
-	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, mwc, func() error {
-		// Set WhatapAgent instance as the owner and controller
-		if err := controllerutil.SetControllerReference(whatapAgent, mwc, r.Scheme); err != nil {
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, mwc, func() error {
+		// Shared by every WhatapAgent instance: each one is recorded as a
+		// (non-controller) owner so garbage collection waits for the last one
+		if err := controllerutil.SetOwnerReference(whatapAgent, mwc, r.Scheme); err != nil {
 			return err
 		}
 
@@ -299,7 +463,7 @@ func (r *WhatapAgentReconciler) ensureMutatingWebhookConfiguration(ctx context.C
 				Namespace: r.DefaultNamespace,
 				Path:      strPtr("/whatap-injection--v1-pod"),
 			},
-			CABundle: r.WebhookCABundle,
+			CABundle: r.Certs.Bundle().CACert,
 		}
 		mpod.Rules = []admissionregistrationv1.RuleWithOperations{{
 			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
@@ -309,7 +473,10 @@ func (r *WhatapAgentReconciler) ensureMutatingWebhookConfiguration(ctx context.C
 				Resources:   []string{"pods"},
 			},
 		}}
-		mpod.FailurePolicy = failurePtr(admissionregistrationv1.Ignore)
+		mpod.FailurePolicy = failurePtr(podWebhook.FailurePolicy)
+		mpod.TimeoutSeconds = podWebhook.TimeoutSeconds
+		mpod.NamespaceSelector = podWebhook.NamespaceSelector
+		mpod.ObjectSelector = podWebhook.ObjectSelector
 		mpod.AdmissionReviewVersions = []string{"v1"}
 		mpod.SideEffects = &sideEffectNone
 
@@ -321,7 +488,7 @@ func (r *WhatapAgentReconciler) ensureMutatingWebhookConfiguration(ctx context.C
 				Namespace: r.DefaultNamespace,
 				Path:      strPtr("/whatap-validation--v2alpha1-whatapagent"),
 			},
-			CABundle: r.WebhookCABundle,
+			CABundle: r.Certs.Bundle().CACert,
 		}
 		whatap.Rules = []admissionregistrationv1.RuleWithOperations{{
 			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
@@ -335,11 +502,38 @@ func (r *WhatapAgentReconciler) ensureMutatingWebhookConfiguration(ctx context.C
 		whatap.AdmissionReviewVersions = []string{"v1"}
 		whatap.SideEffects = &sideEffectNone
 
+		// 3. Monitor CR validation (relabel configs, intervals, Secret references)
+		webhooks := []admissionregistrationv1.MutatingWebhook{mpod, whatap}
+		for _, resource := range []string{"whatappodmonitors", "whatapservicemonitors", "whatapstaticendpoints"} {
+			singular := strings.TrimSuffix(resource, "s")
+			monitor, _ := findWebhook(singular + ".kb.io")
+			monitor.ClientConfig = admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Name:      webhookServiceName,
+					Namespace: r.DefaultNamespace,
+					Path:      strPtr("/whatap-validation--v2alpha1-" + singular),
+				},
+				CABundle: r.Certs.Bundle().CACert,
+			}
+			monitor.Rules = []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"monitoring.whatap.com"},
+					APIVersions: []string{"v2alpha1"},
+					Resources:   []string{resource},
+				},
+			}}
+			monitor.FailurePolicy = failurePtr(admissionregistrationv1.Ignore)
+			monitor.AdmissionReviewVersions = []string{"v1"}
+			monitor.SideEffects = &sideEffectNone
+			webhooks = append(webhooks, monitor)
+		}
+
 		// Assign merged webhooks in stable order
 		// By using the structs retrieved from 'mwc.Webhooks', we preserve all other fields
-		// (e.g., NamespaceSelector, ObjectSelector, MatchPolicy, TimeoutSeconds)
-		// that we did not explicitly overwrite.
-		mwc.Webhooks = []admissionregistrationv1.MutatingWebhook{mpod, whatap}
+		// (e.g., MatchPolicy, or the selectors of whatapagent.kb.io) that we did not
+		// explicitly overwrite.
+		mwc.Webhooks = webhooks
 		return nil
 	})
 	return err
@@ -379,6 +573,8 @@ func (r *WhatapAgentReconciler) populateCredentialsFromEnv(ctx context.Context,
 //+kubebuilder:rbac:groups=monitoring.whatap.com,resources=whatapagents/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=monitoring.whatap.com,resources=whatapagents/finalizers,verbs=update
 //+kubebuilder:rbac:groups=monitoring.whatap.com,resources=whatappodmonitors;whatapservicemonitors;whatapstaticendpoints,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.whatap.com,resources=whatappodmonitors/status;whatapservicemonitors/status;whatapstaticendpoints/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors;podmonitors;probes,verbs=get;list;watch
 
 // Reconcile
 func (r *WhatapAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -402,7 +598,7 @@ func (r *WhatapAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// The object is being deleted
 		if controllerutil.ContainsFinalizer(whatapAgent, whatapFinalizer) {
 			// our finalizer is present, so let's handle any external dependency
-			if err := r.cleanupAgents(ctx); err != nil {
+			if err := r.cleanupAgents(ctx, whatapAgent); err != nil {
 				logger.Error(err, "Failed to clean up agents")
 				// Continue with finalizer removal even if cleanup fails
 			}
@@ -417,59 +613,42 @@ func (r *WhatapAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	logger.Info("Reconciling WhatapAgent", "Name", whatapAgent.Name)
 
-	// Update Status to Progressing
-	apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-		Type:    "Available",
-		Status:  metav1.ConditionFalse,
-		Reason:  "Reconciling",
-		Message: "Reconciling WhatapAgent resources",
-	})
-	// Ignore error on status update to proceed with reconciliation
-	_ = r.Status().Update(ctx, whatapAgent)
-
-	// --- 1. create webhook service
-	if err := r.ensureWebhookService(ctx, whatapAgent); err != nil {
-		logger.Error(err, "failed to ensure ensureWebhookService")
-		r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to ensure Webhook Service: "+err.Error())
+	// Report Reconciling only until the first pass has computed Available; afterwards
+	// applyComponentConditions owns it, so requeues and owned object updates do not flip it
+	if apimeta.FindStatusCondition(whatapAgent.Status.Conditions, conditionAvailable) == nil {
 		apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-			Type:    "Available",
+			Type:    conditionAvailable,
 			Status:  metav1.ConditionFalse,
-			Reason:  "InstallFailed",
-			Message: err.Error(),
+			Reason:  "Reconciling",
+			Message: "Reconciling WhatapAgent resources",
 		})
-		r.Status().Update(ctx, whatapAgent)
-		return ctrl.Result{}, err
+		// Ignore error on status update to proceed with reconciliation
+		_ = r.Status().Update(ctx, whatapAgent)
 	}
 
-	// --- 2. create webhook secret
-	if err := r.ensureWebhookTLSSecret(ctx, whatapAgent); err != nil {
-		r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to ensure Webhook Secret: "+err.Error())
-		apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-			Type:    "Available",
-			Status:  metav1.ConditionFalse,
-			Reason:  "InstallFailed",
-			Message: err.Error(),
-		})
-		r.Status().Update(ctx, whatapAgent)
-		return ctrl.Result{}, err
+	k8sAgentSpec := whatapAgent.Spec.Features.K8sAgent
+	openAgentSpec := whatapAgent.Spec.Features.OpenAgent
+
+	// Every component is reconciled independently: a failure is recorded as that
+	// component's condition and the remaining components are still reconciled.
+	var failedConditions []metav1.Condition
+	var errs []error
+	recordFailure := func(conditionType, what string, err error) {
+		logger.Error(err, "Failed to "+what)
+		r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to "+what+": "+err.Error())
+		failedConditions = append(failedConditions, notReadyCondition(conditionType, "InstallFailed", err.Error()))
+		errs = append(errs, fmt.Errorf("%s: %w", what, err))
 	}
 
-	// 5) WebhookConfiguration 생성/업데이트
-	if err := r.ensureMutatingWebhookConfiguration(ctx, whatapAgent); err != nil {
-		r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to ensure MutatingWebhookConfiguration: "+err.Error())
-		apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-			Type:    "Available",
-			Status:  metav1.ConditionFalse,
-			Reason:  "InstallFailed",
-			Message: err.Error(),
-		})
-		r.Status().Update(ctx, whatapAgent)
-		return ctrl.Result{}, err
+	// Webhook: service -> secret -> configuration, each step needs the previous one
+	if err := r.ensureWebhookService(ctx, whatapAgent); err != nil {
+		recordFailure(conditionWebhookReady, "ensure Webhook Service", err)
+	} else if err := r.ensureWebhookTLSSecret(ctx); err != nil {
+		recordFailure(conditionWebhookReady, "ensure Webhook Secret", err)
+	} else if err := r.ensureMutatingWebhookConfiguration(ctx, whatapAgent); err != nil {
+		recordFailure(conditionWebhookReady, "ensure MutatingWebhookConfiguration", err)
 	}
 
-	// Kubernetes Monitoring
-	k8sAgentSpec := whatapAgent.Spec.Features.K8sAgent
-	openAgentSpec := whatapAgent.Spec.Features.OpenAgent
 	// GPU ConfigMap is now created by Helm, so we don't need to create it here
 	// if k8sAgentSpec.GpuMonitoring.Enabled {
 	// 	logger.Info("createOrUpdate Whatap GPU Monitoring ConfigMap/dcgm-exporter-csv")
@@ -478,127 +657,96 @@ func (r *WhatapAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// 	}
 	// }
 
-	if k8sAgentSpec.MasterAgent.Enabled {
-		logger.V(1).Info("createOrUpdate Whatap Master Agent")
-		if err := createOrUpdateMasterAgent(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to createOrUpdate Master Agent")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to createOrUpdate Master Agent: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
-		}
-	} else {
-		// Cleanup Master Agent if disabled
-		logger.V(1).Info("Cleaning up Whatap Master Agent (disabled)")
-		if err := r.cleanupMasterAgent(ctx); err != nil {
-			logger.Error(err, "Failed to cleanup Master Agent")
-		}
-	}
-	if k8sAgentSpec.NodeAgent.Enabled {
-		logger.V(1).Info("createOrUpdate Whatap Node Agent")
-		if err := createOrUpdateNodeAgent(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to createOrUpdate Node Agent")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to createOrUpdate Node Agent: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
-		}
-	} else {
-		// Cleanup Node Agent if disabled
-		logger.V(1).Info("Cleaning up Whatap Node Agent (disabled)")
-		if err := r.cleanupNodeAgent(ctx); err != nil {
-			logger.Error(err, "Failed to cleanup Node Agent")
-		}
-	}
-	if k8sAgentSpec.ApiserverMonitoring.Enabled {
-		logger.V(1).Info("Installing Apiserver Monitoring Agent")
-		if err := installApiserverMonitor(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to install Apiserver Monitor")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to install Apiserver Monitor: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
-		}
-	}
-	if k8sAgentSpec.EtcdMonitoring.Enabled {
-		logger.V(1).Info("Installing Etcd Monitoring Agent")
-		if err := installEtcdMonitor(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to install Etcd Monitor")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to install Etcd Monitor: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
+	// Components skipped on purpose (conflict, control plane not found) are
+	// reported as conditions rather than install failures.
+	var skippedConditions []metav1.Condition
+	// Requeue delay of components waiting for their previous state to go away
+	var pendingWait time.Duration
+
+	// Only one instance may run a node agent on a given node; the older one wins.
+	nodeAgentEnabled := k8sAgentSpec.NodeAgent.Enabled
+	if nodeAgentEnabled {
+		if owner, err := r.nodeAgentConflict(ctx, whatapAgent); err != nil {
+			logger.Error(err, "Failed to check node agent conflicts")
+		} else if owner != nil {
+			msg := fmt.Sprintf("node agent overlaps with WhatapAgent %q on the same nodes; set disjoint nodeAgent.nodeSelector or required node affinity values", owner.Name)
+			logger.Info("Skipping Node Agent", "reason", msg)
+			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "Conflict", msg)
+			skippedConditions = append(skippedConditions, notReadyCondition(conditionNodeAgentReady, "Conflict", msg))
+			nodeAgentEnabled = false
+		}
+	}
+
+	for _, c := range []struct {
+		conditionType string
+		name          string
+		enabled       bool
+		install       func(context.Context, *WhatapAgentReconciler, logr.Logger, *monitoringv2alpha1.WhatapAgent) error
+		cleanup       func(context.Context, *monitoringv2alpha1.WhatapAgent) error
+	}{
+		{conditionMasterAgentReady, "Master Agent", k8sAgentSpec.MasterAgent.Enabled, createOrUpdateMasterAgent, r.cleanupMasterAgent},
+		{conditionNodeAgentReady, "Node Agent", nodeAgentEnabled, createOrUpdateNodeAgent, r.cleanupNodeAgent},
+		{conditionApiserverMonitoringReady, "Apiserver Monitor", k8sAgentSpec.ApiserverMonitoring.Enabled, installApiserverMonitor, r.cleanupApiserverMonitor},
+		{conditionEtcdMonitoringReady, "Etcd Monitor", k8sAgentSpec.EtcdMonitoring.Enabled, installEtcdMonitor, r.cleanupEtcdMonitor},
+		{conditionSchedulerMonitoringReady, "Scheduler Monitor", k8sAgentSpec.SchedulerMonitoring.Enabled, installSchedulerMonitor, r.cleanupSchedulerMonitor},
+		{conditionControllerManagerMonitoringReady, "ControllerManager Monitor", k8sAgentSpec.ControllerManagerMonitoring.Enabled, installControllerManagerMonitor, r.cleanupControllerManagerMonitor},
+		{conditionOpenAgentReady, "Open Agent", openAgentSpec.Enabled, installOpenAgent, r.cleanupOpenAgent},
+	} {
+		if !c.enabled {
+			logger.V(1).Info("Cleaning up " + c.name + " (disabled)")
+			if err := c.cleanup(ctx, whatapAgent); err != nil {
+				logger.Error(err, "Failed to cleanup "+c.name)
+			}
+			continue
+		}
+		logger.V(1).Info("createOrUpdate " + c.name)
+		err := c.install(ctx, r, logger, whatapAgent)
+		// Control-plane static pods are invisible on managed clusters
+		var notFound *controlPlaneNotFoundError
+		var unreachable *controlPlaneUnreachableError
+		var draining *openAgentShardsDrainingError
+		switch {
+		case errors.As(err, &unreachable):
+			logger.Info(c.name+" cannot scrape its targets", "reason", err.Error())
+			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "ControlPlaneUnreachable", c.name+": "+err.Error())
+			skippedConditions = append(skippedConditions, notReadyCondition(c.conditionType, "MetricsUnreachable", err.Error()))
+		case errors.As(err, &draining):
+			logger.Info("Waiting for "+c.name, "reason", err.Error())
+			skippedConditions = append(skippedConditions, notReadyCondition(c.conditionType, "ShardsDraining", err.Error()))
+			pendingWait = openAgentDrainPoll
+		case errors.As(err, &notFound):
+			logger.Info("Skipping "+c.name, "reason", err.Error())
+			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "ControlPlaneNotFound", c.name+": "+err.Error())
+			if cleanupErr := c.cleanup(ctx, whatapAgent); cleanupErr != nil {
+				logger.Error(cleanupErr, "Failed to cleanup "+c.name)
+			}
+			skippedConditions = append(skippedConditions, notReadyCondition(c.conditionType, "ControlPlaneNotFound", err.Error()))
+		case err != nil:
+			recordFailure(c.conditionType, "createOrUpdate "+c.name, err)
 		}
 	}
-	if k8sAgentSpec.SchedulerMonitoring.Enabled {
-		logger.V(1).Info("Installing Scheduler Monitoring Agent")
-		if err := installSchedulerMonitor(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to install Scheduler Monitor")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to install Scheduler Monitor: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
-		}
+
+	// Pin APM init images before any restart below creates pods
+	if err := r.reconcileImageDigests(ctx, logger, whatapAgent); err != nil {
+		recordFailure(conditionInstrumentationReady, "record image digests", err)
 	}
-	// OpenAgent
-	if openAgentSpec.Enabled {
-		logger.V(1).Info("Installing Open Agent")
-		if err := installOpenAgent(ctx, r, logger, whatapAgent); err != nil {
-			logger.Error(err, "Failed to install Open Agent")
-			r.Recorder.Event(whatapAgent, corev1.EventTypeWarning, "InstallFailed", "Failed to install Open Agent: "+err.Error())
-			apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-				Type:    "Available",
-				Status:  metav1.ConditionFalse,
-				Reason:  "InstallFailed",
-				Message: err.Error(),
-			})
-			r.Status().Update(ctx, whatapAgent)
-			return ctrl.Result{}, err
-		}
-	} else {
-		// Cleanup Open Agent if disabled
-		logger.V(1).Info("Cleaning up Whatap Open Agent (disabled)")
-		if err := r.cleanupOpenAgent(ctx); err != nil {
-			logger.Error(err, "Failed to cleanup Open Agent")
-		}
+
+	// Roll out restarts of workloads whose instrumentation target changed (opt-in)
+	rollout, rolloutWait, err := r.reconcileInstrumentationRestarts(ctx, logger, whatapAgent, time.Now())
+	if err != nil {
+		recordFailure(conditionInstrumentationReady, "restart instrumented workloads", err)
 	}
 
-	// Success
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	// Report per-component readiness from the live workloads; failures from this
+	// pass override the observed state. Available is only True when every
+	// enabled component is ready.
+	conditions := r.componentConditions(ctx, whatapAgent, append(skippedConditions, failedConditions...))
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		if err := r.Get(ctx, req.NamespacedName, whatapAgent); err != nil {
 			return err
 		}
-		apimeta.SetStatusCondition(&whatapAgent.Status.Conditions, metav1.Condition{
-			Type:    "Available",
-			Status:  metav1.ConditionTrue,
-			Reason:  "Installed",
-			Message: "WhatapAgent installed successfully",
-		})
+		applyComponentConditions(&whatapAgent.Status, conditions)
+		whatapAgent.Status.Rollout = rollout
 		whatapAgent.Status.ObservedGeneration = whatapAgent.Generation
 		return r.Status().Update(ctx, whatapAgent)
 	})
@@ -606,18 +754,23 @@ func (r *WhatapAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		logger.Error(err, "Failed to update WhatapAgent status")
 		return ctrl.Result{}, err
 	}
-	// Do not emit event on every reconcile loop if it's already available?
-	// But Reconcile is periodic. We should only emit if status changed?
-	// For simplicity, I'll only emit if I just set it to True.
-	// But `SetStatusCondition` handles deduplication.
-	// I'll emit "Installed" event. It might spam if we requeue every 5 mins.
-	// Better to check if condition changed.
-	// apimeta.SetStatusCondition returns bool (if changed).
-	// But I just called it.
-	// Let's just emit it. `kubectl describe` shows last seen.
-	// Actually, if we want to avoid spamming events, we can skip it.
-	// But the user asked for improvements.
-	// I will just add the status update and return.
+
+	if len(errs) > 0 {
+		delay := r.recordReconcileFailure(req.NamespacedName)
+		logger.Error(kerrors.NewAggregate(errs), "Some components failed to reconcile", "requeueAfter", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	r.resetReconcileFailures(req.NamespacedName)
+
+	// Come back once the shards of the previous shard count have stopped
+	if pendingWait > 0 && (rolloutWait <= 0 || pendingWait < rolloutWait) {
+		return ctrl.Result{RequeueAfter: pendingWait}, nil
+	}
+
+	// Come back for the next restart batch while restarts are pending
+	if rolloutWait > 0 && rolloutWait < time.Minute*5 {
+		return ctrl.Result{RequeueAfter: rolloutWait}, nil
+	}
 
 	// Schedule periodic reconciliation to ensure resources are maintained
 	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
@@ -646,39 +799,58 @@ func removeString(slice []string, s string) []string {
 
 func (r *WhatapAgentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	lp := loggingPredicate(mgr.GetLogger().WithName("event-watcher"))
-	return ctrl.NewControllerManagedBy(mgr).
+	// Monitor status is written by MonitorStatusReconciler and does not affect the scrape config
+	monitorSpecChanged := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{})
+	b := ctrl.NewControllerManagedBy(mgr).
 		// 1) Watch the cluster-scoped WhatapAgent so CR changes still reconcile
 		// Use GenerationChangedPredicate to avoid reconciliation loops on Status updates
 		For(&monitoringv2alpha1.WhatapAgent{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}, lp)).
 		// Watch for changes to resources created by this controller
 		Owns(&appsv1.Deployment{}, builder.WithPredicates(lp)).
 		Owns(&appsv1.DaemonSet{}, builder.WithPredicates(lp)).
-		Owns(&corev1.Service{}, builder.WithPredicates(lp)).
+		Owns(&corev1.Service{}, builder.WithPredicates(lp), builder.MatchEveryOwner).
 		Owns(&corev1.ConfigMap{}, builder.WithPredicates(lp)).
-		Owns(&corev1.Secret{}, builder.WithPredicates(lp)).
+		Owns(&corev1.Secret{}, builder.WithPredicates(lp), builder.MatchEveryOwner).
 		Owns(&corev1.ServiceAccount{}, builder.WithPredicates(lp)).
 		Owns(&rbacv1.ClusterRole{}, builder.WithPredicates(lp)).
 		Owns(&rbacv1.ClusterRoleBinding{}, builder.WithPredicates(lp)).
-		Owns(&admissionregistrationv1.MutatingWebhookConfiguration{}, builder.WithPredicates(lp)).
+		Owns(&admissionregistrationv1.MutatingWebhookConfiguration{}, builder.WithPredicates(lp), builder.MatchEveryOwner).
 		// Watch for WhatapPodMonitor
 		Watches(
 			&monitoringv2alpha1.WhatapPodMonitor{},
 			handler.EnqueueRequestsFromMapFunc(r.findWhatapAgents),
-			builder.WithPredicates(lp),
+			builder.WithPredicates(monitorSpecChanged, lp),
 		).
 		// Watch for WhatapServiceMonitor
 		Watches(
 			&monitoringv2alpha1.WhatapServiceMonitor{},
 			handler.EnqueueRequestsFromMapFunc(r.findWhatapAgents),
-			builder.WithPredicates(lp),
+			builder.WithPredicates(monitorSpecChanged, lp),
 		).
 		// Watch for WhatapStaticEndpoint
 		Watches(
 			&monitoringv2alpha1.WhatapStaticEndpoint{},
 			handler.EnqueueRequestsFromMapFunc(r.findWhatapAgents),
-			builder.WithPredicates(lp),
-		).
-		Complete(r)
+			builder.WithPredicates(monitorSpecChanged, lp),
+		)
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+
+	// Watch the metadata of the installed Prometheus Operator kinds (prometheusOperatorCompat).
+	// Kinds whose CRD is installed later are watched from the first reconcile that sees them.
+	r.restMapper = mgr.GetRESTMapper()
+	r.watchPrometheusOperatorKind = func(kind monitoringv2alpha1.PrometheusOperatorKind) error {
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(prometheusOperatorGVK(kind))
+		return c.Watch(source.Kind[client.Object](mgr.GetCache(), obj,
+			handler.EnqueueRequestsFromMapFunc(r.findPrometheusOperatorAgents),
+			monitorSpecChanged, lp,
+		))
+	}
+	_, err = r.installedPrometheusOperatorKinds()
+	return err
 }
 
 func getKind(obj client.Object) string {
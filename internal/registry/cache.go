@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConfigCache caches the image configs of a ConfigResolver. Failures are cached for a
+// shorter time so a registry outage does not slow down every lookup.
+type ConfigCache struct {
+	Source ConfigResolver
+	// TTL is how long a config is kept
+	TTL time.Duration
+	// ErrorTTL is how long a failure is kept
+	ErrorTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]configCacheEntry
+	now     func() time.Time
+}
+
+type configCacheEntry struct {
+	config  *ImageConfig
+	err     error
+	expires time.Time
+}
+
+var _ ConfigResolver = &ConfigCache{}
+
+// NewConfigCache caches configs of source for ttl and failures for errorTTL.
+func NewConfigCache(source ConfigResolver, ttl, errorTTL time.Duration) *ConfigCache {
+	return &ConfigCache{Source: source, TTL: ttl, ErrorTTL: errorTTL}
+}
+
+// ImageConfig returns the cached config of image, reading it from Source when missing
+// or expired.
+func (c *ConfigCache) ImageConfig(ctx context.Context, image string) (*ImageConfig, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	if entry, ok := c.lookup(image, now()); ok {
+		return entry.config, entry.err
+	}
+
+	config, err := c.Source.ImageConfig(ctx, image)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; the registry may still answer the next lookup
+		return nil, err
+	}
+	ttl := c.TTL
+	if err != nil {
+		ttl = c.ErrorTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]configCacheEntry{}
+	}
+	// Drop expired entries so images of deleted workloads do not pile up
+	for key, e := range c.entries {
+		if !now().Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[image] = configCacheEntry{config: config, err: err, expires: now().Add(ttl)}
+	return config, err
+}
+
+// lookup returns the unexpired entry of image.
+func (c *ConfigCache) lookup(image string, now time.Time) (configCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[image]
+	return entry, ok && now.Before(entry.expires)
+}
+
+// Cached returns a ConfigResolver answering only from the cache: it never contacts a
+// registry, so callers cannot make the operator fetch images of their choice.
+func (c *ConfigCache) Cached() ConfigResolver {
+	return cachedConfigs{cache: c}
+}
+
+type cachedConfigs struct {
+	cache *ConfigCache
+}
+
+func (r cachedConfigs) ImageConfig(ctx context.Context, image string) (*ImageConfig, error) {
+	now := time.Now
+	if r.cache.now != nil {
+		now = r.cache.now
+	}
+	entry, ok := r.cache.lookup(image, now())
+	if !ok {
+		return nil, fmt.Errorf("image config of %s is not cached", image)
+	}
+	return entry.config, entry.err
+}
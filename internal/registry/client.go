@@ -0,0 +1,382 @@
+// Package registry reads image metadata (tag digests and image configs) through the
+// Docker Registry HTTP API V2, which OCI distribution registries such as ECR, Harbor and
+// Quay implement.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerHubRegistry serves images without a registry host, e.g. "busybox:1.36"
+	dockerHubRegistry = "registry-1.docker.io"
+	// defaultTimeout bounds a single request
+	defaultTimeout = 10 * time.Second
+	// maxManifestBytes bounds a downloaded manifest or image config
+	maxManifestBytes = 4 << 20
+
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// manifestMediaTypes are accepted so the registry returns the image index (multi-arch)
+// when there is one, which is what a pull by tag resolves to.
+var manifestMediaTypes = strings.Join([]string{
+	mediaTypeOCIIndex,
+	mediaTypeDockerManifestList,
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// Resolver resolves an image reference (registry/repository:tag) to its digest.
+type Resolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// ImageConfig is the part of an image configuration that decides what a container runs.
+type ImageConfig struct {
+	Entrypoint []string `json:"Entrypoint,omitempty"`
+	Cmd        []string `json:"Cmd,omitempty"`
+}
+
+// ConfigResolver returns the configuration of an image.
+type ConfigResolver interface {
+	ImageConfig(ctx context.Context, image string) (*ImageConfig, error)
+}
+
+// Client reads registries with the credentials of Keychain, anonymously for registries
+// it has none for. It follows the bearer token flow of the registry challenge, or sends
+// the credentials directly to registries asking for basic authentication.
+type Client struct {
+	// HTTPClient defaults to a client with a 10s timeout
+	HTTPClient *http.Client
+	// Keychain is optional; without it every registry is read anonymously
+	Keychain Keychain
+}
+
+// NewClient returns a Client with default settings reading registries with keychain,
+// which may be nil.
+func NewClient(keychain Keychain) *Client {
+	return &Client{HTTPClient: &http.Client{Timeout: defaultTimeout}, Keychain: keychain}
+}
+
+var (
+	_ Resolver       = &Client{}
+	_ ConfigResolver = &Client{}
+)
+
+// Resolve returns the digest (sha256:...) the tag of image currently points to.
+func (c *Client) Resolve(ctx context.Context, image string) (string, error) {
+	if strings.Contains(image, "@") {
+		return "", fmt.Errorf("image %s is already pinned by digest", image)
+	}
+	ref, err := parseReference(image)
+	if err != nil {
+		return "", err
+	}
+	s := &session{client: c}
+	resp, err := s.do(ctx, http.MethodHead, ref.manifestURL(ref.reference), manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); resp.StatusCode == http.StatusOK && digest != "" {
+		return digest, nil
+	}
+	// Some registries only send the digest on GET
+	_, digest, err := s.manifest(ctx, ref, ref.reference)
+	return digest, err
+}
+
+// ImageConfig returns the ENTRYPOINT and CMD of image. For a multi-arch image the
+// linux/amd64 variant is read, falling back to the first linux variant.
+func (c *Client) ImageConfig(ctx context.Context, image string) (*ImageConfig, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{client: c}
+	body, _, err := s.manifest(ctx, ref, ref.reference)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+		Config    struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Digest   string `json:"digest"`
+			Platform struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest of %s: %w", image, err)
+	}
+	if len(manifest.Manifests) > 0 {
+		digest := ""
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS != "linux" {
+				continue
+			}
+			if digest == "" || m.Platform.Architecture == "amd64" {
+				digest = m.Digest
+			}
+			if m.Platform.Architecture == "amd64" {
+				break
+			}
+		}
+		if digest == "" {
+			return nil, fmt.Errorf("image %s has no linux variant", image)
+		}
+		if body, _, err = s.manifest(ctx, ref, digest); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, &manifest); err != nil {
+			return nil, fmt.Errorf("decode manifest of %s: %w", image, err)
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s has no config", image)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, ref.blobURL(manifest.Config.Digest), "")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get config of %s: %s", image, resp.Status)
+	}
+	var config struct {
+		Config ImageConfig `json:"config"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&config); err != nil {
+		return nil, fmt.Errorf("decode config of %s: %w", image, err)
+	}
+	return &config.Config, nil
+}
+
+// reference is a parsed image reference.
+type reference struct {
+	host       string
+	repository string
+	// reference is the tag or digest
+	reference string
+}
+
+func (r reference) manifestURL(ref string) string {
+	return fmt.Sprintf("https://%s/v2/%s/manifests/%s", r.host, r.repository, ref)
+}
+
+func (r reference) blobURL(digest string) string {
+	return fmt.Sprintf("https://%s/v2/%s/blobs/%s", r.host, r.repository, digest)
+}
+
+// parseReference splits an image reference into registry host, repository and tag or
+// digest. References without either resolve "latest".
+func parseReference(image string) (reference, error) {
+	name, ref := image, "latest"
+	if i := strings.Index(image, "@"); i >= 0 {
+		name, ref = image[:i], image[i+1:]
+	} else if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		name, ref = image[:colon], image[colon+1:]
+	}
+	r := reference{reference: ref}
+	first, rest, ok := strings.Cut(name, "/")
+	switch {
+	case ok && (strings.ContainsAny(first, ".:") || first == "localhost"):
+		r.host, r.repository = first, rest
+	case ok:
+		r.host, r.repository = dockerHubRegistry, name
+	default:
+		r.host, r.repository = dockerHubRegistry, "library/"+name
+	}
+	if r.repository == "" || r.reference == "" {
+		return reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	return r, nil
+}
+
+// session sends requests to one registry, keeping the Authorization of the first
+// challenge for the following requests.
+type session struct {
+	client        *Client
+	authorization string
+}
+
+// do sends a request, authenticating once if the registry asks for it. The caller
+// closes the body.
+func (s *session) do(ctx context.Context, method, target, accept string) (*http.Response, error) {
+	resp, err := s.send(ctx, method, target, accept, s.authorization)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || s.authorization != "" {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+	host := resp.Request.URL.Host
+	if s.authorization, err = s.authenticate(ctx, host, resp.Header.Get("WWW-Authenticate")); err != nil {
+		return nil, fmt.Errorf("authenticate to %s: %w", host, err)
+	}
+	return s.send(ctx, method, target, accept, s.authorization)
+}
+
+func (s *session) send(ctx context.Context, method, target, accept, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return s.client.httpClient().Do(req)
+}
+
+// authenticate returns the Authorization header answering the challenge of a 401
+// response of host.
+func (s *session) authenticate(ctx context.Context, host, challenge string) (string, error) {
+	basic := ""
+	if s.client.Keychain != nil {
+		username, password, ok, err := s.client.Keychain.Credentials(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			basic = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		}
+	}
+	scheme, _, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "Basic") {
+		if basic == "" {
+			return "", fmt.Errorf("the registry requires credentials and none are configured for %s", host)
+		}
+		return basic, nil
+	}
+	token, err := s.fetchToken(ctx, challenge, basic)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// manifest downloads the manifest ref (tag or digest) of the repository and returns it
+// with its digest.
+func (s *session) manifest(ctx context.Context, r reference, ref string) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, r.manifestURL(ref), manifestMediaTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("get manifest %s/%s:%s: %s", r.host, r.repository, ref, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return body, digest, nil
+}
+
+// fetchToken fetches a bearer token for the challenge of a 401 response, anonymously
+// when basic is empty.
+func (s *session) fetchToken(ctx context.Context, challenge, basic string) (string, error) {
+	scheme, params, ok := strings.Cut(challenge, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	attrs := parseChallengeParams(params)
+	realm := attrs["realm"]
+	if realm == "" {
+		return "", errors.New("authentication challenge without realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v := attrs[key]; v != "" {
+			query.Set(key, v)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	resp, err := s.send(ctx, http.MethodGet, tokenURL.String(), "", basic)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response without token")
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// parseChallengeParams parses the comma separated key="value" pairs of a
+// WWW-Authenticate challenge. Values may contain commas (e.g. scope).
+func parseChallengeParams(params string) map[string]string {
+	attrs := map[string]string{}
+	for rest := strings.TrimSpace(params); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				attrs[key] = value[1:]
+				break
+			}
+			attrs[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			v, r, _ := strings.Cut(value, ",")
+			attrs[key] = strings.TrimSpace(v)
+			rest = r
+		}
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return attrs
+}
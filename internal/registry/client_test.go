@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResolve_BearerTokenFlow(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:whatap/apm-init-java:pull" {
+				t.Errorf("Unexpected token scope %q", r.URL.Query().Get("scope"))
+			}
+			_, _ = w.Write([]byte(`{"token":"anonymous"}`))
+		case r.URL.Path == "/v2/whatap/apm-init-java/manifests/latest":
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test",scope="repository:whatap/apm-init-java:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Method != http.MethodHead || !strings.Contains(r.Header.Get("Accept"), "image.index") {
+				t.Errorf("Expected a HEAD accepting image indexes, got %s %q", r.Method, r.Header.Get("Accept"))
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{HTTPClient: srv.Client()}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	got, err := c.Resolve(context.Background(), host+"/whatap/apm-init-java:latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != digest {
+		t.Errorf("Expected %s, got %s", digest, got)
+	}
+
+	if _, err := c.Resolve(context.Background(), host+"/whatap/apm-init-php:latest"); err == nil {
+		t.Errorf("Expected an error for an unknown repository")
+	}
+}
+
+// staticKeychain holds the credentials of one host
+type staticKeychain struct{ host, username, password string }
+
+func (k staticKeychain) Credentials(ctx context.Context, host string) (string, string, bool, error) {
+	return k.username, k.password, host == k.host, nil
+}
+
+func TestResolve_Credentials(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			// The token of a private repository is only issued with credentials
+			if user, password, ok := r.BasicAuth(); !ok || user != "robot" || password != "s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"private"}`))
+		case "/v2/team/bearer/manifests/1.0":
+			if r.Header.Get("Authorization") != "Bearer private" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		case "/v2/team/basic/manifests/1.0":
+			if user, password, ok := r.BasicAuth(); !ok || user != "robot" || password != "s3cret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	c := &Client{HTTPClient: srv.Client(), Keychain: staticKeychain{host, "robot", "s3cret"}}
+	for _, image := range []string{host + "/team/bearer:1.0", host + "/team/basic:1.0"} {
+		if got, err := c.Resolve(context.Background(), image); err != nil || got != digest {
+			t.Errorf("Expected %s to resolve with credentials, got %q, %v", image, got, err)
+		}
+	}
+
+	anonymous := &Client{HTTPClient: srv.Client()}
+	if _, err := anonymous.Resolve(context.Background(), host+"/team/basic:1.0"); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Expected an anonymous read of a private registry to fail, got %v", err)
+	}
+}
+
+func TestSecretKeychain(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "hub"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{
+				"https://index.docker.io/v1/":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("hubuser:hubpass")) + `"},
+				"harbor.example.com":{"username":"robot","password":"s3cret"}}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "legacy"},
+			Type:       corev1.SecretTypeDockercfg,
+			Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"quay.io":{"username":"q","password":"p"}}`)},
+		},
+	).Build()
+	k := NewSecretKeychain(reader, "whatap-monitoring", []string{"missing", "hub", "legacy"})
+
+	for host, want := range map[string]string{
+		dockerHubRegistry:    "hubuser:hubpass",
+		"harbor.example.com": "robot:s3cret",
+		"quay.io":            "q:p",
+		"ghcr.io":            "",
+	} {
+		username, password, ok, err := k.Credentials(context.Background(), host)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", host, err)
+		}
+		if got := username + ":" + password; ok != (want != "") || ok && got != want {
+			t.Errorf("%s: expected %q, got %q (ok %v)", host, want, got, ok)
+		}
+	}
+	if NewSecretKeychain(reader, "whatap-monitoring", nil) != nil {
+		t.Errorf("Expected no keychain without secrets")
+	}
+}
+
+func TestImageConfig_MultiArch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/team/api/manifests/3.12", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", mediaTypeOCIIndex)
+		_, _ = w.Write([]byte(`{"mediaType":"` + mediaTypeOCIIndex + `","manifests":[
+			{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}},
+			{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}}]}`))
+	})
+	mux.HandleFunc("/v2/team/api/manifests/sha256:amd", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"config":{"digest":"sha256:cfg"}}`))
+	})
+	mux.HandleFunc("/v2/team/api/blobs/sha256:cfg", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"architecture":"amd64","config":{"Entrypoint":["/docker-entrypoint.sh"],"Cmd":["gunicorn","-c","conf.py","app:app"]}}`))
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	c := &Client{HTTPClient: srv.Client()}
+	config, err := c.ImageConfig(context.Background(), strings.TrimPrefix(srv.URL, "https://")+"/team/api:3.12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(config.Entrypoint, " ") != "/docker-entrypoint.sh" || strings.Join(config.Cmd, " ") != "gunicorn -c conf.py app:app" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	for image, want := range map[string]reference{
+		"public.ecr.aws/whatap/apm-init-java:2.2.50": {"public.ecr.aws", "whatap/apm-init-java", "2.2.50"},
+		"localhost:5000/apm-init-java":               {"localhost:5000", "apm-init-java", "latest"},
+		"whatap/kube_agent:1.0":                      {dockerHubRegistry, "whatap/kube_agent", "1.0"},
+		"busybox":                                    {dockerHubRegistry, "library/busybox", "latest"},
+		"busybox@sha256:abc":                         {dockerHubRegistry, "library/busybox", "sha256:abc"},
+	} {
+		got, err := parseReference(image)
+		if err != nil || got != want {
+			t.Errorf("parseReference(%q) = %+v %v, want %+v", image, got, err, want)
+		}
+	}
+	if _, err := (&Client{}).Resolve(context.Background(), "busybox@sha256:abc"); err == nil {
+		t.Errorf("Expected an error resolving a reference pinned by digest")
+	}
+}
+
+// countingResolver counts the lookups that reach the registry
+type countingResolver struct{ lookups int }
+
+func (r *countingResolver) ImageConfig(ctx context.Context, image string) (*ImageConfig, error) {
+	r.lookups++
+	return &ImageConfig{Entrypoint: []string{"python"}}, nil
+}
+
+func TestConfigCache_Cached(t *testing.T) {
+	source := &countingResolver{}
+	cache := NewConfigCache(source, time.Hour, time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.Cached().ImageConfig(ctx, "example.com/app:1"); err == nil {
+		t.Errorf("Expected an error for an image that was never read")
+	}
+	if _, err := cache.ImageConfig(ctx, "example.com/app:1"); err != nil {
+		t.Fatal(err)
+	}
+	config, err := cache.Cached().ImageConfig(ctx, "example.com/app:1")
+	if err != nil || config.Entrypoint[0] != "python" {
+		t.Errorf("Expected the cached config, got %v %v", config, err)
+	}
+	if source.lookups != 1 {
+		t.Errorf("Expected only the uncached lookup to reach the registry, got %d", source.lookups)
+	}
+}
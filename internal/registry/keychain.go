@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keychain returns the credentials of a registry host. ok is false when the registry is
+// read anonymously.
+type Keychain interface {
+	Credentials(ctx context.Context, host string) (username, password string, ok bool, err error)
+}
+
+// SecretKeychain reads credentials from docker config Secrets (kubernetes.io/dockerconfigjson
+// or kubernetes.io/dockercfg), the format of image pull secrets. The Secrets are read on
+// every lookup, so rotated credentials apply without a restart; callers cache the results
+// of the registry instead.
+type SecretKeychain struct {
+	Reader    client.Reader
+	Namespace string
+	// Names are the Secrets in Namespace, searched in order. Missing Secrets are skipped.
+	Names []string
+}
+
+// NewSecretKeychain returns a SecretKeychain, or nil when names is empty.
+func NewSecretKeychain(reader client.Reader, namespace string, names []string) Keychain {
+	if len(names) == 0 {
+		return nil
+	}
+	return &SecretKeychain{Reader: reader, Namespace: namespace, Names: names}
+}
+
+var _ Keychain = &SecretKeychain{}
+
+// Credentials returns the credentials of host from the first Secret that has an entry for it.
+func (k *SecretKeychain) Credentials(ctx context.Context, host string) (string, string, bool, error) {
+	for _, name := range k.Names {
+		secret := &corev1.Secret{}
+		if err := k.Reader.Get(ctx, client.ObjectKey{Namespace: k.Namespace, Name: name}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", "", false, fmt.Errorf("read registry secret %s/%s: %w", k.Namespace, name, err)
+		}
+		auths, err := dockerConfigAuths(secret)
+		if err != nil {
+			return "", "", false, fmt.Errorf("registry secret %s/%s: %w", k.Namespace, name, err)
+		}
+		for key, auth := range auths {
+			if registryHost(key) != host {
+				continue
+			}
+			username, password := auth.Username, auth.Password
+			if auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					return "", "", false, fmt.Errorf("registry secret %s/%s: decode auth of %s: %w", k.Namespace, name, key, err)
+				}
+				username, password, _ = strings.Cut(string(decoded), ":")
+			}
+			return username, password, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// dockerConfigAuth is an entry of the auths of a docker config.
+type dockerConfigAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// dockerConfigAuths returns the auths of a docker config Secret, keyed by registry.
+func dockerConfigAuths(secret *corev1.Secret) (map[string]dockerConfigAuth, error) {
+	if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		var config struct {
+			Auths map[string]dockerConfigAuth `json:"auths"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", corev1.DockerConfigJsonKey, err)
+		}
+		return config.Auths, nil
+	}
+	if data, ok := secret.Data[corev1.DockerConfigKey]; ok {
+		var auths map[string]dockerConfigAuth
+		if err := json.Unmarshal(data, &auths); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", corev1.DockerConfigKey, err)
+		}
+		return auths, nil
+	}
+	return nil, fmt.Errorf("no %s or %s key", corev1.DockerConfigJsonKey, corev1.DockerConfigKey)
+}
+
+// registryHost returns the registry host of a docker config key, which may be a URL such
+// as "https://index.docker.io/v1/".
+func registryHost(key string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "docker.io", "index.docker.io":
+		return dockerHubRegistry
+	}
+	return host
+}
@@ -0,0 +1,142 @@
+package v2alpha1
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// appNameData is the data TargetSpec.AppNameTemplate is executed with.
+type appNameData struct {
+	Namespace     string
+	OwnerKind     string
+	OwnerName     string
+	PodName       string
+	ContainerName string
+	Language      string
+	Labels        map[string]string
+	Annotations   map[string]string
+}
+
+// sampleAppNameData is used to check templates when a WhatapAgent is admitted.
+var sampleAppNameData = appNameData{
+	Namespace:     "default",
+	OwnerKind:     "Deployment",
+	OwnerName:     "app",
+	ContainerName: "app",
+	Language:      "java",
+	Labels:        map[string]string{},
+	Annotations:   map[string]string{},
+}
+
+// appNameEnvName returns the env the agent of target reads its application name from.
+func appNameEnvName(target monitoringv2alpha1.TargetSpec) string {
+	switch {
+	case target.UsesOtel():
+		return EnvOtelServiceName
+	case target.Language == "java":
+		return EnvJavaAppName
+	default:
+		return EnvAppName
+	}
+}
+
+// renderAppName executes the application name template text with data.
+func renderAppName(text string, data appNameData) (string, error) {
+	tmpl, err := template.New("appNameTemplate").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(name.String()), nil
+}
+
+// validateAppNameTemplates checks that the appNameTemplate of every target parses and
+// executes with sample data.
+func validateAppNameTemplates(whatapagent *monitoringv2alpha1.WhatapAgent) error {
+	for i, target := range whatapagent.Spec.Features.Apm.Instrumentation.Targets {
+		if target.AppNameTemplate == "" {
+			continue
+		}
+		if _, err := renderAppName(target.AppNameTemplate, sampleAppNameData); err != nil {
+			return fmt.Errorf("target[%d] %q: invalid appNameTemplate: %w", i, target.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyAppNameTemplate names the application of every container of pod instrumented for
+// target from target.AppNameTemplate. It runs before the agent is injected, so the name
+// is taken as a container value: a name the container already sets, or one set in
+// target.Envs, is kept. The returned warnings report templates that failed to execute.
+func applyAppNameTemplate(pod *corev1.Pod, namespace string, target monitoringv2alpha1.TargetSpec, containerNames []string, logger logr.Logger) []string {
+	if target.AppNameTemplate == "" {
+		return nil
+	}
+	envName := appNameEnvName(target)
+	if _, ok := findEnvValueByKeys(target.Envs, envName); ok {
+		return nil
+	}
+
+	kind, owner := podWorkload(pod)
+	data := appNameData{
+		Namespace:   namespace,
+		OwnerKind:   kind,
+		OwnerName:   owner,
+		PodName:     pod.Name,
+		Language:    target.Language,
+		Labels:      pod.Labels,
+		Annotations: pod.Annotations,
+	}
+	var warnings []string
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if ok, _ := shouldInstrumentContainer(*c, target.ContainerSelector, containerNames); !ok {
+			continue
+		}
+		if _, ok := findEnvValueByKeys(c.Env, envName); ok {
+			continue
+		}
+		data.ContainerName = c.Name
+		name, err := renderAppName(target.AppNameTemplate, data)
+		if err != nil {
+			logger.Error(err, "Failed to render the application name template", "container", c.Name, "target", target.Name)
+			warnings = append(warnings, fmt.Sprintf("container %s: appNameTemplate: %v", c.Name, err))
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		c.Env = append(c.Env, corev1.EnvVar{Name: envName, Value: name})
+	}
+	return warnings
+}
+
+// podWorkload returns the kind and name of the workload controlling pod. Pods of a
+// Deployment resolve to the Deployment through their ReplicaSet. The kind is empty for
+// pods without a controller, whose name is used instead, or at admission, where they have
+// none yet, their generateName without the trailing dash.
+func podWorkload(pod *corev1.Pod) (string, string) {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		if ref.Kind == "ReplicaSet" {
+			if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+				return "Deployment", strings.TrimSuffix(ref.Name, "-"+hash)
+			}
+		}
+		return ref.Kind, ref.Name
+	}
+	if pod.Name != "" {
+		return "", pod.Name
+	}
+	return "", strings.TrimSuffix(pod.GenerateName, "-")
+}
@@ -0,0 +1,136 @@
+package v2alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyAppNameTemplate(t *testing.T) {
+	controller := true
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "orders-7d9f8-",
+				Labels:       map[string]string{"app": "orders", "pod-template-hash": "7d9f8"},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "orders-7d9f8", Controller: &controller},
+				},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app"},
+				{Name: "worker", Env: []corev1.EnvVar{{Name: EnvAppName, Value: "custom"}}},
+				{Name: "istio-proxy"},
+			}},
+		}
+	}
+	target := monitoringv2alpha1.TargetSpec{
+		Name:            "shop",
+		Language:        "python",
+		AppNameTemplate: `{{.Namespace}}-{{.OwnerName}}-{{.ContainerName}}{{with index .Labels "tier"}}-{{.}}{{end}}`,
+	}
+
+	pod := newPod()
+	if warnings := applyAppNameTemplate(pod, "shop", target, nil, logr.Discard()); len(warnings) != 0 {
+		t.Fatalf("Unexpected warnings %v", warnings)
+	}
+	if got := envValues(pod.Spec.Containers[0].Env, EnvAppName); len(got) != 1 || got[0] != "shop-orders-app" {
+		t.Errorf("Expected the templated app name, got %v", got)
+	}
+	if got := envValues(pod.Spec.Containers[1].Env, EnvAppName); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("Expected the container app name to be kept, got %v", got)
+	}
+	if len(pod.Spec.Containers[2].Env) != 0 {
+		t.Errorf("Expected sidecars to be left alone, got %v", pod.Spec.Containers[2].Env)
+	}
+
+	// The injected agent keeps the templated name
+	envs := injectPythonEnvVars(pod.Spec.Containers[0], target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+	if got := envValues(envs, EnvAppName); len(got) != 1 || got[0] != "shop-orders-app" {
+		t.Errorf("Expected the injection to keep the templated app name, got %v", got)
+	}
+
+	// The bootstrap init container writes the templated name into whatap.conf
+	for _, lang := range []string{"python", "nodejs"} {
+		inits := createAgentInitContainers(target, monitoringv2alpha1.WhatapAgent{}, lang, "latest", initAppName(&pod.Spec, target, nil), logr.Discard())
+		if got := envValues(inits[0].Env, EnvAppName); len(got) != 1 || got[0] != "shop-orders-app" {
+			t.Errorf("%s: expected the init container to get the templated app name, got %v", lang, got)
+		}
+	}
+
+	// Java names the agent through whatap.name
+	java := target
+	java.Language = "java"
+	pod = newPod()
+	applyAppNameTemplate(pod, "shop", java, nil, logr.Discard())
+	if got, _ := effective(pod.Spec.Containers[0].Env, EnvJavaAppName); got != "shop-orders-app" {
+		t.Errorf("Expected whatap.name to be set, got %q", got)
+	}
+
+	// An app name set in target.Envs wins over the template
+	pinned := target
+	pinned.Envs = []corev1.EnvVar{{Name: EnvAppName, Value: "orders"}}
+	pod = newPod()
+	applyAppNameTemplate(pod, "shop", pinned, nil, logr.Discard())
+	if _, ok := effective(pod.Spec.Containers[0].Env, EnvAppName); ok {
+		t.Errorf("Expected the template to be skipped when envs set app_name")
+	}
+
+	// Execution errors are reported, not fatal
+	broken := target
+	broken.AppNameTemplate = `{{.Missing}}`
+	pod = newPod()
+	if warnings := applyAppNameTemplate(pod, "shop", broken, nil, logr.Discard()); len(warnings) != 1 || !strings.Contains(warnings[0], "container app") {
+		t.Errorf("Expected a warning for the unnamed container, got %v", warnings)
+	}
+}
+
+func TestPodWorkload(t *testing.T) {
+	controller := true
+	owned := func(kind, name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}},
+		}}
+	}
+	tests := []struct {
+		pod                *corev1.Pod
+		wantKind, wantName string
+	}{
+		{owned("ReplicaSet", "orders-7d9f8", map[string]string{"pod-template-hash": "7d9f8"}), "Deployment", "orders"},
+		{owned("ReplicaSet", "legacy", nil), "ReplicaSet", "legacy"},
+		{owned("StatefulSet", "db", nil), "StatefulSet", "db"},
+		{owned("Rollout", "canary", nil), "Rollout", "canary"},
+		{&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug"}}, "", "debug"},
+		{&corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "batch-"}}, "", "batch"},
+	}
+	for _, tt := range tests {
+		if kind, name := podWorkload(tt.pod); kind != tt.wantKind || name != tt.wantName {
+			t.Errorf("podWorkload(%v) = %q, %q, want %q, %q", tt.pod.OwnerReferences, kind, name, tt.wantKind, tt.wantName)
+		}
+	}
+}
+
+func TestWhatapAgentValidator_AppNameTemplate(t *testing.T) {
+	v := &WhatapAgentCustomValidator{client: newFakeClient(), namespace: "whatap-monitoring"}
+	ctx := context.Background()
+
+	agent := newAgent("whatap", metav1.Now().Time, "shop")
+	agent.Spec.Features.Apm.Instrumentation.Targets[0].AppNameTemplate = "{{.Namespace"
+	if _, err := v.ValidateCreate(ctx, &agent); err == nil || !strings.Contains(err.Error(), "appNameTemplate") {
+		t.Errorf("Expected an invalid template to be rejected on create, got %v", err)
+	}
+	agent.Spec.Features.Apm.Instrumentation.Targets[0].AppNameTemplate = "{{.Namespace}-{{.OwnerName}}"
+	if _, err := v.ValidateUpdate(ctx, &agent, &agent); err == nil || !strings.Contains(err.Error(), "appNameTemplate") {
+		t.Errorf("Expected an invalid template to be rejected on update, got %v", err)
+	}
+	agent.Spec.Features.Apm.Instrumentation.Targets[0].AppNameTemplate = `{{.Namespace}}-{{index .Labels "app"}}`
+	if _, err := v.ValidateUpdate(ctx, &agent, &agent); err != nil {
+		t.Errorf("Expected a valid template, got %v", err)
+	}
+}
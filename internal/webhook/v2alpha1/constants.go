@@ -19,6 +19,7 @@ const (
 	EnvJavaLicense           = "license"
 	EnvJavaWhatapHost        = "whatap.server.host"
 	EnvJavaWhatapPort        = "whatap.server.port"
+	EnvJavaAppName           = "whatap.name"
 	EnvJavaAgentPath         = "WHATAP_JAVA_AGENT_PATH"
 	EnvJavaToolOptions       = "JAVA_TOOL_OPTIONS"
 	ValJavaAgentPath         = "/whatap-agent/whatap.agent.java.jar"
@@ -35,9 +36,10 @@ const (
 	EnvOkind            = "OKIND"
 	EnvPythonPath       = "PYTHONPATH"
 
-	ValWhatapHome      = "/whatap-agent"
-	ValPythonBootstrap = "/whatap-agent/whatap/bootstrap"
-	ValPythonAgentPath = "/whatap-agent/whatap_python"
+	ValWhatapHome       = "/whatap-agent"
+	ValPythonBootstrap  = "/whatap-agent/whatap/bootstrap"
+	ValPythonAgentPath  = "/whatap-agent/whatap_python"
+	ValPythonStartAgent = "/whatap-agent/bin/whatap-start-agent"
 
 	// Node.js Agent Constants
 	EnvNodejsLicense    = "WHATAP_LICENSE"
@@ -50,6 +52,51 @@ const (
 	ValNodejsModules    = "/whatap-agent/node_modules"
 	ValNodejsRequire    = "-r whatap"
 
+	// PHP Agent Constants
+	EnvPhpLicense    = "WHATAP_LICENSE"
+	EnvPhpWhatapHost = "WHATAP_SERVER_HOST"
+	EnvPhpWhatapPort = "WHATAP_SERVER_PORT"
+	EnvPhpIniScanDir = "PHP_INI_SCAN_DIR"
+	ValPhpIniDir     = "/whatap-agent/php/conf.d"
+
+	// .NET Agent Constants. The profiler CLSID and path depend on the agent image and are
+	// taken from the target envs CORECLR_PROFILER / CORECLR_PROFILER_PATH
+	EnvDotnetLicense          = "WHATAP_LICENSE"
+	EnvDotnetWhatapHost       = "WHATAP_SERVER_HOST"
+	EnvDotnetWhatapPort       = "WHATAP_SERVER_PORT"
+	EnvCoreclrEnableProfiling = "CORECLR_ENABLE_PROFILING"
+	EnvCoreclrProfiler        = "CORECLR_PROFILER"
+	EnvCoreclrProfilerPath    = "CORECLR_PROFILER_PATH"
+	ValCoreclrEnableProfiling = "1"
+
+	// Go Agent Constants
+	EnvGolangLicense    = "WHATAP_LICENSE"
+	EnvGolangWhatapHost = "WHATAP_SERVER_HOST"
+	EnvGolangWhatapPort = "WHATAP_SERVER_PORT"
+
+	// OpenTelemetry auto-instrumentation Constants (agentType "otel")
+	EnvOtelEndpoint           = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvOtelProtocol           = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvOtelTracesExporter     = "OTEL_TRACES_EXPORTER"
+	EnvOtelMetricsExporter    = "OTEL_METRICS_EXPORTER"
+	EnvOtelServiceName        = "OTEL_SERVICE_NAME"
+	EnvOtelResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+	EnvOtelPodName            = "OTEL_RESOURCE_ATTRIBUTES_POD_NAME"
+	EnvOtelNodeName           = "OTEL_RESOURCE_ATTRIBUTES_NODE_NAME"
+	ValOtelProtocol           = "http/protobuf"
+	ValOtelExporter           = "otlp"
+	ValOtelJavaAgentPath      = "/whatap-agent/opentelemetry-javaagent.jar"
+	ValOtelHome               = "/whatap-agent/otel"
+	ValOtelPythonBootstrap    = "/whatap-agent/otel/opentelemetry/instrumentation/auto_instrumentation"
+	ValOtelNodejsRequire      = "--require /whatap-agent/otel/autoinstrumentation.js"
+
+	// Pod annotations controlling APM injection
+	AnnotationInject               = "apm.whatap.com/inject"          // "false" opts the pod out
+	AnnotationInjectLanguagePrefix = "apm.whatap.com/inject-"         // inject-<language>: "true" forces (within webhook namespaces), "false" opts out
+	AnnotationInjectContainerNames = "apm.whatap.com/container-names" // comma separated container names to instrument
+	AnnotationImageEntrypoint      = "apm.whatap.com/entrypoint"      // image ENTRYPOINT as a JSON array, for commands the webhook wraps
+	AnnotationImageCmd             = "apm.whatap.com/cmd"             // image CMD as a JSON array, for commands the webhook wraps
+
 	// Init Container
 	InitContainerName     = "whatap-agent-init"
 	VolumeNameWhatapAgent = "whatap-agent-volume"
@@ -0,0 +1,74 @@
+package v2alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+// imageConfigTimeout bounds the registry lookups of the image configs of one admission
+// request, well within the timeout of the pod webhook
+const imageConfigTimeout = 3 * time.Second
+
+// containerArgv returns the argv container runs, following the Kubernetes rules:
+// command overrides ENTRYPOINT, args override CMD. The image ENTRYPOINT/CMD come from the
+// hint annotations of the pod, or from the image config in the registry.
+func containerArgv(ctx context.Context, container *corev1.Container, annotations map[string]string, images registry.ConfigResolver) ([]string, error) {
+	if len(container.Command) > 0 {
+		return append(append([]string{}, container.Command...), container.Args...), nil
+	}
+	config, err := imageConfigFor(ctx, container.Image, annotations, images)
+	if err != nil {
+		return nil, err
+	}
+	argv := append([]string{}, config.Entrypoint...)
+	if len(container.Args) > 0 {
+		argv = append(argv, container.Args...)
+	} else {
+		argv = append(argv, config.Cmd...)
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("image %s has no ENTRYPOINT or CMD", container.Image)
+	}
+	return argv, nil
+}
+
+// imageConfigFor returns the ENTRYPOINT/CMD of image: the hint annotations when the pod
+// has any of them, the image config from the registry otherwise.
+func imageConfigFor(ctx context.Context, image string, annotations map[string]string, images registry.ConfigResolver) (*registry.ImageConfig, error) {
+	config := &registry.ImageConfig{}
+	hinted := false
+	for _, hint := range []struct {
+		key  string
+		into *[]string
+	}{
+		{AnnotationImageEntrypoint, &config.Entrypoint},
+		{AnnotationImageCmd, &config.Cmd},
+	} {
+		value, ok := annotations[hint.key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(value), hint.into); err != nil {
+			return nil, fmt.Errorf("annotation %s must be a JSON array of strings: %w", hint.key, err)
+		}
+		hinted = true
+	}
+	if hinted {
+		return config, nil
+	}
+	if images == nil {
+		return nil, fmt.Errorf("no command and no %s annotation", AnnotationImageEntrypoint)
+	}
+	// ctx carries the deadline shared by the lookups of the request (see mutatePod)
+	config, err := images.ImageConfig(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("read the image config (set the %s/%s annotations for private registries): %w", AnnotationImageEntrypoint, AnnotationImageCmd, err)
+	}
+	return config, nil
+}
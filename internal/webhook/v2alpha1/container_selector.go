@@ -0,0 +1,97 @@
+package v2alpha1
+
+import (
+	"regexp"
+	"strings"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// knownSidecarNames / knownSidecarImages identify sidecars injected by service meshes, secret
+// managers and log shippers. Instrumenting them is at best useless (-javaagent in a non-JVM
+// process) and at worst fatal (NODE_OPTIONS "-r whatap" in a Node based sidecar).
+var (
+	knownSidecarNames = []string{"istio-proxy", "istio-init", "linkerd-proxy", "linkerd-init", "vault-agent", "vault-agent-init", "fluent-bit"}
+
+	knownSidecarImages = []string{"istio/proxyv2", "linkerd/proxy", "hashicorp/vault", "fluent/fluent-bit"}
+)
+
+// isKnownSidecar reports whether container is a well-known sidecar.
+func isKnownSidecar(container corev1.Container) bool {
+	if containsString(knownSidecarNames, container.Name) {
+		return true
+	}
+	for _, image := range knownSidecarImages {
+		if strings.Contains(container.Image, image) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldInstrumentContainer decides whether the agent is injected into container.
+// containerNames comes from the apm.whatap.com/container-names pod annotation and, when
+// set, is authoritative: the listed containers are instrumented (sidecars included) and
+// nothing else. Otherwise the target's ContainerSelector is applied and well-known
+// sidecars are skipped. The returned reason is empty when the container is instrumented.
+func shouldInstrumentContainer(container corev1.Container, selector *monitoringv2alpha1.ContainerSelector, containerNames []string) (bool, string) {
+	if len(containerNames) > 0 {
+		if containsString(containerNames, container.Name) {
+			return true, ""
+		}
+		return false, "not listed in " + AnnotationInjectContainerNames
+	}
+
+	if selector == nil {
+		if isKnownSidecar(container) {
+			return false, "well-known sidecar"
+		}
+		return true, ""
+	}
+
+	explicitlyNamed := containsString(selector.Names, container.Name)
+	if len(selector.Names) > 0 && !explicitlyNamed {
+		return false, "not listed in containerSelector.names"
+	}
+	if selector.NameRegex != "" {
+		re, err := regexp.Compile(selector.NameRegex)
+		if err != nil {
+			return false, "invalid containerSelector.nameRegex: " + err.Error()
+		}
+		if !re.MatchString(container.Name) {
+			return false, "name does not match containerSelector.nameRegex"
+		}
+	}
+	if len(selector.ImagePatterns) > 0 && !matchesAnyGlob(selector.ImagePatterns, container.Image) {
+		return false, "image does not match containerSelector.imagePatterns"
+	}
+	if !explicitlyNamed && !selector.IncludeSidecars && isKnownSidecar(container) {
+		return false, "well-known sidecar"
+	}
+	return true, ""
+}
+
+// selectContainersForInjection returns the names of the containers in podSpec that
+// shouldInstrumentContainer accepts.
+func selectContainersForInjection(podSpec *corev1.PodSpec, selector *monitoringv2alpha1.ContainerSelector, containerNames []string) []string {
+	var selected []string
+	for _, c := range podSpec.Containers {
+		if ok, _ := shouldInstrumentContainer(c, selector, containerNames); ok {
+			selected = append(selected, c.Name)
+		}
+	}
+	return selected
+}
+
+// matchesAnyGlob reports whether s matches one of the glob patterns. Unlike path.Match,
+// '*' also matches '/', so "*/my-app:*" matches "registry.example.com/team/my-app:1.0".
+func matchesAnyGlob(patterns []string, s string) bool {
+	for _, p := range patterns {
+		expr := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(p)) + "$"
+		if matched, _ := regexp.MatchString(expr, s); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package v2alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestShouldInstrumentContainer(t *testing.T) {
+	app := corev1.Container{Name: "app", Image: "registry.example.com/team/orders:1.4"}
+	worker := corev1.Container{Name: "worker", Image: "registry.example.com/team/orders-worker:1.4"}
+	istio := corev1.Container{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:1.22.0"}
+	fluent := corev1.Container{Name: "logs", Image: "cr.fluentbit.io/fluent/fluent-bit:3.0"}
+
+	tests := []struct {
+		name           string
+		container      corev1.Container
+		selector       *monitoringv2alpha1.ContainerSelector
+		containerNames []string
+		want           bool
+	}{
+		{"no selector instruments app", app, nil, nil, true},
+		{"no selector skips sidecar by name", istio, nil, nil, false},
+		{"no selector skips sidecar by image", fluent, nil, nil, false},
+		{"names match", app, &monitoringv2alpha1.ContainerSelector{Names: []string{"app"}}, nil, true},
+		{"names miss", worker, &monitoringv2alpha1.ContainerSelector{Names: []string{"app"}}, nil, false},
+		{"explicit name includes sidecar", istio, &monitoringv2alpha1.ContainerSelector{Names: []string{"istio-proxy"}}, nil, true},
+		{"regex match", worker, &monitoringv2alpha1.ContainerSelector{NameRegex: "^(app|worker)$"}, nil, true},
+		{"regex does not include sidecar", istio, &monitoringv2alpha1.ContainerSelector{NameRegex: ".*"}, nil, false},
+		{"includeSidecars", istio, &monitoringv2alpha1.ContainerSelector{NameRegex: ".*", IncludeSidecars: true}, nil, true},
+		{"image glob crosses slashes", app, &monitoringv2alpha1.ContainerSelector{ImagePatterns: []string{"*/orders:*"}}, nil, true},
+		{"image glob miss", worker, &monitoringv2alpha1.ContainerSelector{ImagePatterns: []string{"*/orders:*"}}, nil, false},
+		{"fields are ANDed", worker, &monitoringv2alpha1.ContainerSelector{NameRegex: "worker", ImagePatterns: []string{"*/orders:*"}}, nil, false},
+		{"invalid regex matches nothing", app, &monitoringv2alpha1.ContainerSelector{NameRegex: "("}, nil, false},
+		{"annotation overrides selector", worker, &monitoringv2alpha1.ContainerSelector{Names: []string{"app"}}, []string{"worker"}, true},
+		{"annotation may include sidecar", istio, nil, []string{"istio-proxy"}, true},
+		{"annotation excludes others", app, nil, []string{"worker"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := shouldInstrumentContainer(tt.container, tt.selector, tt.containerNames)
+			if got != tt.want {
+				t.Errorf("shouldInstrumentContainer() = %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Errorf("expected a reason for skipping")
+			}
+		})
+	}
+}
+
+func TestValidateContainerSelectors(t *testing.T) {
+	agent := newAgent("whatap", time.Time{})
+	agent.Spec.Features.Apm.Instrumentation.Targets[0].ContainerSelector = &monitoringv2alpha1.ContainerSelector{NameRegex: "app-[0-9"}
+	err := validateContainerSelectors(&agent)
+	if err == nil || !strings.Contains(err.Error(), "nameRegex") {
+		t.Fatalf("Expected an invalid nameRegex error, got %v", err)
+	}
+
+	agent.Spec.Features.Apm.Instrumentation.Targets[0].ContainerSelector.NameRegex = "app-[0-9]+"
+	if err := validateContainerSelectors(&agent); err != nil {
+		t.Fatalf("Expected valid selector, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package v2alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+// DryRunPath serves the injection dry-run on the metrics server. POST a Pod or a
+// workload (Deployment, StatefulSet, DaemonSet, ReplicaSet, Job, CronJob) as YAML or
+// JSON to see what the pod webhook would do to it. Requests are authenticated with a
+// TokenReview and authorized with a SubjectAccessReview of the nonResourceURL
+// /whatap-injection-dryrun and the verb post (see config/rbac/injection_dryrun_role.yaml):
+//
+//	kubectl -n <operator namespace> port-forward svc/whatap-operator-controller-manager-metrics-service 8443
+//	curl -k -H "Authorization: Bearer $(kubectl create token <service-account>)" \
+//	  --data-binary @deploy.yaml https://localhost:8443/whatap-injection-dryrun
+//
+// Images are not read from registries: containers without a command are only resolved
+// when admission already cached their image config, or from the hint annotations.
+const DryRunPath = "/whatap-injection-dryrun"
+
+// maxDryRunBodyBytes limits the size of a dry-run request
+const maxDryRunBodyBytes = 1 << 20
+
+// redactedValue replaces license values in dry-run output
+const redactedValue = "<redacted>"
+
+// licenseEnvNames are never echoed by the dry-run endpoint
+var licenseEnvNames = toNameSet(EnvWhatapLicense, EnvJavaLicense, EnvPythonLicense, EnvNodejsLicense, EnvPhpLicense, EnvDotnetLicense, EnvGolangLicense)
+
+// dryRunResult is the response of the dry-run endpoint.
+type dryRunResult struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Decision is what the webhook would do with the pod
+	Decision injectionDecision `json:"decision"`
+	// Targets lists every target, oldest instance first, with the reason it does not match
+	Targets []targetEvaluation `json:"targets"`
+	// Patch is the JSON patch the webhook would return
+	Patch []json.RawMessage `json:"patch"`
+	// Containers is the resulting environment of every container
+	Containers []containerEnv `json:"containers"`
+	Warnings   []string       `json:"warnings,omitempty"`
+}
+
+type targetEvaluation struct {
+	Instance string `json:"instance"`
+	Target   string `json:"target"`
+	Language string `json:"language"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type containerEnv struct {
+	Name string          `json:"name"`
+	Env  []corev1.EnvVar `json:"env"`
+}
+
+// dryRunHandler serves DryRunPath.
+type dryRunHandler struct {
+	client client.Client
+	images registry.ConfigResolver
+}
+
+func (h *dryRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST with a Pod or workload manifest", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxDryRunBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	obj, _, err := clientgoscheme.Codecs.UniversalDeserializer().Decode(body, nil, nil)
+	if err != nil {
+		http.Error(w, "decode manifest: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	kind, pod, err := podFromObject(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = r.URL.Query().Get("namespace")
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = metav1.NamespaceDefault
+	}
+	result, err := h.dryRun(r.Context(), pod)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Kind, result.Name = kind, obj.(metav1.Object).GetName()
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		whatapWebhookLogger.Error(err, "Failed to write dry-run response")
+	}
+}
+
+// dryRun runs mutatePod on pod against the live WhatapAgents.
+func (h *dryRunHandler) dryRun(ctx context.Context, pod *corev1.Pod) (*dryRunResult, error) {
+	result := &dryRunResult{Namespace: pod.Namespace}
+
+	var agents monitoringv2alpha1.WhatapAgentList
+	if err := h.client.List(ctx, &agents); err != nil {
+		return nil, fmt.Errorf("list WhatapAgents: %w", err)
+	}
+	ns := &corev1.Namespace{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: pod.Namespace}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get namespace %s: %w", pod.Namespace, err)
+		}
+		// Evaluate against the labels every namespace gets
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: pod.Namespace, Labels: map[string]string{corev1.LabelMetadataName: pod.Namespace}}}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("namespace %s does not exist; only its name label was evaluated", pod.Namespace))
+	}
+
+	result.Targets = evaluateTargets(agents.Items, pod, ns)
+
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	result.Decision = mutatePod(ctx, pod, agents.Items, ns, h.client, h.images, whatapWebhookLogger.WithValues("dryRun", true))
+	redactLicenses(&pod.Spec)
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range admission.PatchResponseFromRaw(original, mutated).Patches {
+		raw, err := json.Marshal(op)
+		if err != nil {
+			return nil, err
+		}
+		result.Patch = append(result.Patch, raw)
+	}
+	for _, c := range pod.Spec.Containers {
+		result.Containers = append(result.Containers, containerEnv{Name: c.Name, Env: c.Env})
+	}
+	return result, nil
+}
+
+// podFromObject returns the pod the webhook would see for obj: the Pod itself or a pod
+// created from the workload's template.
+func podFromObject(obj runtime.Object) (string, *corev1.Pod, error) {
+	var template *corev1.PodTemplateSpec
+	var kind string
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return "Pod", o.DeepCopy(), nil
+	case *appsv1.Deployment:
+		kind, template = "Deployment", &o.Spec.Template
+	case *appsv1.StatefulSet:
+		kind, template = "StatefulSet", &o.Spec.Template
+	case *appsv1.DaemonSet:
+		kind, template = "DaemonSet", &o.Spec.Template
+	case *appsv1.ReplicaSet:
+		kind, template = "ReplicaSet", &o.Spec.Template
+	case *batchv1.Job:
+		kind, template = "Job", &o.Spec.Template
+	case *batchv1.CronJob:
+		kind, template = "CronJob", &o.Spec.JobTemplate.Spec.Template
+	default:
+		return "", nil, fmt.Errorf("unsupported kind %s, expected a Pod or a workload with a pod template", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	meta := obj.(metav1.Object)
+	pod := &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Namespace = meta.GetNamespace()
+	pod.Name = ""
+	pod.GenerateName = meta.GetName() + "-"
+	return kind, pod, nil
+}
+
+// evaluateTargets explains, for every target of every instance (oldest first), whether
+// its selectors match pod.
+func evaluateTargets(agents []monitoringv2alpha1.WhatapAgent, pod *corev1.Pod, namespace *corev1.Namespace) []targetEvaluation {
+	sorted := make([]*monitoringv2alpha1.WhatapAgent, 0, len(agents))
+	for i := range agents {
+		sorted = append(sorted, &agents[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].IsOlderThan(sorted[j]) })
+
+	var evaluations []targetEvaluation
+	for _, cr := range sorted {
+		instrumentation := cr.Spec.Features.Apm.Instrumentation
+		for i := range instrumentation.Targets {
+			target := &instrumentation.Targets[i]
+			e := targetEvaluation{Instance: cr.Name, Target: target.Name, Language: target.Language}
+			switch {
+			case !cr.DeletionTimestamp.IsZero():
+				e.Reason = "instance is being deleted"
+			case !instrumentation.Enabled:
+				e.Reason = "instrumentation is disabled"
+			case !target.Enabled:
+				e.Reason = "target is disabled"
+			default:
+				e.Reason = targetMismatch(target, pod, namespace)
+				e.Matched = e.Reason == ""
+			}
+			evaluations = append(evaluations, e)
+		}
+	}
+	return evaluations
+}
+
+// redactLicenses hides literal license values so the dry-run output can be shared.
+func redactLicenses(podSpec *corev1.PodSpec) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			for j := range containers[i].Env {
+				env := &containers[i].Env[j]
+				if _, ok := licenseEnvNames[env.Name]; ok && env.Value != "" {
+					env.Value = redactedValue
+				}
+			}
+		}
+	}
+}
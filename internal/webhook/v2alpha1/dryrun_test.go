@@ -0,0 +1,99 @@
+package v2alpha1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const dryRunDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: orders
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: orders
+  template:
+    metadata:
+      labels:
+        app: orders
+    spec:
+      containers:
+      - name: app
+        image: orders:1.0
+        env:
+        - name: JAVA_TOOL_OPTIONS
+          value: -Xmx512m
+`
+
+func TestDryRunHandler(t *testing.T) {
+	older := newAgent("whatap", time.Now().Add(-time.Hour), "billing")
+	newer := newAgent("team-b", time.Now(), "shop")
+	newer.Spec.License = "secret-license"
+	c := newFakeClient(
+		&older, &newer,
+		// team-b sets its license in the spec and reads the host from its own Secret
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "team-b-whatap-credentials"},
+			Data:       map[string][]byte{"WHATAP_HOST": []byte("10.0.0.1")},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{corev1.LabelMetadataName: "shop"}}},
+	)
+	h := &dryRunHandler{client: c}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DryRunPath, strings.NewReader(dryRunDeployment)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret-license") {
+		t.Errorf("Expected the license to be redacted from the dry-run output")
+	}
+	var result dryRunResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Kind != "Deployment" || result.Namespace != "shop" {
+		t.Errorf("Unexpected object %s %s", result.Kind, result.Namespace)
+	}
+	if !result.Decision.Injected || result.Decision.Instance != "team-b" || result.Decision.Target != "team-b-java" {
+		t.Errorf("Expected injection by team-b/team-b-java, got %+v", result.Decision)
+	}
+	if len(result.Targets) != 2 || result.Targets[0].Matched || result.Targets[0].Reason != "namespace does not match namespaceSelector" || !result.Targets[1].Matched {
+		t.Errorf("Unexpected target evaluations %+v", result.Targets)
+	}
+	if len(result.Patch) == 0 {
+		t.Errorf("Expected a non-empty JSON patch")
+	}
+	if len(result.Containers) != 1 {
+		t.Fatalf("Expected one container, got %+v", result.Containers)
+	}
+	opts, _ := effective(result.Containers[0].Env, EnvJavaToolOptions)
+	if !strings.Contains(opts, "-Xmx512m") || !strings.Contains(opts, ValJavaAgentOptionPrefix) {
+		t.Errorf("Expected JAVA_TOOL_OPTIONS to keep the user value and add the agent, got %q", opts)
+	}
+}
+
+func TestDryRunHandler_BadRequest(t *testing.T) {
+	h := &dryRunHandler{}
+	for name, body := range map[string]string{
+		"not a manifest":   "hello",
+		"unsupported kind": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DryRunPath, strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package v2alpha1
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func injectDotnetEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, version string, logger logr.Logger) []corev1.EnvVar {
+	logger.Info("Configuring .NET APM agent injection", "version", version)
+
+	// .NET 전용 환경변수 (CR 기반, target envs 오버라이드 지원)
+	licenseEnv := getWhatapLicenseEnvVar(cr, target)
+	licenseEnv.Name = EnvDotnetLicense
+
+	hostEnv := getWhatapHostEnvVar(cr, target)
+	hostEnv.Name = EnvDotnetWhatapHost
+
+	portEnv := getWhatapPortEnvVar(cr, target)
+	portEnv.Name = EnvDotnetWhatapPort
+
+	envVars := []corev1.EnvVar{
+		// Whatap 서버 연결 정보
+		licenseEnv,
+		hostEnv,
+		portEnv,
+
+		// .NET 에이전트 경로 설정
+		{Name: EnvWhatapHome, Value: ValWhatapHome},
+		// Whatap 설정
+		{Name: EnvWhatapMicroEnabled, Value: ValTrue},
+
+		// Kubernetes 메타데이터
+		{Name: EnvNodeIP, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}}},
+		{Name: EnvNodeName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+		{Name: EnvPodName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+	}
+
+	// CLR profiler 설정 (whatap profiler를 런타임에 attach).
+	// profiler CLSID/경로는 에이전트 이미지마다 다르므로 target envs 로만 지정하며, 없으면 attach 하지 않는다.
+	if profiler, profilerPath := dotnetProfiler(target); profiler != "" && profilerPath != "" {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: EnvCoreclrEnableProfiling, Value: ValCoreclrEnableProfiling},
+			corev1.EnvVar{Name: EnvCoreclrProfiler, Value: profiler},
+			corev1.EnvVar{Name: EnvCoreclrProfilerPath, Value: profilerPath},
+		)
+	} else {
+		logger.Info("CLR profiler is not set in the target envs. Skipping profiler injection.", "container", container.Name)
+	}
+
+	// 와탭 소유 연결/설정 ENV와 CORECLR_* 는 기존 동일 키가 있어도 operator 값으로 강제 override 한다.
+	// (프로세스당 profiler는 하나만 attach 되므로 타 솔루션이 남긴 CORECLR_PROFILER 가 우선되면 안 됨)
+	return combineEnvVars(container.Env, envVars, func(name string) bool {
+		_, ok := dotnetForceEnvNames[name]
+		return ok
+	})
+}
+
+// dotnetProfiler returns the CLR profiler CLSID and path set in the target envs.
+func dotnetProfiler(target monitoringv2alpha1.TargetSpec) (profiler, profilerPath string) {
+	for _, e := range target.Envs {
+		switch e.Name {
+		case EnvCoreclrProfiler:
+			profiler = e.Value
+		case EnvCoreclrProfilerPath:
+			profilerPath = e.Value
+		}
+	}
+	return profiler, profilerPath
+}
+
+// validateDotnetTargets checks that every enabled dotnet target names the CLR profiler of
+// its agent image, which the operator cannot know.
+func validateDotnetTargets(whatapagent *monitoringv2alpha1.WhatapAgent) error {
+	for i, target := range whatapagent.Spec.Features.Apm.Instrumentation.Targets {
+		if !target.Enabled || target.Language != "dotnet" || target.UsesOtel() {
+			continue
+		}
+		if profiler, profilerPath := dotnetProfiler(target); profiler == "" || profilerPath == "" {
+			return fmt.Errorf("target[%d] %q: envs %s and %s are required for language dotnet",
+				i, target.Name, EnvCoreclrProfiler, EnvCoreclrProfilerPath)
+		}
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package v2alpha1
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
@@ -146,3 +148,162 @@ func TestInjectLanguageSpecific_UserEnvOverridesStaleAndHostWins(t *testing.T) {
 		t.Fatalf("expected single whatap.server.host=10.20.30.40, got %v", vals)
 	}
 }
+
+func TestInjectPhpEnvVars_OverridesPreInjectedHostAndAppendsIniScanDir(t *testing.T) {
+	container := corev1.Container{
+		Name: "app",
+		Env: []corev1.EnvVar{
+			{Name: EnvPhpWhatapHost, Value: "127.0.0.1"},
+			{Name: EnvPhpIniScanDir, Value: "/usr/local/etc/php/conf.d"}, // user value must be kept
+		},
+	}
+	target := monitoringv2alpha1.TargetSpec{
+		Envs: []corev1.EnvVar{{Name: EnvWhatapHost, Value: "10.20.30.40"}},
+	}
+
+	got := injectPhpEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+
+	if vals := envValues(got, EnvPhpWhatapHost); len(vals) != 1 || vals[0] != "10.20.30.40" {
+		t.Fatalf("expected single WHATAP_SERVER_HOST=10.20.30.40, got %v", vals)
+	}
+	want := "/usr/local/etc/php/conf.d:" + ValPhpIniDir
+	if vals := envValues(got, EnvPhpIniScanDir); len(vals) != 1 || vals[0] != want {
+		t.Fatalf("expected single PHP_INI_SCAN_DIR=%q, got %v", want, vals)
+	}
+
+	// Re-injection must not append the whatap ini dir twice.
+	container.Env = got
+	again := injectPhpEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+	if vals := envValues(again, EnvPhpIniScanDir); len(vals) != 1 || vals[0] != want {
+		t.Fatalf("PHP_INI_SCAN_DIR changed on re-injection: %v", vals)
+	}
+}
+
+func TestInjectPhpEnvVars_DefaultIniScanDirKeepsCompiledDefault(t *testing.T) {
+	got := injectPhpEnvVars(corev1.Container{Name: "app"}, monitoringv2alpha1.TargetSpec{}, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+
+	if v, _ := effective(got, EnvPhpIniScanDir); v != ":"+ValPhpIniDir {
+		t.Fatalf("expected PHP_INI_SCAN_DIR=%q, got %q", ":"+ValPhpIniDir, v)
+	}
+}
+
+// Only one CLR profiler can be attached, so a profiler left by another APM must be replaced.
+func TestInjectDotnetEnvVars_OverridesForeignProfiler(t *testing.T) {
+	container := corev1.Container{
+		Name: "app",
+		Env: []corev1.EnvVar{
+			{Name: EnvCoreclrProfiler, Value: "{00000000-0000-0000-0000-000000000000}"},
+			{Name: EnvCoreclrEnableProfiling, Value: "0"},
+			{Name: EnvDotnetWhatapHost, Value: "127.0.0.1"},
+		},
+	}
+	target := monitoringv2alpha1.TargetSpec{
+		Envs: []corev1.EnvVar{
+			{Name: EnvWhatapHost, Value: "10.20.30.40"},
+			{Name: EnvCoreclrProfiler, Value: "{11111111-2222-3333-4444-555555555555}"},
+			{Name: EnvCoreclrProfilerPath, Value: "/whatap-agent/dotnet/libwhatap_profiler.so"},
+		},
+	}
+
+	got := injectDotnetEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+
+	for name, want := range map[string]string{
+		EnvCoreclrEnableProfiling: ValCoreclrEnableProfiling,
+		EnvCoreclrProfiler:        "{11111111-2222-3333-4444-555555555555}",
+		EnvCoreclrProfilerPath:    "/whatap-agent/dotnet/libwhatap_profiler.so",
+		EnvDotnetWhatapHost:       "10.20.30.40",
+	} {
+		if vals := envValues(got, name); len(vals) != 1 || vals[0] != want {
+			t.Fatalf("expected single %s=%s, got %v", name, want, vals)
+		}
+	}
+}
+
+// Without a profiler in the target envs nothing is attached, rather than a guessed profiler.
+func TestInjectDotnetEnvVars_NoProfilerWithoutTargetEnvs(t *testing.T) {
+	container := corev1.Container{Name: "app"}
+	target := monitoringv2alpha1.TargetSpec{
+		Envs: []corev1.EnvVar{{Name: EnvCoreclrProfiler, Value: "{11111111-2222-3333-4444-555555555555}"}},
+	}
+
+	got := injectDotnetEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+
+	for _, name := range []string{EnvCoreclrEnableProfiling, EnvCoreclrProfiler, EnvCoreclrProfilerPath} {
+		if vals := envValues(got, name); len(vals) != 0 {
+			t.Errorf("expected no %s, got %v", name, vals)
+		}
+	}
+	if vals := envValues(got, EnvWhatapHome); len(vals) != 1 {
+		t.Errorf("expected the agent envs to be injected, got %v", got)
+	}
+}
+
+func TestWhatapAgentValidator_DotnetProfiler(t *testing.T) {
+	v := &WhatapAgentCustomValidator{client: newFakeClient(), namespace: "whatap-monitoring"}
+	ctx := context.Background()
+
+	agent := newAgent("whatap", time.Now(), "shop")
+	target := &agent.Spec.Features.Apm.Instrumentation.Targets[0]
+	target.Language = "dotnet"
+	if _, err := v.ValidateCreate(ctx, &agent); err == nil || !strings.Contains(err.Error(), EnvCoreclrProfilerPath) {
+		t.Errorf("Expected a dotnet target without profiler envs to be rejected on create, got %v", err)
+	}
+
+	target.Envs = []corev1.EnvVar{
+		{Name: EnvCoreclrProfiler, Value: "{11111111-2222-3333-4444-555555555555}"},
+		{Name: EnvCoreclrProfilerPath, Value: "/whatap-agent/dotnet/libwhatap_profiler.so"},
+	}
+	if _, err := v.ValidateUpdate(ctx, &agent, &agent); err != nil {
+		t.Errorf("Expected a dotnet target with profiler envs to be admitted, got %v", err)
+	}
+}
+
+// Agent images with another layout set their profiler through the target envs.
+func TestInjectDotnetEnvVars_ProfilerFromTargetEnvs(t *testing.T) {
+	container := corev1.Container{
+		Name: "app",
+		Env:  []corev1.EnvVar{{Name: EnvCoreclrProfiler, Value: "{00000000-0000-0000-0000-000000000000}"}},
+	}
+	target := monitoringv2alpha1.TargetSpec{
+		Envs: []corev1.EnvVar{
+			{Name: EnvCoreclrProfiler, Value: "{11111111-2222-3333-4444-555555555555}"},
+			{Name: EnvCoreclrProfilerPath, Value: "/whatap-agent/dotnet/linux-musl-x64/libwhatap_profiler.so"},
+		},
+	}
+
+	got := injectDotnetEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "latest", logr.Discard())
+
+	for name, want := range map[string]string{
+		EnvCoreclrProfiler:     "{11111111-2222-3333-4444-555555555555}",
+		EnvCoreclrProfilerPath: "/whatap-agent/dotnet/linux-musl-x64/libwhatap_profiler.so",
+	} {
+		if vals := envValues(got, name); len(vals) != 1 || vals[0] != want {
+			t.Fatalf("expected single %s=%s, got %v", name, want, vals)
+		}
+	}
+	if stripped := stripAgentEnvVars(got); len(envValues(stripped, EnvCoreclrProfiler)) != 0 {
+		t.Errorf("expected uninstrumenting to remove the overridden profiler, got %v", stripped)
+	}
+}
+
+func TestInjectGolangEnvVars_OverridesPreInjectedHostAndKeepsAppName(t *testing.T) {
+	container := corev1.Container{
+		Name: "app",
+		Env: []corev1.EnvVar{
+			{Name: EnvGolangWhatapHost, Value: "127.0.0.1"},
+			{Name: EnvAppName, Value: "orders"}, // user value preserved
+		},
+	}
+	target := monitoringv2alpha1.TargetSpec{
+		Envs: []corev1.EnvVar{{Name: EnvWhatapHost, Value: "10.20.30.40"}},
+	}
+
+	got := injectLanguageSpecificEnvVars(container, target, monitoringv2alpha1.WhatapAgent{}, "golang", "latest", logr.Discard())
+
+	if vals := envValues(got, EnvGolangWhatapHost); len(vals) != 1 || vals[0] != "10.20.30.40" {
+		t.Fatalf("expected single WHATAP_SERVER_HOST=10.20.30.40, got %v", vals)
+	}
+	if vals := envValues(got, EnvAppName); len(vals) != 1 || vals[0] != "orders" {
+		t.Fatalf("user app_name not preserved, got %v", vals)
+	}
+}
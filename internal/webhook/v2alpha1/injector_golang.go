@@ -0,0 +1,61 @@
+package v2alpha1
+
+import (
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// injectGolangEnvVars wires the connection settings for the whatap Go agent. The Go agent is
+// linked into the application binary, so unlike the other languages there is no loader or
+// search-path env to set; only the server connection and Kubernetes metadata are injected.
+func injectGolangEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, version string, logger logr.Logger) []corev1.EnvVar {
+	logger.Info("Configuring Go APM agent connection envs", "version", version)
+
+	appName := ""
+	for _, e := range target.Envs {
+		if e.Name == EnvAppName {
+			appName = e.Value
+		}
+	}
+	if appName == "" {
+		appName = container.Name
+	}
+
+	// Go 전용 환경변수 (CR 기반, target envs 오버라이드 지원)
+	licenseEnv := getWhatapLicenseEnvVar(cr, target)
+	licenseEnv.Name = EnvGolangLicense
+
+	hostEnv := getWhatapHostEnvVar(cr, target)
+	hostEnv.Name = EnvGolangWhatapHost
+
+	portEnv := getWhatapPortEnvVar(cr, target)
+	portEnv.Name = EnvGolangWhatapPort
+
+	envVars := []corev1.EnvVar{
+		// Whatap 서버 연결 정보
+		licenseEnv,
+		hostEnv,
+		portEnv,
+
+		// Go 애플리케이션 정보
+		{Name: EnvAppName, Value: appName},
+
+		// whatap.conf 위치
+		{Name: EnvWhatapHome, Value: ValWhatapHome},
+		// Whatap 설정
+		{Name: EnvWhatapMicroEnabled, Value: ValTrue},
+
+		// Kubernetes 메타데이터
+		{Name: EnvNodeIP, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}}},
+		{Name: EnvNodeName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+		{Name: EnvPodName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+	}
+
+	// 와탭 소유 연결/설정 ENV는 기존 동일 키가 있어도 operator 값으로 강제 override 한다.
+	// app_name은 기존/사용자 값을 보존한다.
+	return combineEnvVars(container.Env, envVars, func(name string) bool {
+		_, ok := golangForceEnvNames[name]
+		return ok
+	})
+}
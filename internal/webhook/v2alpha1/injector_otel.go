@@ -0,0 +1,208 @@
+package v2alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// otelInitUser runs the OpenTelemetry init container when initContainerSecurity is not
+// set: the OpenTelemetry images declare no USER, which runAsNonRoot alone rejects.
+const otelInitUser = 65534
+
+// otelIgnoredLabels are controller-generated pod labels left out of the resource attributes
+var otelIgnoredLabels = toNameSet("pod-template-hash", "controller-revision-hash")
+
+// otelWorkloadKinds are the workload kinds with a k8s.<kind>.name resource attribute
+var otelWorkloadKinds = toNameSet("Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job")
+
+// otelInitCommand copies the auto-instrumentation of the OpenTelemetry Operator image of
+// lang into the agent volume.
+func otelInitCommand(lang string) []string {
+	if lang == "java" {
+		return []string{"cp", "/javaagent.jar", ValOtelJavaAgentPath}
+	}
+	return []string{"sh", "-c", fmt.Sprintf("mkdir -p %s && cp -r /autoinstrumentation/. %s", ValOtelHome, ValOtelHome)}
+}
+
+// injectOtelEnvVars loads the OpenTelemetry auto-instrumentation of lang into container
+// and points its OTLP exporter at the collector of the target. The resource envs depend
+// on the pod and are set by setOtelResource.
+func injectOtelEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, lang string, logger logr.Logger) []corev1.EnvVar {
+	logger.Info("Configuring OpenTelemetry auto-instrumentation", "language", lang, "version", target.AgentVersion())
+
+	envVars := append([]corev1.EnvVar(nil), container.Env...)
+	switch lang {
+	case "java":
+		envVars = injectJavaToolOptions(envVars, ValJavaAgentOptionPrefix+ValOtelJavaAgentPath, logger)
+	case "python":
+		envVars = injectPythonPath(envVars, ValOtelPythonBootstrap+":"+ValOtelHome, logger)
+	case "nodejs":
+		envVars = injectNodejsOptions(envVars, ValOtelNodejsRequire, logger)
+	}
+
+	protocol := ValOtelProtocol
+	var endpoint string
+	if target.Otel != nil {
+		endpoint = target.Otel.Endpoint
+		if target.Otel.Protocol != "" {
+			protocol = target.Otel.Protocol
+		}
+	}
+	// 수집기 연결 정보는 operator 값으로 강제하고, exporter 선택은 사용자 값을 보존한다.
+	envVars = upsertEnvVars(envVars, []corev1.EnvVar{
+		{Name: EnvOtelEndpoint, Value: endpoint},
+		{Name: EnvOtelProtocol, Value: protocol},
+	})
+	return mergeEnvVars(envVars, []corev1.EnvVar{
+		{Name: EnvOtelTracesExporter, Value: ValOtelExporter},
+		{Name: EnvOtelMetricsExporter, Value: ValOtelExporter},
+	})
+}
+
+// setOtelResource sets OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES on the containers of
+// pod instrumented for target. The service name defaults to the workload owning the pod;
+// values already set on the container are kept.
+func setOtelResource(pod *corev1.Pod, namespace string, target monitoringv2alpha1.TargetSpec, containerNames []string, logger logr.Logger) {
+	kind, workload := podWorkload(pod)
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if ok, _ := shouldInstrumentContainer(*c, target.ContainerSelector, containerNames); !ok {
+			continue
+		}
+		serviceName := workload
+		if serviceName == "" {
+			serviceName = c.Name
+		}
+		c.Env = mergeEnvVars(c.Env, []corev1.EnvVar{{Name: EnvOtelServiceName, Value: serviceName}})
+		c.Env = mergeOtelResourceAttributes(c.Env, otelResourceAttributes(pod, namespace, kind, workload, c.Name, target.Otel), logger)
+	}
+}
+
+// otelResourceAttributes returns the key=value resource attributes of container. The
+// attributes of the target come first and win over the derived ones.
+func otelResourceAttributes(pod *corev1.Pod, namespace, kind, workload, container string, spec *monitoringv2alpha1.OtelSpec) []string {
+	var attrs []string
+	seen := map[string]struct{}{}
+	add := func(key, value string) {
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		attrs = append(attrs, key+"="+value)
+	}
+
+	if spec != nil {
+		for _, key := range sortedKeys(spec.ResourceAttributes) {
+			add(key, spec.ResourceAttributes[key])
+		}
+	}
+	add("k8s.namespace.name", namespace)
+	if _, ok := otelWorkloadKinds[kind]; ok {
+		add("k8s."+strings.ToLower(kind)+".name", workload)
+	}
+	add("k8s.pod.name", "$("+EnvOtelPodName+")")
+	add("k8s.node.name", "$("+EnvOtelNodeName+")")
+	add("k8s.container.name", container)
+	if version := pod.Labels["app.kubernetes.io/version"]; version != "" {
+		add("service.version", version)
+	}
+	for _, key := range sortedKeys(pod.Labels) {
+		if _, ignored := otelIgnoredLabels[key]; !ignored && key != monitoringv2alpha1.InjectionLabelKey {
+			add("k8s.pod.label."+key, pod.Labels[key])
+		}
+	}
+	return attrs
+}
+
+// mergeOtelResourceAttributes adds attrs to OTEL_RESOURCE_ATTRIBUTES of envs, keeping the
+// attributes already set. The variable is moved behind the downward API variables it
+// references, since Kubernetes only expands variables defined before. A value taken from
+// a ConfigMap/Secret is left alone.
+func mergeOtelResourceAttributes(envs []corev1.EnvVar, attrs []string, logger logr.Logger) []corev1.EnvVar {
+	var existing string
+	result := make([]corev1.EnvVar, 0, len(envs)+3)
+	for _, e := range envs {
+		if e.Name != EnvOtelResourceAttributes {
+			result = append(result, e)
+			continue
+		}
+		if e.ValueFrom != nil {
+			logger.Info("OTEL_RESOURCE_ATTRIBUTES is set via ConfigMap/Secret. Skipping injection.")
+			return envs
+		}
+		existing = e.Value
+	}
+
+	value := existing
+	present := map[string]struct{}{}
+	for _, attr := range strings.Split(existing, ",") {
+		if key, _, ok := strings.Cut(attr, "="); ok {
+			present[strings.TrimSpace(key)] = struct{}{}
+		}
+	}
+	for _, attr := range attrs {
+		key, _, _ := strings.Cut(attr, "=")
+		if _, ok := present[key]; ok {
+			continue
+		}
+		if value != "" {
+			value += ","
+		}
+		value += attr
+	}
+
+	result = mergeEnvVars(result, []corev1.EnvVar{
+		{Name: EnvOtelPodName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+		{Name: EnvOtelNodeName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+	})
+	return append(result, corev1.EnvVar{Name: EnvOtelResourceAttributes, Value: value})
+}
+
+// validateOtelTargets checks every enabled target of whatapagent with agentType "otel".
+func validateOtelTargets(whatapagent *monitoringv2alpha1.WhatapAgent) error {
+	for i, target := range whatapagent.Spec.Features.Apm.Instrumentation.Targets {
+		if !target.Enabled || !target.UsesOtel() {
+			continue
+		}
+		if err := validateOtelTarget(target); err != nil {
+			return fmt.Errorf("target[%d] %q: %w", i, target.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateOtelTarget checks the settings of a target with agentType "otel".
+func validateOtelTarget(target monitoringv2alpha1.TargetSpec) error {
+	switch target.Language {
+	case "java", "python", "nodejs":
+	default:
+		return fmt.Errorf("agentType otel supports java, python and nodejs, not %q", target.Language)
+	}
+	if target.Otel == nil || target.Otel.Endpoint == "" {
+		return fmt.Errorf("otel.endpoint is required when agentType is otel")
+	}
+	if target.Language == "python" && target.Otel.Protocol == "grpc" {
+		return fmt.Errorf("the Python auto-instrumentation only supports otel.protocol http/protobuf")
+	}
+	if target.PythonStartMode == monitoringv2alpha1.PythonStartModeWrap {
+		return fmt.Errorf("pythonStartMode wrap is not supported with agentType otel")
+	}
+	if target.RuntimeCheck != nil && target.RuntimeCheck.Enabled {
+		return fmt.Errorf("runtimeCheck is not supported with agentType otel")
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
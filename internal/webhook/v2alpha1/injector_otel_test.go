@@ -0,0 +1,145 @@
+package v2alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMutatePod_Otel(t *testing.T) {
+	agent := newAgent("whatap", time.Now(), "shop")
+	agent.Spec.ImageRegistry = "harbor.example.com/mirror"
+	target := &agent.Spec.Features.Apm.Instrumentation.Targets[0]
+	target.AgentType = monitoringv2alpha1.AgentTypeOtel
+	target.Otel = &monitoringv2alpha1.OtelSpec{
+		Endpoint:           "http://collector.whatap-monitoring:4318",
+		Version:            "2.10.0",
+		ResourceAttributes: map[string]string{"deployment.environment": "prod"},
+	}
+	controller := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "shop",
+			Labels:    map[string]string{"app": "orders", "pod-template-hash": "7d9f8", "app.kubernetes.io/version": "1.4.2"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "orders-7d9f8", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "shop/orders:1.4.2",
+			Env: []corev1.EnvVar{
+				{Name: EnvOtelResourceAttributes, Value: "team=payments,deployment.environment=staging"},
+				{Name: EnvJavaToolOptions, Value: "-Xmx512m"},
+			},
+		}}},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}}
+
+	decision := mutatePod(context.Background(), pod, []monitoringv2alpha1.WhatapAgent{agent}, ns, nil, fakeImages{}, logr.Discard())
+	if !decision.Injected || decision.Version != "2.10.0" {
+		t.Fatalf("Expected the pod to be injected with version 2.10.0, got %+v", decision)
+	}
+	init := pod.Spec.InitContainers[0]
+	if init.Image != "harbor.example.com/mirror/open-telemetry/opentelemetry-operator/autoinstrumentation-java:2.10.0" {
+		t.Errorf("Unexpected init image %q", init.Image)
+	}
+	if strings.Join(init.Command, " ") != "cp /javaagent.jar "+ValOtelJavaAgentPath {
+		t.Errorf("Unexpected init command %v", init.Command)
+	}
+	if init.SecurityContext.RunAsUser == nil || *init.SecurityContext.RunAsUser != otelInitUser {
+		t.Errorf("Expected the init container to run as %d, got %+v", otelInitUser, init.SecurityContext)
+	}
+
+	envs := pod.Spec.Containers[0].Env
+	for name, want := range map[string]string{
+		EnvJavaToolOptions:     "-Xmx512m -javaagent:" + ValOtelJavaAgentPath,
+		EnvOtelEndpoint:        "http://collector.whatap-monitoring:4318",
+		EnvOtelProtocol:        "http/protobuf",
+		EnvOtelTracesExporter:  "otlp",
+		EnvOtelServiceName:     "orders",
+		EnvOtelMetricsExporter: "otlp",
+	} {
+		if got := envValues(envs, name); len(got) != 1 || got[0] != want {
+			t.Errorf("Expected %s=%q, got %v", name, want, got)
+		}
+	}
+	if _, ok := effective(envs, EnvWhatapHome); ok {
+		t.Errorf("Expected no WhaTap agent env in otel mode")
+	}
+
+	// The resource attributes come last, after the downward API variables they reference
+	last := envs[len(envs)-1]
+	wantAttrs := "team=payments,deployment.environment=staging,k8s.namespace.name=shop,k8s.deployment.name=orders," +
+		"k8s.pod.name=$(OTEL_RESOURCE_ATTRIBUTES_POD_NAME),k8s.node.name=$(OTEL_RESOURCE_ATTRIBUTES_NODE_NAME)," +
+		"k8s.container.name=app,service.version=1.4.2,k8s.pod.label.app=orders,k8s.pod.label.app.kubernetes.io/version=1.4.2"
+	if last.Name != EnvOtelResourceAttributes || last.Value != wantAttrs {
+		t.Errorf("Unexpected resource attributes\n got %s=%s\nwant %s", last.Name, last.Value, wantAttrs)
+	}
+	if values := envValues(envs, EnvOtelPodName); len(values) != 1 {
+		t.Errorf("Expected the pod name variable once, got %v", values)
+	}
+
+	// Uninstrumenting removes the javaagent
+	stripInjectionArtifacts(pod)
+	if got, _ := effective(pod.Spec.Containers[0].Env, EnvJavaToolOptions); got != "-Xmx512m" {
+		t.Errorf("Expected the javaagent to be stripped, got %q", got)
+	}
+}
+
+func TestValidateOtelTarget(t *testing.T) {
+	valid := monitoringv2alpha1.TargetSpec{
+		Name: "otel", Enabled: true, Language: "python", AgentType: monitoringv2alpha1.AgentTypeOtel,
+		Otel: &monitoringv2alpha1.OtelSpec{Endpoint: "http://collector:4318"},
+	}
+	if err := validateOtelTarget(valid); err != nil {
+		t.Errorf("Expected a valid target, got %v", err)
+	}
+	for name, mutate := range map[string]func(*monitoringv2alpha1.TargetSpec){
+		"language": func(t *monitoringv2alpha1.TargetSpec) { t.Language = "php" },
+		"endpoint": func(t *monitoringv2alpha1.TargetSpec) { t.Otel = nil },
+		"grpc": func(t *monitoringv2alpha1.TargetSpec) {
+			t.Otel = &monitoringv2alpha1.OtelSpec{Endpoint: "x", Protocol: "grpc"}
+		},
+		"wrap": func(t *monitoringv2alpha1.TargetSpec) { t.PythonStartMode = monitoringv2alpha1.PythonStartModeWrap },
+		"runtime": func(t *monitoringv2alpha1.TargetSpec) {
+			t.RuntimeCheck = &monitoringv2alpha1.RuntimeCheckSpec{Enabled: true}
+		},
+	} {
+		target := valid
+		mutate(&target)
+		if err := validateOtelTarget(target); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWhatapAgentValidator_OtelTargets(t *testing.T) {
+	v := &WhatapAgentCustomValidator{client: newFakeClient(), namespace: "whatap-monitoring"}
+	ctx := context.Background()
+
+	agent := newAgent("whatap", time.Now(), "shop")
+	target := &agent.Spec.Features.Apm.Instrumentation.Targets[0]
+	target.AgentType = monitoringv2alpha1.AgentTypeOtel
+	target.Otel = &monitoringv2alpha1.OtelSpec{Endpoint: "http://collector:4318"}
+	if _, err := v.ValidateCreate(ctx, &agent); err != nil {
+		t.Fatalf("Expected a valid otel target to be admitted, got %v", err)
+	}
+
+	// There is no OpenTelemetry auto-instrumentation image for php
+	target.Language = "php"
+	if _, err := v.ValidateCreate(ctx, &agent); err == nil || !strings.Contains(err.Error(), "agentType otel supports") {
+		t.Errorf("Expected a php otel target to be rejected on create, got %v", err)
+	}
+	target.Language = "java"
+	target.Otel = nil
+	if _, err := v.ValidateUpdate(ctx, &agent, &agent); err == nil || !strings.Contains(err.Error(), "otel.endpoint") {
+		t.Errorf("Expected an otel target without endpoint to be rejected on update, got %v", err)
+	}
+}
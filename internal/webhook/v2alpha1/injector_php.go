@@ -0,0 +1,81 @@
+package v2alpha1
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func injectPhpEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, version string, logger logr.Logger) []corev1.EnvVar {
+	logger.Info("Configuring PHP APM agent injection", "version", version)
+
+	// PHP 전용 환경변수 (CR 기반, target envs 오버라이드 지원)
+	licenseEnv := getWhatapLicenseEnvVar(cr, target)
+	licenseEnv.Name = EnvPhpLicense
+
+	hostEnv := getWhatapHostEnvVar(cr, target)
+	hostEnv.Name = EnvPhpWhatapHost
+
+	portEnv := getWhatapPortEnvVar(cr, target)
+	portEnv.Name = EnvPhpWhatapPort
+
+	envVars := []corev1.EnvVar{
+		// Whatap 서버 연결 정보
+		licenseEnv,
+		hostEnv,
+		portEnv,
+
+		// PHP 에이전트 경로 설정
+		{Name: EnvWhatapHome, Value: ValWhatapHome},
+		// Whatap 설정
+		{Name: EnvWhatapMicroEnabled, Value: ValTrue},
+
+		// Kubernetes 메타데이터
+		{Name: EnvNodeIP, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}}},
+		{Name: EnvNodeName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+		{Name: EnvPodName, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+	}
+
+	// PHP_INI_SCAN_DIR에 whatap extension ini 경로 추가 (기존 사용자 값 보존)
+	scanDirForced := false
+	if scanDir, ok := phpIniScanDirEnvVar(container.Env, ValPhpIniDir, logger); ok {
+		envVars = append(envVars, scanDir)
+		scanDirForced = true
+	}
+
+	// 와탭 소유 연결/설정 ENV는 기존 동일 키가 있어도 operator 값으로 강제 override 한다.
+	// PHP_INI_SCAN_DIR은 기존 값에 whatap 경로를 덧붙인 값이므로 함께 override 한다.
+	return combineEnvVars(container.Env, envVars, func(name string) bool {
+		if name == EnvPhpIniScanDir {
+			return scanDirForced
+		}
+		_, ok := phpForceEnvNames[name]
+		return ok
+	})
+}
+
+// phpIniScanDirEnvVar returns the PHP_INI_SCAN_DIR value that makes PHP load the whatap
+// extension ini from iniDir. An existing user value is kept and iniDir is appended to it;
+// without one, the leading ':' keeps PHP's compiled-in scan dir in effect. The bool is false
+// when the user sets PHP_INI_SCAN_DIR via ConfigMap/Secret, in which case it is left alone.
+func phpIniScanDirEnvVar(existing []corev1.EnvVar, iniDir string, logger logr.Logger) (corev1.EnvVar, bool) {
+	for _, env := range existing {
+		if env.Name != EnvPhpIniScanDir {
+			continue
+		}
+		if env.ValueFrom != nil {
+			logger.Info("PHP_INI_SCAN_DIR is set via ConfigMap/Secret. Skipping injection.")
+			return corev1.EnvVar{}, false
+		}
+		for _, dir := range strings.Split(env.Value, ":") {
+			if dir == iniDir {
+				return corev1.EnvVar{Name: EnvPhpIniScanDir, Value: env.Value}, true
+			}
+		}
+		logger.Info("Appending to existing PHP_INI_SCAN_DIR", "original", env.Value)
+		return corev1.EnvVar{Name: EnvPhpIniScanDir, Value: env.Value + ":" + iniDir}, true
+	}
+	return corev1.EnvVar{Name: EnvPhpIniScanDir, Value: ":" + iniDir}, true
+}
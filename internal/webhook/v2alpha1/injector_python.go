@@ -1,9 +1,14 @@
 package v2alpha1
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/go-logr/logr"
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/whatap/whatap-operator/internal/registry"
 )
 
 func injectPythonEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, version string, logger logr.Logger) []corev1.EnvVar {
@@ -131,56 +136,42 @@ func getPythonAppConfig(envs []corev1.EnvVar) (string, string, string) {
 	return appName, appProcessName, okind
 }
 
-func wrapPythonCommand(container *corev1.Container, logger logr.Logger) {
-	// If the container has a command, prepend the whatap-start-agent
-	// If not, we can't do much unless we know the entrypoint.
-	// But usually for Python containers, Command or Args are defined.
-
-	// Case 1: Command is defined
-	if len(container.Command) > 0 {
-		originalCommand := container.Command
-		originalArgs := container.Args
-
-		// Construct new command
-		// whatap-start-agent [original command] [original args]
-		// But wait, whatap-start-agent is a script.
-		// /whatap-agent/bin/whatap-start-agent
-		// It executes the passed command with instrumentation.
-
-		newArgs := make([]string, 0)
-		newArgs = append(newArgs, originalCommand...)
-		newArgs = append(newArgs, originalArgs...)
-
-		logger.Info("Wrapping Python application command with whatap-start-agent", "originalCommand", originalCommand, "originalArgs", originalArgs)
-
-		container.Command = []string{"/whatap-agent/bin/whatap-start-agent"}
-		container.Args = newArgs
-	} else {
-		// Case 2: Only Args defined (Entrypoint is implicit or in image)
-		// We can try to prepend to Args if we assume Entrypoint is python
-		// But if Entrypoint is a script, it might work too.
-		// safest is if we can set Command.
-		// If Command is empty, k8s uses Image Entrypoint.
-		// We can't wrap invisible Entrypoint easily without changing Command.
-		// Let's assume the user provided Command or we can just prepend to Args if Command is empty?
-		// No, if Command is empty, we set Command to whatap-start-agent and move original Args to new Args.
-		// BUT we don't know the original Entrypoint!
-		// If we overwrite Command, original Entrypoint is ignored.
-		// So we can only support cases where we know what to run.
-
-		// For now, let's only support cases where we explicitly wrap if we can guess.
-		// Or, if this function is called, we assume the user accepts wrapper.
-		// But without knowing original Entrypoint, we risk breaking it.
-
-		// However, in many Python Docker images, Entrypoint is `python` or undefined.
-		// If undefined, we can't guess.
-
-		// Current strategy: Only modify if we can see the command.
-		// This might be limited.
-		// (Legacy behavior preserved)
+// wrapPythonCommands runs the instrumented containers of pod through whatap-start-agent
+// (TargetSpec.PythonStartMode "wrap"). Containers without a command run the image
+// ENTRYPOINT/CMD (see containerArgv). A container
+// whose command cannot be determined keeps its command and relies on sitecustomize; the
+// returned warnings explain why.
+func wrapPythonCommands(ctx context.Context, pod *corev1.Pod, target monitoringv2alpha1.TargetSpec, containerNames []string, images registry.ConfigResolver, logger logr.Logger) []string {
+	var warnings []string
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if ok, _ := shouldInstrumentContainer(*container, target.ContainerSelector, containerNames); !ok || isPythonWrapped(container) {
+			continue
+		}
+		argv, err := containerArgv(ctx, container, pod.Annotations, images)
+		if err != nil {
+			warning := fmt.Sprintf("container %s: cannot wrap the command with whatap-start-agent, relying on sitecustomize: %v", container.Name, err)
+			logger.Info("Failed to wrap Python application command", "container", container.Name, "error", err.Error())
+			warnings = append(warnings, warning)
+			continue
+		}
+		logger.Info("Wrapping Python application command with whatap-start-agent", "container", container.Name, "command", argv)
+		container.Command = []string{ValPythonStartAgent}
+		container.Args = argv
+	}
+	return warnings
+}
+
+// isPythonWrapped reports whether container already runs through whatap-start-agent.
+func isPythonWrapped(container *corev1.Container) bool {
+	return len(container.Command) == 1 && container.Command[0] == ValPythonStartAgent
+}
+
+// unwrapPythonCommand restores the command wrapPythonCommands replaced. The container
+// runs the same argv, now given as its command.
+func unwrapPythonCommand(container *corev1.Container) {
+	if isPythonWrapped(container) {
+		container.Command = container.Args
+		container.Args = nil
 	}
-	// Actually, looking at previous logic (lines 324-343 of original), it handled:
-	// if len(container.Command) > 0 { ... }
-	// It did NOT handle empty Command.
-	// We preserve this logic.
 }
@@ -0,0 +1,129 @@
+package v2alpha1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+// fakeImages returns the configs of known images.
+type fakeImages map[string]*registry.ImageConfig
+
+func (f fakeImages) ImageConfig(_ context.Context, image string) (*registry.ImageConfig, error) {
+	if config, ok := f[image]; ok {
+		return config, nil
+	}
+	return nil, errors.New("manifest unknown")
+}
+
+func TestWrapPythonCommands(t *testing.T) {
+	images := fakeImages{
+		"shop/api:1.0": {Entrypoint: []string{"/docker-entrypoint.sh"}, Cmd: []string{"gunicorn", "app:app"}},
+	}
+	target := monitoringv2alpha1.TargetSpec{Name: "py", Enabled: true, Language: "python", PythonStartMode: monitoringv2alpha1.PythonStartModeWrap}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "explicit", Image: "shop/api:1.0", Command: []string{"python", "-I"}, Args: []string{"main.py"}},
+			{Name: "image", Image: "shop/api:1.0"},
+			{Name: "args", Image: "shop/api:1.0", Args: []string{"uwsgi", "--ini", "app.ini"}},
+			{Name: "private", Image: "registry.local/worker:2.0"},
+		}},
+	}
+
+	warnings := wrapPythonCommands(context.Background(), pod, target, nil, images, logr.Discard())
+
+	for i, want := range []string{
+		"python -I main.py",
+		"/docker-entrypoint.sh gunicorn app:app",
+		"/docker-entrypoint.sh uwsgi --ini app.ini",
+	} {
+		c := pod.Spec.Containers[i]
+		if !isPythonWrapped(&c) || strings.Join(c.Args, " ") != want {
+			t.Errorf("container %s: expected whatap-start-agent %s, got %v %v", c.Name, want, c.Command, c.Args)
+		}
+	}
+	if private := pod.Spec.Containers[3]; len(private.Command) != 0 || len(warnings) != 1 || !strings.Contains(warnings[0], "private") {
+		t.Errorf("Expected the private image to keep its command with a warning, got %v %v", private.Command, warnings)
+	}
+
+	// Wrapping is idempotent and stripping restores the same argv
+	wrapPythonCommands(context.Background(), pod, target, nil, images, logr.Discard())
+	if args := pod.Spec.Containers[0].Args; strings.Join(args, " ") != "python -I main.py" {
+		t.Errorf("Expected a wrapped command not to be wrapped again, got %v", args)
+	}
+	stripInjectionArtifacts(pod)
+	if c := pod.Spec.Containers[1]; strings.Join(c.Command, " ") != "/docker-entrypoint.sh gunicorn app:app" || len(c.Args) != 0 {
+		t.Errorf("Expected the unwrapped container to run the same argv, got %v %v", c.Command, c.Args)
+	}
+}
+
+func TestWrapPythonCommands_HintAnnotations(t *testing.T) {
+	target := monitoringv2alpha1.TargetSpec{Name: "py", Enabled: true, Language: "python", PythonStartMode: monitoringv2alpha1.PythonStartModeWrap}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationImageCmd: `["gunicorn", "-c", "conf.py", "app:app"]`,
+		}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "registry.local/api:1.0"}}},
+	}
+
+	// The hint is used without asking the registry
+	if warnings := wrapPythonCommands(context.Background(), pod, target, nil, nil, logr.Discard()); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if args := pod.Spec.Containers[0].Args; strings.Join(args, " ") != "gunicorn -c conf.py app:app" {
+		t.Errorf("Expected the hinted CMD to be wrapped, got %v", args)
+	}
+
+	pod.Spec.Containers[0] = corev1.Container{Name: "app", Image: "registry.local/api:1.0"}
+	pod.Annotations[AnnotationImageCmd] = "gunicorn app:app"
+	if warnings := wrapPythonCommands(context.Background(), pod, target, nil, nil, logr.Discard()); len(warnings) != 1 {
+		t.Errorf("Expected a warning for a hint that is not a JSON array, got %v", warnings)
+	}
+}
+
+// deadlineImages records the deadline of every lookup and fails it.
+type deadlineImages struct {
+	deadlines []time.Time
+}
+
+func (d *deadlineImages) ImageConfig(ctx context.Context, _ string) (*registry.ImageConfig, error) {
+	deadline, _ := ctx.Deadline()
+	d.deadlines = append(d.deadlines, deadline)
+	return nil, context.DeadlineExceeded
+}
+
+func TestMutatePod_ImageLookupsShareOneDeadline(t *testing.T) {
+	agent := newAgent("whatap", time.Now(), "shop")
+	target := &agent.Spec.Features.Apm.Instrumentation.Targets[0]
+	target.Language = "python"
+	target.PythonStartMode = monitoringv2alpha1.PythonStartModeWrap
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "api", Image: "registry.local/api:1.0"},
+			{Name: "worker", Image: "registry.local/worker:1.0"},
+			{Name: "beat", Image: "registry.local/beat:1.0"},
+		}},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}}
+	images := &deadlineImages{}
+
+	mutatePod(context.Background(), pod, []monitoringv2alpha1.WhatapAgent{agent}, ns, nil, images, logr.Discard())
+	if len(images.deadlines) < 3 {
+		t.Fatalf("Expected a lookup per container, got %d", len(images.deadlines))
+	}
+	for _, deadline := range images.deadlines {
+		if deadline.IsZero() || !deadline.Equal(images.deadlines[0]) {
+			t.Fatalf("Expected every lookup to share one deadline, got %v", images.deadlines)
+		}
+	}
+}
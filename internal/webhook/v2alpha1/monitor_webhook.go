@@ -0,0 +1,244 @@
+package v2alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+)
+
+// relabelActions are the Prometheus relabel actions the OpenAgent understands
+var relabelActions = toNameSet("replace", "keep", "drop", "hashmod", "labelmap", "labeldrop", "labelkeep",
+	"lowercase", "uppercase", "keepequal", "dropequal")
+
+// relabelActionsWithTargetLabel are the actions that fail without target_label
+var relabelActionsWithTargetLabel = toNameSet("replace", "hashmod", "lowercase", "uppercase", "keepequal", "dropequal")
+
+// SetupMonitorWebhooksWithManager registers the validating webhooks of WhatapPodMonitor,
+// WhatapServiceMonitor and WhatapStaticEndpoint. namespace is where the OpenAgent runs:
+// Secrets referenced without a namespace are looked up there.
+func SetupMonitorWebhooksWithManager(mgr ctrl.Manager, namespace string) error {
+	validator := &MonitorCustomValidator{client: mgr.GetClient(), namespace: namespace}
+	for path, obj := range map[string]runtime.Object{
+		"/whatap-validation--v2alpha1-whatappodmonitor":     &monitoringv2alpha1.WhatapPodMonitor{},
+		"/whatap-validation--v2alpha1-whatapservicemonitor": &monitoringv2alpha1.WhatapServiceMonitor{},
+		"/whatap-validation--v2alpha1-whatapstaticendpoint": &monitoringv2alpha1.WhatapStaticEndpoint{},
+	} {
+		if err := ctrl.NewWebhookManagedBy(mgr).
+			For(obj).
+			WithValidator(validator).
+			WithValidatorCustomPath(path).
+			Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MonitorCustomValidator rejects monitor CRs the OpenAgent would fail to load, and warns
+// about the Secrets they reference that do not exist (yet).
+type MonitorCustomValidator struct {
+	client    client.Client
+	namespace string
+}
+
+var _ webhook.CustomValidator = &MonitorCustomValidator{}
+
+func (v *MonitorCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+func (v *MonitorCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj)
+}
+
+func (v *MonitorCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *MonitorCustomValidator) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	var kind string
+	var relabelConfigs []monitoringv2alpha1.MetricRelabelConfig
+	var endpoints []monitoringv2alpha1.OpenAgentEndpoint
+	switch m := obj.(type) {
+	case *monitoringv2alpha1.WhatapPodMonitor:
+		kind, relabelConfigs, endpoints = "WhatapPodMonitor", m.Spec.RelabelConfigs, m.Spec.Endpoints
+	case *monitoringv2alpha1.WhatapServiceMonitor:
+		kind, relabelConfigs, endpoints = "WhatapServiceMonitor", m.Spec.RelabelConfigs, m.Spec.Endpoints
+	case *monitoringv2alpha1.WhatapStaticEndpoint:
+		kind, relabelConfigs, endpoints = "WhatapStaticEndpoint", m.Spec.RelabelConfigs, m.Spec.Endpoints
+	default:
+		return nil, fmt.Errorf("expected a monitor object but got %T", obj)
+	}
+	monitor := obj.(client.Object)
+	whatapWebhookLogger.Info("Validation for "+kind, "namespace", monitor.GetNamespace(), "name", monitor.GetName())
+
+	problems := validateRelabelConfigs("spec.relabelConfigs", relabelConfigs)
+	problems = append(problems, validateScrapeEndpoints("spec.endpoints", endpoints)...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s %q is invalid: %s", kind, monitor.GetName(), strings.Join(problems, "; "))
+	}
+	return missingSecretWarnings(ctx, v.client, v.namespace, "spec.endpoints", endpoints), nil
+}
+
+// validateOpenAgentTargets applies the monitor checks to the inline OpenAgent targets of
+// a WhatapAgent, and checks the selectors of prometheusOperatorCompat and the interval
+// and relabelings of annotationDiscovery.
+func validateOpenAgentTargets(whatapagent *monitoringv2alpha1.WhatapAgent) error {
+	var problems []string
+	for i, target := range whatapagent.Spec.Features.OpenAgent.Targets {
+		path := fmt.Sprintf("spec.features.openAgent.targets[%d]", i)
+		problems = append(problems, validateRelabelConfigs(path+".relabelConfigs", target.RelabelConfigs)...)
+		problems = append(problems, validateScrapeEndpoints(path+".endpoints", target.Endpoints)...)
+	}
+	if compat := whatapagent.Spec.Features.OpenAgent.PrometheusOperatorCompat; compat != nil {
+		if compat.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(compat.Selector); err != nil {
+				problems = append(problems, fmt.Sprintf("spec.features.openAgent.prometheusOperatorCompat.selector: %v", err))
+			}
+		}
+		problems = append(problems, validateNamespaceSelector("spec.features.openAgent.prometheusOperatorCompat.namespaceSelector", compat.NamespaceSelector)...)
+	}
+	if discovery := whatapagent.Spec.Features.OpenAgent.AnnotationDiscovery; discovery != nil {
+		path := "spec.features.openAgent.annotationDiscovery"
+		problems = append(problems, validateNamespaceSelector(path+".namespaceSelector", discovery.NamespaceSelector)...)
+		if discovery.Interval != "" {
+			if _, err := model.ParseDuration(discovery.Interval); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.interval: %v (use a unit, e.g. 30s or 1m)", path, err))
+			}
+		}
+		problems = append(problems, validateRelabelConfigs(path+".metricRelabelConfigs", discovery.MetricRelabelConfigs)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid OpenAgent targets: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateNamespaceSelector checks that ns converts to a valid label selector.
+func validateNamespaceSelector(path string, ns *monitoringv2alpha1.NamespaceSelector) []string {
+	if ns == nil {
+		return nil
+	}
+	selector := &metav1.LabelSelector{MatchLabels: ns.MatchLabels}
+	for _, e := range ns.MatchExpressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key: e.Key, Operator: metav1.LabelSelectorOperator(e.Operator), Values: e.Values,
+		})
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		return []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+	return nil
+}
+
+// openAgentTargetWarnings reports the missing Secrets of the inline OpenAgent targets.
+func openAgentTargetWarnings(ctx context.Context, c client.Client, namespace string, whatapagent *monitoringv2alpha1.WhatapAgent) admission.Warnings {
+	var warnings admission.Warnings
+	for i, target := range whatapagent.Spec.Features.OpenAgent.Targets {
+		path := fmt.Sprintf("spec.features.openAgent.targets[%d].endpoints", i)
+		warnings = append(warnings, missingSecretWarnings(ctx, c, namespace, path, target.Endpoints)...)
+	}
+	return warnings
+}
+
+// validateScrapeEndpoints checks the scrape intervals and metric relabelings of endpoints.
+func validateScrapeEndpoints(path string, endpoints []monitoringv2alpha1.OpenAgentEndpoint) []string {
+	var problems []string
+	for i, endpoint := range endpoints {
+		if endpoint.Interval != "" {
+			if _, err := model.ParseDuration(endpoint.Interval); err != nil {
+				problems = append(problems, fmt.Sprintf("%s[%d].interval: %v (use a unit, e.g. 30s or 1m)", path, i, err))
+			}
+		}
+		problems = append(problems, validateRelabelConfigs(fmt.Sprintf("%s[%d].metricRelabelConfigs", path, i), endpoint.MetricRelabelConfigs)...)
+	}
+	return problems
+}
+
+// validateRelabelConfigs checks configs the way Prometheus does when it loads them: the
+// action must be known, the regex must compile as an anchored RE2 expression, and actions
+// writing a label need target_label (and hashmod a modulus).
+func validateRelabelConfigs(path string, configs []monitoringv2alpha1.MetricRelabelConfig) []string {
+	var problems []string
+	for i, rc := range configs {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		action := strings.ToLower(rc.Action)
+		if action == "" {
+			action = "replace"
+		}
+		if _, ok := relabelActions[action]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.action: unknown relabel action %q", at, rc.Action))
+			continue
+		}
+		if rc.Regex != "" {
+			if _, err := regexp.Compile("^(?:" + rc.Regex + ")$"); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.regex: %v", at, err))
+			}
+		}
+		if _, ok := relabelActionsWithTargetLabel[action]; ok && rc.TargetLabel == "" {
+			problems = append(problems, fmt.Sprintf("%s.target_label: required for action %s", at, action))
+		}
+		if action == "hashmod" && rc.Modulus == 0 {
+			problems = append(problems, fmt.Sprintf("%s.modulus: required for action hashmod", at))
+		}
+	}
+	return problems
+}
+
+// missingSecretWarnings returns a warning for every Secret (or Secret key) referenced by
+// endpoints that does not exist. TLS Secrets are always mounted from namespace; basic
+// auth and authorization Secrets default to it.
+func missingSecretWarnings(ctx context.Context, c client.Client, namespace, path string, endpoints []monitoringv2alpha1.OpenAgentEndpoint) admission.Warnings {
+	var warnings admission.Warnings
+	check := func(field string, sel *monitoringv2alpha1.SecretKeySelector, mountedFromNamespace bool) {
+		if sel == nil || sel.Name == "" {
+			return
+		}
+		ns := sel.Namespace
+		if ns == "" || mountedFromNamespace {
+			ns = namespace
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: sel.Name}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				warnings = append(warnings, fmt.Sprintf("%s: Secret %s/%s not found", field, ns, sel.Name))
+			} else {
+				whatapWebhookLogger.Error(err, "Failed to check Secret", "namespace", ns, "name", sel.Name)
+			}
+			return
+		}
+		if _, ok := secret.Data[sel.Key]; !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: Secret %s/%s has no key %q", field, ns, sel.Name, sel.Key))
+		}
+	}
+	for i, endpoint := range endpoints {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		if endpoint.BasicAuth != nil {
+			check(at+".basicAuth.username", endpoint.BasicAuth.Username, false)
+			check(at+".basicAuth.password", endpoint.BasicAuth.Password, false)
+		}
+		if endpoint.Authorization != nil {
+			check(at+".authorization.credentialsSecret", endpoint.Authorization.CredentialsSecret, false)
+		}
+		if endpoint.TLSConfig != nil {
+			check(at+".tlsConfig.caSecret", endpoint.TLSConfig.CASecret, true)
+			check(at+".tlsConfig.certSecret", endpoint.TLSConfig.CertSecret, true)
+			check(at+".tlsConfig.keySecret", endpoint.TLSConfig.KeySecret, true)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,114 @@
+package v2alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateRelabelConfigs(t *testing.T) {
+	valid := []monitoringv2alpha1.MetricRelabelConfig{
+		{SourceLabels: []string{"__name__"}, Regex: "go_.*", Action: "drop"},
+		{SourceLabels: []string{"pod"}, TargetLabel: "instance", Action: "Replace"},
+		{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 4, Action: "hashmod"},
+		{Regex: "__meta_kubernetes_pod_label_(.+)", Action: "labelmap"},
+	}
+	if problems := validateRelabelConfigs("relabelConfigs", valid); len(problems) != 0 {
+		t.Errorf("Expected valid relabel configs, got %v", problems)
+	}
+
+	for name, rc := range map[string]monitoringv2alpha1.MetricRelabelConfig{
+		"regex":        {Regex: "go_(", Action: "keep"},
+		"action":       {Action: "rename"},
+		"target_label": {SourceLabels: []string{"pod"}, Action: "lowercase"},
+		"modulus":      {SourceLabels: []string{"pod"}, TargetLabel: "shard", Action: "hashmod"},
+	} {
+		problems := validateRelabelConfigs("relabelConfigs", []monitoringv2alpha1.MetricRelabelConfig{rc})
+		if len(problems) != 1 || !strings.Contains(problems[0], name) {
+			t.Errorf("%s: expected one problem naming the field, got %v", name, problems)
+		}
+	}
+}
+
+func TestMonitorCustomValidator(t *testing.T) {
+	c := newFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "scrape-auth"},
+		Data:       map[string][]byte{"username": []byte("whatap")},
+	})
+	v := &MonitorCustomValidator{client: c, namespace: "whatap-monitoring"}
+	ctx := context.Background()
+
+	monitor := &monitoringv2alpha1.WhatapServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "orders"},
+		Spec: monitoringv2alpha1.WhatapServiceMonitorSpec{
+			Endpoints: []monitoringv2alpha1.OpenAgentEndpoint{{
+				Port:     "metrics",
+				Interval: "30s",
+				BasicAuth: &monitoringv2alpha1.BasicAuthConfig{
+					Username: &monitoringv2alpha1.SecretKeySelector{Name: "scrape-auth", Key: "username", Namespace: "shop"},
+					Password: &monitoringv2alpha1.SecretKeySelector{Name: "scrape-auth", Key: "password", Namespace: "shop"},
+				},
+				TLSConfig: &monitoringv2alpha1.TLSConfig{
+					CASecret: &monitoringv2alpha1.SecretKeySelector{Name: "orders-ca", Key: "ca.crt"},
+				},
+			}},
+		},
+	}
+	warnings, err := v.ValidateCreate(ctx, monitor)
+	if err != nil {
+		t.Fatalf("Expected the monitor to be admitted, got %v", err)
+	}
+	if len(warnings) != 2 ||
+		!strings.Contains(warnings[0], `shop/scrape-auth has no key "password"`) ||
+		!strings.Contains(warnings[1], "whatap-monitoring/orders-ca not found") {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	// An interval without a unit and a broken metric relabeling are rejected
+	monitor.Spec.Endpoints[0].Interval = "30"
+	monitor.Spec.Endpoints[0].MetricRelabelConfigs = []monitoringv2alpha1.MetricRelabelConfig{{Regex: "[", Action: "drop"}}
+	_, err = v.ValidateUpdate(ctx, monitor, monitor)
+	if err == nil || !strings.Contains(err.Error(), "spec.endpoints[0].interval") ||
+		!strings.Contains(err.Error(), "spec.endpoints[0].metricRelabelConfigs[0].regex") {
+		t.Errorf("Expected interval and regex errors, got %v", err)
+	}
+}
+
+func TestValidateOpenAgentTargets(t *testing.T) {
+	agent := newAgent("whatap", metav1.Now().Time)
+	agent.Spec.Features.OpenAgent.Targets = []monitoringv2alpha1.OpenAgentTargetSpec{{
+		TargetName:     "node-exporter",
+		Type:           "StaticEndpoints",
+		RelabelConfigs: []monitoringv2alpha1.MetricRelabelConfig{{Action: "keepequal"}},
+		Endpoints:      []monitoringv2alpha1.OpenAgentEndpoint{{Address: "10.0.0.5:9100", Interval: "1m30s"}},
+	}}
+	err := validateOpenAgentTargets(&agent)
+	if err == nil || !strings.Contains(err.Error(), "spec.features.openAgent.targets[0].relabelConfigs[0].target_label") {
+		t.Errorf("Expected a target_label error, got %v", err)
+	}
+
+	agent.Spec.Features.OpenAgent.Targets[0].RelabelConfigs[0].TargetLabel = "instance"
+	if err := validateOpenAgentTargets(&agent); err != nil {
+		t.Errorf("Expected valid targets, got %v", err)
+	}
+
+	agent.Spec.Features.OpenAgent.PrometheusOperatorCompat = &monitoringv2alpha1.PrometheusOperatorCompatSpec{
+		Enabled: true,
+		NamespaceSelector: &monitoringv2alpha1.NamespaceSelector{
+			MatchExpressions: []monitoringv2alpha1.LabelSelectorRequirement{{Key: "team", Operator: "In"}},
+		},
+	}
+	if err := validateOpenAgentTargets(&agent); err == nil || !strings.Contains(err.Error(), "prometheusOperatorCompat.namespaceSelector") {
+		t.Errorf("Expected a namespaceSelector error, got %v", err)
+	}
+
+	agent.Spec.Features.OpenAgent.PrometheusOperatorCompat = nil
+	agent.Spec.Features.OpenAgent.AnnotationDiscovery = &monitoringv2alpha1.AnnotationDiscoverySpec{Enabled: true, Interval: "30"}
+	if err := validateOpenAgentTargets(&agent); err == nil || !strings.Contains(err.Error(), "annotationDiscovery.interval") {
+		t.Errorf("Expected an annotationDiscovery interval error, got %v", err)
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, lang, version string, logger logr.Logger) []corev1.Container {
+// createAgentInitContainers returns the init container laying down the agent. appName, when
+// set, is the application name the Python and Node.js bootstrap writes into whatap.conf
+// instead of the app_name of target.Envs (see initAppName).
+func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, lang, version, appName string, logger logr.Logger) []corev1.Container {
 	baseVolumeMount := corev1.VolumeMount{
 		Name:      VolumeNameWhatapAgent,
 		MountPath: MountPathWhatapAgent,
@@ -41,6 +44,9 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		securityContext = &corev1.SecurityContext{
 			RunAsNonRoot: boolPtr(true),
 		}
+		if target.UsesOtel() {
+			securityContext.RunAsUser = int64Ptr(otelInitUser)
+		}
 	} else {
 		// Use only provided fields. If RunAsNonRoot=true without RunAsUser, leave RunAsUser nil for OpenShift compatibility
 		securityContext = &corev1.SecurityContext{}
@@ -67,11 +73,30 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		resources = *cr.Spec.Features.Apm.Instrumentation.InitContainerResources
 	}
 
+	if target.UsesOtel() {
+		logger.Info("Using OpenTelemetry auto-instrumentation init container", "language", lang, "version", version)
+
+		return []corev1.Container{
+			{
+				Name:            InitContainerName,
+				Image:           getAgentImage(target, cr),
+				ImagePullPolicy: cr.PullPolicy(corev1.PullAlways),
+				Command:         otelInitCommand(lang),
+				VolumeMounts:    []corev1.VolumeMount{baseVolumeMount},
+				SecurityContext: securityContext,
+				Resources:       resources,
+			},
+		}
+	}
+
 	if lang == "python" {
 		logger.Info("Using Python APM bootstrap init container with new structure", "version", version)
 
 		// Get Python app configuration
-		appName, appProcessName, OKIND := getPythonAppConfig(target.Envs)
+		staticAppName, appProcessName, OKIND := getPythonAppConfig(target.Envs)
+		if appName == "" {
+			appName = staticAppName
+		}
 
 		// Prepare environment variables for Python InitContainer
 		envVars := []corev1.EnvVar{
@@ -86,8 +111,8 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		return []corev1.Container{
 			{
 				Name:            InitContainerName,
-				Image:           getAgentImage(target, lang, version),
-				ImagePullPolicy: corev1.PullAlways,
+				Image:           getAgentImage(target, cr),
+				ImagePullPolicy: cr.PullPolicy(corev1.PullAlways),
 				Env:             envVars,
 				VolumeMounts:    []corev1.VolumeMount{baseVolumeMount},
 				SecurityContext: securityContext,
@@ -109,8 +134,8 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		return []corev1.Container{
 			{
 				Name:            InitContainerName,
-				Image:           getAgentImage(target, lang, version),
-				ImagePullPolicy: corev1.PullAlways,
+				Image:           getAgentImage(target, cr),
+				ImagePullPolicy: cr.PullPolicy(corev1.PullAlways),
 				Env:             envVars,
 				VolumeMounts:    []corev1.VolumeMount{baseVolumeMount},
 				SecurityContext: securityContext,
@@ -123,7 +148,10 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		logger.Info("Using Node.js APM init container", "version", version)
 
 		// Get Node.js app configuration
-		appName, appProcessName, OKIND := getNodejsAppConfig(target.Envs)
+		staticAppName, appProcessName, OKIND := getNodejsAppConfig(target.Envs)
+		if appName == "" {
+			appName = staticAppName
+		}
 
 		// Prepare environment variables for Node.js InitContainer
 		envVars := []corev1.EnvVar{
@@ -138,8 +166,8 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 		return []corev1.Container{
 			{
 				Name:            InitContainerName,
-				Image:           getAgentImage(target, lang, version),
-				ImagePullPolicy: corev1.PullAlways,
+				Image:           getAgentImage(target, cr),
+				ImagePullPolicy: cr.PullPolicy(corev1.PullAlways),
 				Env:             envVars,
 				VolumeMounts:    []corev1.VolumeMount{baseVolumeMount},
 				SecurityContext: securityContext,
@@ -152,8 +180,8 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 	return []corev1.Container{
 		{
 			Name:            InitContainerName,
-			Image:           getAgentImage(target, lang, version),
-			ImagePullPolicy: corev1.PullAlways,
+			Image:           getAgentImage(target, cr),
+			ImagePullPolicy: cr.PullPolicy(corev1.PullAlways),
 			VolumeMounts:    []corev1.VolumeMount{baseVolumeMount},
 			SecurityContext: securityContext,
 			Resources:       resources,
@@ -164,13 +192,21 @@ func createAgentInitContainers(target monitoringv2alpha1.TargetSpec, cr monitori
 func injectLanguageSpecificEnvVars(container corev1.Container, target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent, lang, version string, logger logr.Logger) []corev1.EnvVar {
 	var envs []corev1.EnvVar
 
-	switch lang {
-	case "java":
+	switch {
+	case target.UsesOtel():
+		envs = injectOtelEnvVars(container, target, lang, logger)
+	case lang == "java":
 		envs = injectJavaEnvVars(container, target, cr, logger)
-	case "python":
+	case lang == "python":
 		envs = injectPythonEnvVars(container, target, cr, version, logger)
-	case "nodejs":
+	case lang == "nodejs":
 		envs = injectNodejsEnvVars(container, target, cr, version, logger)
+	case lang == "php":
+		envs = injectPhpEnvVars(container, target, cr, version, logger)
+	case lang == "dotnet":
+		envs = injectDotnetEnvVars(container, target, cr, version, logger)
+	case lang == "golang":
+		envs = injectGolangEnvVars(container, target, cr, version, logger)
 	default:
 		// Other languages might just need basic Kubernetes envs + standard whatap envs if implemented
 		// For now, if not specialized, just return original + basic
@@ -197,11 +233,12 @@ func injectBasicKubernetesEnvVars(container corev1.Container) []corev1.EnvVar {
 // Deployment 처리
 
 // PodSpec 수정 (자동 주입 핵심 로직)
-func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapAgent, target monitoringv2alpha1.TargetSpec, namespace string, logger logr.Logger) {
+// containerNames(어노테이션)가 비어있지 않으면 해당 이름의 컨테이너에만, 아니면 target.ContainerSelector에
+// 매칭되는 컨테이너(잘 알려진 사이드카 제외)에만 에이전트를 주입한다.
+func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapAgent, target monitoringv2alpha1.TargetSpec, namespace string, containerNames []string, logger logr.Logger) {
 	lang := target.Language
-	version := target.WhatapApmVersions[lang]
-	if version == "" {
-		version = "latest"
+	version := target.AgentVersion()
+	if version == monitoringv2alpha1.DefaultApmInitVersion {
 		logger.Info("No explicit version specified; defaulting to 'latest'", "language", lang, "target", target.Name)
 	}
 
@@ -231,7 +268,7 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 	}
 
 	// 1️⃣ InitContainer - 에이전트 복사
-	initContainers := createAgentInitContainers(target, cr, lang, version, logger)
+	initContainers := createAgentInitContainers(target, cr, lang, version, initAppName(podSpec, target, containerNames), logger)
 
 	// Merge custom-config and/or plugin file copies into the agent init container
 	// (avoids spawning a separate alpine init container). Both inputs are optional and
@@ -240,7 +277,8 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 	//   2) the agent image's own /init.sh              (lays down the agent files)
 	//   3) copy plugin files into $WHATAP_HOME/plugin/ (post-init, so they are not
 	//      clobbered by the agent layout produced in step 2)
-	if (target.Config.Mode == "custom" && target.Config.ConfigMapRef != nil) || target.Config.PluginConfigMapRef != nil {
+	// whatap.conf and plugins only apply to the WhaTap agent.
+	if !target.UsesOtel() && ((target.Config.Mode == "custom" && target.Config.ConfigMapRef != nil) || target.Config.PluginConfigMapRef != nil) {
 		var preInitSteps, postInitSteps []string
 
 		// volumeExists reports whether the Pod already defines a volume with the
@@ -307,20 +345,19 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 		// 2) Agent plugin files via ConfigMap. Every entry is copied into
 		//    $WHATAP_HOME/plugin/ (e.g. TraceHelperEnd.x).
 		if target.Config.PluginConfigMapRef != nil {
-			const pluginVolume = "whatap-plugin-volume"
 			const pluginMountPath = "/whatap-plugin"
 			pluginDir := MountPathWhatapAgent + "/plugin"
-			if volumeExists(pluginVolume) {
+			if volumeExists(pluginVolumeName) {
 				logger.Error(nil,
 					"APM plugin volume name collides with an existing Pod volume; "+
 						"rename the conflicting volume to enable plugin injection",
-					"volumeName", pluginVolume,
+					"volumeName", pluginVolumeName,
 					"target", target.Name,
 					"configMap", target.Config.PluginConfigMapRef.Name,
 				)
 			} else {
 				podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
-					Name: pluginVolume,
+					Name: pluginVolumeName,
 					VolumeSource: corev1.VolumeSource{
 						ConfigMap: &corev1.ConfigMapVolumeSource{
 							LocalObjectReference: corev1.LocalObjectReference{
@@ -331,7 +368,7 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 				})
 				if len(initContainers) > 0 {
 					initContainers[0].VolumeMounts = append(initContainers[0].VolumeMounts, corev1.VolumeMount{
-						Name:      pluginVolume,
+						Name:      pluginVolumeName,
 						MountPath: pluginMountPath,
 					})
 				}
@@ -370,13 +407,14 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 
 	// 컨테이너별 환경변수 & 볼륨 마운트
 	for i, container := range podSpec.Containers {
+		if ok, reason := shouldInstrumentContainer(container, target.ContainerSelector, containerNames); !ok {
+			logger.Info("Skipping container for APM injection", "container", container.Name, "reason", reason)
+			continue
+		}
 		podSpec.Containers[i].Env = injectLanguageSpecificEnvVars(container, target, cr, lang, version, logger)
 
-		// Python 전용: 새로운 구조에서는 sitecustomize.py를 통한 자동 활성화 사용
-		// wrapPythonCommand는 더 이상 필요하지 않음 (OpenTelemetry 방식)
-		if lang == "python" {
-			logger.Info("Using sitecustomize.py for automatic Python APM activation, no command wrapping needed")
-		}
+		// Python 전용: sitecustomize.py(PYTHONPATH)를 통한 자동 활성화가 기본이며,
+		// pythonStartMode "wrap" 이면 mutatePod 에서 whatap-start-agent 로 명령을 감싼다.
 
 		// 공통 볼륨 마운트
 		podSpec.Containers[i].VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
@@ -385,3 +423,20 @@ func patchPodTemplateSpec(podSpec *corev1.PodSpec, cr monitoringv2alpha1.WhatapA
 		})
 	}
 }
+
+// initAppName returns the app_name env of the first container instrumented for target,
+// e.g. the name rendered from target.AppNameTemplate, so that the whatap.conf written by
+// the init container names the application the same way. It is "" when that container
+// sets none.
+func initAppName(podSpec *corev1.PodSpec, target monitoringv2alpha1.TargetSpec, containerNames []string) string {
+	for _, c := range podSpec.Containers {
+		if ok, _ := shouldInstrumentContainer(c, target.ContainerSelector, containerNames); !ok {
+			continue
+		}
+		if value, ok := findEnvValueByKeys(c.Env, EnvAppName); ok {
+			return value
+		}
+		return ""
+	}
+	return ""
+}
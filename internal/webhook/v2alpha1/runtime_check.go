@@ -0,0 +1,233 @@
+package v2alpha1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+const (
+	// runtimeCheckDir holds one <container>.disabled file per container whose runtime
+	// version is out of range
+	runtimeCheckDir = MountPathWhatapAgent + "/runtime"
+	// runtimeGateName is $0 of the gate shell; it marks gated containers
+	runtimeGateName = "whatap-runtime-gate"
+)
+
+// runtimeCheckScript detects the runtime version in the application image and compares
+// it with the supported range. It never fails: an out-of-range runtime only creates the
+// <dir>/<name>.disabled file the gate looks for. The result is also the termination
+// message, which the operator turns into a pod event.
+//
+// Arguments: language, min version, max version, container name, runtime binary (may be
+// empty), runtime dir, termination message path.
+const runtimeCheckScript = `lang=$1 min=$2 max=$3 name=$4 bin=$5 dir=$6 log=$7
+mkdir -p "$dir" && rm -f "$dir/$name.disabled"
+vcmp() {
+  v=$1 b=$2
+  while [ -n "$b" ]; do
+    bh=${b%%.*} vh=${v%%.*}
+    [ -n "$vh" ] || vh=0
+    [ "$vh" -lt "$bh" ] && { echo lt; return; }
+    [ "$vh" -gt "$bh" ] && { echo gt; return; }
+    case $b in *.*) b=${b#*.} ;; *) b= ;; esac
+    case $v in *.*) v=${v#*.} ;; *) v= ;; esac
+  done
+  echo eq
+}
+case $lang in
+java)
+  [ -n "$bin" ] || bin=$(command -v java) || bin=$JAVA_HOME/bin/java
+  raw=$(unset JAVA_TOOL_OPTIONS _JAVA_OPTIONS JDK_JAVA_OPTIONS; "$bin" -version 2>&1 | sed -n 's/.* version "\([^"]*\)".*/\1/p') ;;
+python)
+  [ -n "$bin" ] || bin=$(command -v python3 || command -v python)
+  raw=$(unset PYTHONPATH; "$bin" -V 2>&1 | sed -n 's/^Python \([^ ]*\).*/\1/p') ;;
+nodejs)
+  [ -n "$bin" ] || bin=$(command -v node || command -v nodejs)
+  raw=$(unset NODE_OPTIONS; "$bin" -v 2>/dev/null | sed -n 's/^v//p') ;;
+esac
+raw=$(echo "$raw" | sed -n 1p)
+v=$(echo "$raw" | sed 's/[^0-9][^0-9]*/./g; s/^\.//; s/\.$//')
+[ "$lang" = java ] && case $v in 1.*) v=${v#1.} ;; esac
+reason=
+if [ -z "$v" ]; then
+  msg="unknown: cannot detect the $lang version of container $name, the agent stays enabled"
+elif [ -n "$min" ] && [ "$(vcmp "$v" "$min")" = lt ]; then
+  reason="older than $min"
+elif [ -n "$max" ] && [ "$(vcmp "$v" "$max")" = gt ]; then
+  reason="newer than $max"
+fi
+if [ -n "$reason" ]; then
+  : > "$dir/$name.disabled"
+  msg="disabled: $lang $raw of container $name is $reason"
+elif [ -n "$v" ]; then
+  msg="enabled: $lang $raw of container $name"
+fi
+echo "$msg"
+{ echo "$msg" > "$log"; } 2>/dev/null
+exit 0
+`
+
+// runtimeGateScript starts the application command ("$@"), without the agent when the
+// runtime check disabled it. Arguments: language, container name, runtime dir.
+const runtimeGateScript = `lang=$1 name=$2 dir=$3
+shift 3
+if [ -f "$dir/$name.disabled" ]; then
+  echo "whatap: APM agent disabled, $lang runtime version is not supported (see the $name runtime check)" >&2
+  set -f
+  case $lang in
+  java)
+    opts=
+    for w in $JAVA_TOOL_OPTIONS; do [ "$w" = "` + ValJavaAgentOptionPrefix + ValJavaAgentPath + `" ] || opts="$opts $w"; done
+    JAVA_TOOL_OPTIONS=${opts# }
+    if [ -n "$JAVA_TOOL_OPTIONS" ]; then export JAVA_TOOL_OPTIONS; else unset JAVA_TOOL_OPTIONS; fi ;;
+  python)
+    paths= ifs=$IFS IFS=:
+    for p in $PYTHONPATH; do [ "$p" = "` + ValPythonBootstrap + `" ] || paths="$paths:$p"; done
+    IFS=$ifs PYTHONPATH=${paths#:}
+    if [ -n "$PYTHONPATH" ]; then export PYTHONPATH; else unset PYTHONPATH; fi
+    [ "$1" = "` + ValPythonStartAgent + `" ] && shift ;;
+  nodejs)
+    opts= req=
+    for w in $NODE_OPTIONS; do
+      if [ -n "$req" ]; then
+        req=
+        [ "$w" = whatap ] || opts="$opts -r $w"
+      elif [ "$w" = -r ]; then
+        req=1
+      else
+        opts="$opts $w"
+      fi
+    done
+    NODE_OPTIONS=${opts# }
+    if [ -n "$NODE_OPTIONS" ]; then export NODE_OPTIONS; else unset NODE_OPTIONS; fi ;;
+  esac
+  set +f
+fi
+exec "$@"
+`
+
+// runtimeBinaryPatterns match argv[0] of the runtime of each checked language
+var runtimeBinaryPatterns = map[string]*regexp.Regexp{
+	"java":   regexp.MustCompile(`^java$`),
+	"python": regexp.MustCompile(`^python[0-9.]*$`),
+	"nodejs": regexp.MustCompile(`^node(js)?$`),
+}
+
+// defaultRuntimeCheckResources are the resources of the check init containers when
+// runtimeCheck.resources is unset. Like the agent init container only limits are set,
+// high enough for a JVM to print its version.
+var defaultRuntimeCheckResources = corev1.ResourceRequirements{
+	Limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	},
+}
+
+// gateRuntimeVersions adds the runtime version check of target.RuntimeCheck to the
+// instrumented containers of pod: a check init container running the container's image
+// and a gate around its command, both run with runtimeCheck.shell. Containers whose
+// command cannot be determined are not checked and keep the agent; the returned warnings
+// explain why.
+func gateRuntimeVersions(ctx context.Context, pod *corev1.Pod, target monitoringv2alpha1.TargetSpec, containerNames []string, images registry.ConfigResolver, logger logr.Logger) []string {
+	check := target.RuntimeCheck
+	if check == nil || !check.Enabled {
+		return nil
+	}
+	lang := target.Language
+	binaryPattern, ok := runtimeBinaryPatterns[lang]
+	if !ok {
+		return []string{fmt.Sprintf("runtimeCheck does not support %s, the runtime version is not checked", lang)}
+	}
+	minVersion, maxVersion := normalizeRuntimeVersion(lang, check.MinVersion), normalizeRuntimeVersion(lang, check.MaxVersion)
+
+	shell := check.Shell
+	switch shell {
+	case monitoringv2alpha1.RuntimeCheckShellNone:
+		return nil
+	case "":
+		shell = monitoringv2alpha1.RuntimeCheckShellDefault
+	}
+
+	resources := defaultRuntimeCheckResources
+	if check.Resources != nil {
+		resources = *check.Resources
+	}
+
+	var warnings []string
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if ok, _ := shouldInstrumentContainer(*container, target.ContainerSelector, containerNames); !ok || isRuntimeGated(container) {
+			continue
+		}
+		argv, err := containerArgv(ctx, container, pod.Annotations, images)
+		if err != nil {
+			logger.Info("Runtime version of container is not checked", "container", container.Name, "error", err.Error())
+			warnings = append(warnings, fmt.Sprintf("container %s: the runtime version is not checked: %v", container.Name, err))
+			continue
+		}
+		binary := ""
+		if args := argv; len(args) > 0 {
+			if args[0] == ValPythonStartAgent && len(args) > 1 {
+				args = args[1:]
+			}
+			if binaryPattern.MatchString(path.Base(args[0])) {
+				binary = args[0]
+			}
+		}
+
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+			Name:            fmt.Sprintf("%s-%d", monitoringv2alpha1.RuntimeCheckContainerPrefix, i),
+			Image:           container.Image,
+			ImagePullPolicy: container.ImagePullPolicy,
+			Command: []string{shell, "-c", runtimeCheckScript, monitoringv2alpha1.RuntimeCheckContainerPrefix,
+				lang, minVersion, maxVersion, container.Name, binary, runtimeCheckDir, corev1.TerminationMessagePathDefault},
+			Env:                      append([]corev1.EnvVar{}, container.Env...),
+			EnvFrom:                  append([]corev1.EnvFromSource{}, container.EnvFrom...),
+			VolumeMounts:             []corev1.VolumeMount{{Name: VolumeNameWhatapAgent, MountPath: MountPathWhatapAgent}},
+			Resources:                resources,
+			SecurityContext:          container.SecurityContext,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		})
+		logger.Info("Gating APM agent on the runtime version", "container", container.Name, "language", lang, "minVersion", minVersion, "maxVersion", maxVersion)
+		container.Command = []string{shell, "-c", runtimeGateScript, runtimeGateName, lang, container.Name, runtimeCheckDir}
+		container.Args = argv
+	}
+	return warnings
+}
+
+// isRuntimeGated reports whether container already runs through the runtime gate.
+func isRuntimeGated(container *corev1.Container) bool {
+	return len(container.Command) > 3 && container.Command[1] == "-c" && container.Command[3] == runtimeGateName
+}
+
+// unwrapRuntimeGate restores the command gateRuntimeVersions replaced.
+func unwrapRuntimeGate(container *corev1.Container) {
+	if isRuntimeGated(container) {
+		container.Command = container.Args
+		container.Args = nil
+	}
+}
+
+// isRuntimeCheckContainer reports whether c is an init container added by gateRuntimeVersions.
+func isRuntimeCheckContainer(c corev1.Container) bool {
+	return strings.HasPrefix(c.Name, monitoringv2alpha1.RuntimeCheckContainerPrefix+"-")
+}
+
+// normalizeRuntimeVersion maps Java's legacy "1.x" versions to "x", the form the check
+// script compares.
+func normalizeRuntimeVersion(lang, version string) string {
+	if lang == "java" && strings.HasPrefix(version, "1.") {
+		return strings.TrimPrefix(version, "1.")
+	}
+	return version
+}
@@ -0,0 +1,174 @@
+package v2alpha1
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// runShell runs script with /bin/sh like the injected containers do.
+func runShell(t *testing.T, env []string, script string, args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	cmd := exec.Command("sh", append([]string{"-c", script, "test"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRuntimeCheckScript(t *testing.T) {
+	bin := t.TempDir()
+	// fake writes a runtime that prints output like the real one (java -version on stderr)
+	fake := func(name, output, redirect string) string {
+		p := filepath.Join(bin, name)
+		if err := os.WriteFile(p, []byte("#!/bin/sh\necho '"+output+"'"+redirect+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	java6 := fake("java", `java version "1.6.0_45"`, " >&2")
+	python := fake("python3.13", "Python 3.13.0", "")
+	fake("node", "v18.17.0", "")
+
+	for _, c := range []struct {
+		lang, min, max, binary string
+		disabled               bool
+		message                string
+	}{
+		{"java", "8", "", java6, true, "disabled: java 1.6.0_45 of container app is older than 8"},
+		{"java", "6", "21", java6, false, "enabled: java 1.6.0_45 of container app"},
+		{"python", "3.7", "3.12", python, true, "disabled: python 3.13.0 of container app is newer than 3.12"},
+		{"python", "3.7", "3.13", python, false, "enabled: python 3.13.0 of container app"},
+		{"nodejs", "14", "", "", false, "enabled: nodejs 18.17.0 of container app"},
+		{"java", "8", "", filepath.Join(bin, "missing"), false, "unknown: cannot detect the java version of container app, the agent stays enabled"},
+	} {
+		dir := t.TempDir()
+		log := filepath.Join(dir, "termination-log")
+		out := runShell(t, []string{"PATH=" + bin + ":" + os.Getenv("PATH")}, runtimeCheckScript,
+			c.lang, c.min, c.max, "app", c.binary, dir, log)
+		if out != c.message {
+			t.Errorf("%s %s: expected %q, got %q", c.lang, c.binary, c.message, out)
+		}
+		if message, _ := os.ReadFile(log); strings.TrimSpace(string(message)) != c.message {
+			t.Errorf("%s: expected the result as termination message, got %q", c.lang, message)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "app.disabled")); (err == nil) != c.disabled {
+			t.Errorf("%s %s-%s: expected disabled=%v", c.lang, c.min, c.max, c.disabled)
+		}
+	}
+}
+
+func TestRuntimeGateScript(t *testing.T) {
+	dir := t.TempDir()
+	printEnv := []string{"sh", "-c", `echo "$JAVA_TOOL_OPTIONS|$NODE_OPTIONS|$PYTHONPATH|$*"`, "app", "arg"}
+	env := []string{
+		"JAVA_TOOL_OPTIONS=-Xmx1g " + ValJavaAgentOptionPrefix + ValJavaAgentPath,
+		"NODE_OPTIONS=--max-old-space-size=100 " + ValNodejsRequire + " -r dotenv/config",
+		"PYTHONPATH=" + ValPythonBootstrap + ":/app",
+	}
+
+	// Without the disabled marker the command runs with the agent
+	out := runShell(t, env, runtimeGateScript, append([]string{"java", "app", dir}, printEnv...)...)
+	if !strings.Contains(out, ValJavaAgentPath) {
+		t.Errorf("Expected the agent to stay enabled, got %q", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "app.disabled"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for lang, want := range map[string]string{
+		"java":   "-Xmx1g|--max-old-space-size=100 -r whatap -r dotenv/config|" + ValPythonBootstrap + ":/app|arg",
+		"nodejs": "-Xmx1g " + ValJavaAgentOptionPrefix + ValJavaAgentPath + "|--max-old-space-size=100 -r dotenv/config|" + ValPythonBootstrap + ":/app|arg",
+		"python": "-Xmx1g " + ValJavaAgentOptionPrefix + ValJavaAgentPath + "|--max-old-space-size=100 -r whatap -r dotenv/config|/app|arg",
+	} {
+		argv := printEnv
+		if lang == "python" {
+			argv = append([]string{ValPythonStartAgent}, printEnv...)
+		}
+		out := runShell(t, env, runtimeGateScript, append([]string{lang, "app", dir}, argv...)...)
+		if lines := strings.Split(out, "\n"); lines[len(lines)-1] != want {
+			t.Errorf("%s: expected only the %s agent to be removed, got %q", lang, lang, out)
+		}
+	}
+}
+
+func TestGateRuntimeVersions(t *testing.T) {
+	target := monitoringv2alpha1.TargetSpec{
+		Name: "java", Enabled: true, Language: "java",
+		RuntimeCheck: &monitoringv2alpha1.RuntimeCheckSpec{Enabled: true, MinVersion: "1.8", Shell: "/bin/bash"},
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: InitContainerName}},
+		Containers: []corev1.Container{
+			{Name: "app", Image: "shop/orders:1.0", Command: []string{"/opt/jdk/bin/java"}, Args: []string{"-jar", "app.jar"}},
+			{Name: "batch", Image: "registry.local/batch:1.0"},
+		},
+	}}
+
+	warnings := gateRuntimeVersions(context.Background(), pod, target, nil, fakeImages{}, logr.Discard())
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "batch") {
+		t.Errorf("Expected a warning for the container without a known command, got %v", warnings)
+	}
+	if len(pod.Spec.InitContainers) != 2 {
+		t.Fatalf("Expected one runtime check init container, got %+v", pod.Spec.InitContainers)
+	}
+	check := pod.Spec.InitContainers[1]
+	if check.Name != "whatap-runtime-check-0" || check.Image != "shop/orders:1.0" {
+		t.Errorf("Expected the check to run the application image, got %s %s", check.Name, check.Image)
+	}
+	if args := strings.Join(check.Command[4:], ","); check.Command[0] != "/bin/bash" || args != "java,8,,app,/opt/jdk/bin/java,"+runtimeCheckDir+",/dev/termination-log" {
+		t.Errorf("Unexpected runtime check command %s %s", check.Command[0], args)
+	}
+	if memory := check.Resources.Limits[corev1.ResourceMemory]; memory.String() != "512Mi" {
+		t.Errorf("Expected the check to get its own resources, got %v", check.Resources)
+	}
+	app := pod.Spec.Containers[0]
+	if !isRuntimeGated(&app) || app.Command[0] != "/bin/bash" || strings.Join(app.Args, " ") != "/opt/jdk/bin/java -jar app.jar" {
+		t.Errorf("Expected the application command behind the gate, got %v %v", app.Command, app.Args)
+	}
+
+	stripInjectionArtifacts(pod)
+	if len(pod.Spec.InitContainers) != 0 || strings.Join(pod.Spec.Containers[0].Command, " ") != "/opt/jdk/bin/java -jar app.jar" {
+		t.Errorf("Expected stripping to remove the check and the gate, got %+v", pod.Spec)
+	}
+}
+
+func TestGateRuntimeVersions_Shell(t *testing.T) {
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "gcr.io/distroless/java17:nonroot", Command: []string{"java"}, Args: []string{"-jar", "app.jar"}},
+		}}}
+	}
+	check := &monitoringv2alpha1.RuntimeCheckSpec{Enabled: true}
+	target := monitoringv2alpha1.TargetSpec{Name: "java", Enabled: true, Language: "java", RuntimeCheck: check}
+
+	// An unset shell (CR created without defaulting) runs the check with /bin/sh
+	pod := newPod()
+	gateRuntimeVersions(context.Background(), pod, target, nil, fakeImages{}, logr.Discard())
+	if len(pod.Spec.InitContainers) != 1 || pod.Spec.InitContainers[0].Command[0] != monitoringv2alpha1.RuntimeCheckShellDefault {
+		t.Errorf("Expected the check to run with the default shell, got %+v", pod.Spec.InitContainers)
+	}
+
+	// Images without a shell opt out of the check
+	check.Shell = monitoringv2alpha1.RuntimeCheckShellNone
+	pod = newPod()
+	if warnings := gateRuntimeVersions(context.Background(), pod, target, nil, fakeImages{}, logr.Discard()); len(warnings) != 0 {
+		t.Errorf("Expected no warnings with shell none, got %v", warnings)
+	}
+	if len(pod.Spec.InitContainers) != 0 || isRuntimeGated(&pod.Spec.Containers[0]) {
+		t.Errorf("Expected shell none to skip the check, got %+v", pod.Spec)
+	}
+}
@@ -0,0 +1,161 @@
+package v2alpha1
+
+import (
+	"strings"
+
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// pluginVolumeName is the operator-owned volume carrying agent plugin files
+const pluginVolumeName = "whatap-plugin-volume"
+
+// agentPathEnvNames point into the agent volume and are meaningless once it is removed
+var agentPathEnvNames = toNameSet(EnvJavaAgentPath, EnvPythonAgentPath, EnvNodejsAgentPath)
+
+// hasInjectionArtifacts reports whether podSpec still carries the agent init container or
+// volume, e.g. because the workload template was copied from an injected pod.
+func hasInjectionArtifacts(podSpec *corev1.PodSpec) bool {
+	for _, c := range podSpec.InitContainers {
+		if c.Name == InitContainerName {
+			return true
+		}
+	}
+	for _, v := range podSpec.Volumes {
+		if v.Name == VolumeNameWhatapAgent {
+			return true
+		}
+	}
+	return false
+}
+
+// stripInjectionArtifacts removes everything the webhook injects from pod: the agent init
+// container, the agent and plugin volumes and their mounts, the agent fragments of
+// JAVA_TOOL_OPTIONS / NODE_OPTIONS / PYTHONPATH / NODE_PATH / PHP_INI_SCAN_DIR (WhaTap and
+// OpenTelemetry agents), the CLR profiler, the agent path envs, the whatap-start-agent
+// wrapper and the runtime version check, plus the injection labels and annotations. User
+// values sharing those env vars are kept. The OTEL_* variables are left in place; they
+// have no effect without the agent.
+func stripInjectionArtifacts(pod *corev1.Pod) {
+	podSpec := &pod.Spec
+
+	initContainers := podSpec.InitContainers[:0]
+	for _, c := range podSpec.InitContainers {
+		if c.Name != InitContainerName && !isRuntimeCheckContainer(c) {
+			initContainers = append(initContainers, c)
+		}
+	}
+	podSpec.InitContainers = initContainers
+	stripContainers(podSpec.InitContainers)
+	stripContainers(podSpec.Containers)
+
+	volumes := podSpec.Volumes[:0]
+	for _, v := range podSpec.Volumes {
+		if v.Name != VolumeNameWhatapAgent && v.Name != pluginVolumeName {
+			volumes = append(volumes, v)
+		}
+	}
+	podSpec.Volumes = volumes
+
+	delete(pod.Labels, monitoringv2alpha1.InjectionLabelKey)
+	for _, key := range []string{
+		monitoringv2alpha1.AnnotationApmInjected,
+		monitoringv2alpha1.AnnotationApmLanguage,
+		monitoringv2alpha1.AnnotationApmVersion,
+		monitoringv2alpha1.AnnotationApmInstance,
+		monitoringv2alpha1.AnnotationApmTarget,
+		monitoringv2alpha1.AnnotationApmSkippedReason,
+	} {
+		delete(pod.Annotations, key)
+	}
+}
+
+func stripContainers(containers []corev1.Container) {
+	for i := range containers {
+		c := &containers[i]
+		mounts := c.VolumeMounts[:0]
+		for _, m := range c.VolumeMounts {
+			if m.Name != VolumeNameWhatapAgent && m.Name != pluginVolumeName {
+				mounts = append(mounts, m)
+			}
+		}
+		c.VolumeMounts = mounts
+		c.Env = stripAgentEnvVars(c.Env)
+		unwrapRuntimeGate(c)
+		unwrapPythonCommand(c)
+	}
+}
+
+// stripAgentEnvVars removes the agent fragments from envs. List-style variables that only
+// held the agent fragment are dropped; variables set via ConfigMap/Secret are never touched.
+func stripAgentEnvVars(envs []corev1.EnvVar) []corev1.EnvVar {
+	ownsProfiler := false
+	for _, e := range envs {
+		// The profiler path may be overridden by target envs, but always lies in the agent volume
+		if e.Name == EnvCoreclrProfilerPath && strings.HasPrefix(e.Value, MountPathWhatapAgent+"/") {
+			ownsProfiler = true
+		}
+	}
+
+	result := envs[:0]
+	for _, e := range envs {
+		if e.ValueFrom != nil {
+			result = append(result, e)
+			continue
+		}
+		if _, ok := agentPathEnvNames[e.Name]; ok {
+			continue
+		}
+		switch e.Name {
+		case EnvJavaToolOptions:
+			e.Value = removeField(e.Value, " ", ValJavaAgentOptionPrefix+ValJavaAgentPath)
+			e.Value = removeField(e.Value, " ", ValJavaAgentOptionPrefix+ValOtelJavaAgentPath)
+		case EnvNodejsOptions:
+			for _, require := range []string{ValNodejsRequire, ValOtelNodejsRequire} {
+				e.Value = strings.TrimSpace(strings.ReplaceAll(" "+e.Value+" ", " "+require+" ", " "))
+			}
+		case EnvPythonPath:
+			for _, path := range []string{ValPythonBootstrap, ValOtelPythonBootstrap, ValOtelHome} {
+				e.Value = removeField(e.Value, ":", path)
+			}
+		case EnvNodejsPath:
+			e.Value = removeField(e.Value, ":", ValNodejsModules)
+		case EnvPhpIniScanDir:
+			e.Value = removeField(e.Value, ":", ValPhpIniDir)
+		case EnvCoreclrEnableProfiling, EnvCoreclrProfiler, EnvCoreclrProfilerPath:
+			if ownsProfiler {
+				continue
+			}
+			result = append(result, e)
+			continue
+		case EnvWhatapHome:
+			if e.Value == ValWhatapHome {
+				continue
+			}
+			result = append(result, e)
+			continue
+		default:
+			result = append(result, e)
+			continue
+		}
+		// one of the list-style variables above: drop it if only the agent fragment was set
+		if e.Value != "" {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// removeField removes every occurrence of field from the sep-separated list s. A leading
+// empty element (PHP_INI_SCAN_DIR's ":dir" form) is preserved.
+func removeField(s, sep, field string) string {
+	parts := strings.Split(s, sep)
+	kept := parts[:0]
+	for i, p := range parts {
+		if strings.TrimSpace(p) == field || (p == "" && i > 0) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.TrimSpace(strings.Join(kept, sep))
+}
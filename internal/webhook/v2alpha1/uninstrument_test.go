@@ -0,0 +1,92 @@
+package v2alpha1
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStripInjectionArtifacts_RoundTrip(t *testing.T) {
+	original := corev1.PodSpec{
+		Volumes: []corev1.Volume{{Name: "data"}},
+		Containers: []corev1.Container{{
+			Name: "app",
+			Env: []corev1.EnvVar{
+				{Name: EnvJavaToolOptions, Value: "-Xmx512m"},
+				{Name: EnvNodejsOptions, Value: "--max-old-space-size=512"},
+				{Name: "EMPTY_ON_PURPOSE", Value: ""},
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		}},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: *original.DeepCopy()}
+	target := monitoringv2alpha1.TargetSpec{Name: "t", Enabled: true, Language: "java"}
+	patchPodTemplateSpec(&pod.Spec, monitoringv2alpha1.WhatapAgent{}, target, "whatap-monitoring", nil, logr.Discard())
+	cr := monitoringv2alpha1.WhatapAgent{ObjectMeta: metav1.ObjectMeta{Name: "whatap"}}
+	setInjectionMetadata(pod, &cr, &target, "true")
+
+	if !hasInjectionArtifacts(&pod.Spec) {
+		t.Fatalf("Expected injected pod to carry artifacts")
+	}
+	stripInjectionArtifacts(pod)
+
+	if hasInjectionArtifacts(&pod.Spec) || len(pod.Spec.InitContainers) != 0 {
+		t.Fatalf("Expected init container and volume to be removed, got %+v", pod.Spec)
+	}
+	if len(pod.Spec.Volumes) != 1 || len(pod.Spec.Containers[0].VolumeMounts) != 1 {
+		t.Errorf("Expected user volumes to be kept, got %+v", pod.Spec)
+	}
+	if v, _ := effective(pod.Spec.Containers[0].Env, EnvJavaToolOptions); v != "-Xmx512m" {
+		t.Errorf("Expected JAVA_TOOL_OPTIONS to keep only the user value, got %q", v)
+	}
+	if _, ok := effective(pod.Spec.Containers[0].Env, EnvJavaAgentPath); ok {
+		t.Errorf("Expected %s to be removed", EnvJavaAgentPath)
+	}
+	if _, ok := effective(pod.Spec.Containers[0].Env, "EMPTY_ON_PURPOSE"); !ok {
+		t.Errorf("Expected unrelated empty env to be kept")
+	}
+	if _, ok := pod.Labels[monitoringv2alpha1.InjectionLabelKey]; ok {
+		t.Errorf("Expected injection label to be removed")
+	}
+	if _, ok := pod.Annotations[monitoringv2alpha1.AnnotationApmInjected]; ok {
+		t.Errorf("Expected injection annotations to be removed")
+	}
+}
+
+func TestStripAgentEnvVars(t *testing.T) {
+	envs := []corev1.EnvVar{
+		{Name: EnvNodejsOptions, Value: ValNodejsRequire + " --inspect"},
+		{Name: EnvPythonPath, Value: ValPythonBootstrap + ":/app/libs"},
+		{Name: EnvNodejsPath, Value: ValNodejsModules},
+		{Name: EnvPhpIniScanDir, Value: "/usr/local/etc/php/conf.d:" + ValPhpIniDir},
+		{Name: EnvCoreclrEnableProfiling, Value: ValCoreclrEnableProfiling},
+		{Name: EnvCoreclrProfiler, Value: "{11111111-2222-3333-4444-555555555555}"},
+		{Name: EnvCoreclrProfilerPath, Value: ValWhatapHome + "/dotnet/libwhatap_profiler.so"},
+		{Name: EnvWhatapHome, Value: ValWhatapHome},
+		{Name: EnvJavaToolOptions, ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "opts"}}},
+	}
+
+	got := stripAgentEnvVars(envs)
+
+	want := map[string]string{
+		EnvNodejsOptions: "--inspect",
+		EnvPythonPath:    "/app/libs",
+		EnvPhpIniScanDir: "/usr/local/etc/php/conf.d",
+	}
+	for name, value := range want {
+		if v, _ := effective(got, name); v != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, v)
+		}
+	}
+	for _, name := range []string{EnvNodejsPath, EnvCoreclrProfiler, EnvCoreclrProfilerPath, EnvCoreclrEnableProfiling, EnvWhatapHome} {
+		if _, ok := effective(got, name); ok {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+	if len(envValues(got, EnvJavaToolOptions)) != 1 {
+		t.Errorf("Expected JAVA_TOOL_OPTIONS from a ConfigMap to be kept")
+	}
+}
@@ -1,16 +1,53 @@
 package v2alpha1
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	"github.com/whatap/whatap-operator/internal/config"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Helper functions for pointer types
 func boolPtr(b bool) *bool    { return &b }
 func int64Ptr(i int64) *int64 { return &i }
 
+// supportedLanguages lists the TargetSpec.Language values the webhook can inject.
+var supportedLanguages = []string{"java", "python", "nodejs", "php", "dotnet", "golang"}
+
+// 헬퍼: 슬라이스에 문자열이 있는지 확인
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationIsTrue / annotationIsFalse report whether the annotation is set to a boolean
+// value. Unset or unparsable values are neither true nor false.
+func annotationIsTrue(annotations map[string]string, key string) bool {
+	v, ok := annotations[key]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
+
+func annotationIsFalse(annotations map[string]string, key string) bool {
+	v, ok := annotations[key]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	return err == nil && !b
+}
+
 // findEnvValueByKeys searches for an environment variable by multiple candidate keys.
 // Returns the value of the first matching key found.
 func findEnvValueByKeys(envs []corev1.EnvVar, keys ...string) (string, bool) {
@@ -29,6 +66,14 @@ func getWhatapLicenseEnvVar(cr monitoringv2alpha1.WhatapAgent, target monitoring
 	if val, ok := findEnvValueByKeys(target.Envs, EnvWhatapLicense, EnvJavaLicense); ok {
 		return corev1.EnvVar{Name: EnvWhatapLicense, Value: val}
 	}
+	// 인스턴스(CR) 스펙 값이 operator 환경변수보다 우선 (여러 WhatapAgent 인스턴스 지원)
+	if cr.Spec.License != "" {
+		return corev1.EnvVar{Name: EnvWhatapLicense, Value: cr.Spec.License}
+	}
+	// operator 환경변수는 기본 인스턴스의 값: 다른 인스턴스는 resolveInstanceCredentials 로 채운다
+	if !cr.IsDefaultInstance() {
+		return corev1.EnvVar{Name: EnvWhatapLicense}
+	}
 	return corev1.EnvVar{Name: EnvWhatapLicense, Value: config.GetWhatapLicense()}
 }
 
@@ -37,6 +82,14 @@ func getWhatapHostEnvVar(cr monitoringv2alpha1.WhatapAgent, target monitoringv2a
 	if val, ok := findEnvValueByKeys(target.Envs, EnvWhatapHost, EnvJavaWhatapHost, EnvPythonWhatapHost, EnvNodejsWhatapHost); ok {
 		return corev1.EnvVar{Name: EnvWhatapHost, Value: val}
 	}
+	// 인스턴스(CR) 스펙 값이 operator 환경변수보다 우선 (여러 WhatapAgent 인스턴스 지원)
+	if cr.Spec.Host != "" {
+		return corev1.EnvVar{Name: EnvWhatapHost, Value: cr.Spec.Host}
+	}
+	// operator 환경변수는 기본 인스턴스의 값: 다른 인스턴스는 resolveInstanceCredentials 로 채운다
+	if !cr.IsDefaultInstance() {
+		return corev1.EnvVar{Name: EnvWhatapHost}
+	}
 	return corev1.EnvVar{Name: EnvWhatapHost, Value: config.GetWhatapHost()}
 }
 
@@ -45,9 +98,69 @@ func getWhatapPortEnvVar(cr monitoringv2alpha1.WhatapAgent, target monitoringv2a
 	if val, ok := findEnvValueByKeys(target.Envs, EnvWhatapPort, EnvJavaWhatapPort, EnvPythonWhatapPort, EnvNodejsWhatapPort); ok {
 		return corev1.EnvVar{Name: EnvWhatapPort, Value: val}
 	}
+	// 인스턴스(CR) 스펙 값이 operator 환경변수보다 우선 (여러 WhatapAgent 인스턴스 지원)
+	if cr.Spec.Port != "" {
+		return corev1.EnvVar{Name: EnvWhatapPort, Value: cr.Spec.Port}
+	}
+	// operator 환경변수는 기본 인스턴스의 값: 다른 인스턴스는 resolveInstanceCredentials 로 채운다
+	if !cr.IsDefaultInstance() {
+		return corev1.EnvVar{Name: EnvWhatapPort}
+	}
 	return corev1.EnvVar{Name: EnvWhatapPort, Value: config.GetWhatapPort()}
 }
 
+// instanceCredentialsKeys are the keys of the whatap-credentials Secret of an instance
+const (
+	credentialsKeyLicense = "WHATAP_LICENSE"
+	credentialsKeyHost    = "WHATAP_HOST"
+	credentialsKeyPort    = "WHATAP_PORT"
+)
+
+// resolveInstanceCredentials returns cr with the license, host and port that neither its
+// spec nor target set filled from the instance's own whatap-credentials Secret in
+// namespace. The operator environment only holds the credentials of the default instance,
+// so a non-default instance without them must not be injected: an error is returned when
+// its Secret or its license/host keys are missing. The default instance is returned as is.
+func resolveInstanceCredentials(ctx context.Context, c client.Reader, namespace string, cr *monitoringv2alpha1.WhatapAgent, target monitoringv2alpha1.TargetSpec) (*monitoringv2alpha1.WhatapAgent, error) {
+	if cr.IsDefaultInstance() {
+		return cr, nil
+	}
+	_, licenseSet := findEnvValueByKeys(target.Envs, EnvWhatapLicense, EnvJavaLicense)
+	_, hostSet := findEnvValueByKeys(target.Envs, EnvWhatapHost, EnvJavaWhatapHost, EnvPythonWhatapHost, EnvNodejsWhatapHost)
+	_, portSet := findEnvValueByKeys(target.Envs, EnvWhatapPort, EnvJavaWhatapPort, EnvPythonWhatapPort, EnvNodejsWhatapPort)
+	needLicense := !licenseSet && cr.Spec.License == ""
+	needHost := !hostSet && cr.Spec.Host == ""
+	needPort := !portSet && cr.Spec.Port == ""
+	if !needLicense && !needHost && !needPort {
+		return cr, nil
+	}
+
+	name := cr.ResourceName("whatap-credentials")
+	if c == nil {
+		return nil, fmt.Errorf("instance %s has no credentials in its spec and Secret %s/%s cannot be read", cr.Name, namespace, name)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("instance %s has no credentials in its spec: Secret %s/%s: %w", cr.Name, namespace, name, err)
+	}
+	resolved := cr.DeepCopy()
+	if needLicense {
+		if resolved.Spec.License = string(secret.Data[credentialsKeyLicense]); resolved.Spec.License == "" {
+			return nil, fmt.Errorf("secret %s/%s of instance %s has no %s", namespace, name, cr.Name, credentialsKeyLicense)
+		}
+	}
+	if needHost {
+		if resolved.Spec.Host = string(secret.Data[credentialsKeyHost]); resolved.Spec.Host == "" {
+			return nil, fmt.Errorf("secret %s/%s of instance %s has no %s", namespace, name, cr.Name, credentialsKeyHost)
+		}
+	}
+	if needPort {
+		// The agents default to port 6600 when it is not set
+		resolved.Spec.Port = string(secret.Data[credentialsKeyPort])
+	}
+	return resolved, nil
+}
+
 func appendIfNotExists(volumes []corev1.Volume, newVol corev1.Volume) []corev1.Volume {
 	for _, v := range volumes {
 		if v.Name == newVol.Name {
@@ -144,8 +257,9 @@ func toNameSet(names ...string) map[string]struct{} {
 	return set
 }
 
-// pythonForceEnvNames / nodejsForceEnvNames are the whatap-owned connection/config keys that
-// the operator must force onto the pod even if another webhook injected a duplicate earlier.
+// pythonForceEnvNames / nodejsForceEnvNames (and the php/dotnet/golang sets) are the
+// whatap-owned connection/config keys that the operator must force onto the pod even if
+// another webhook injected a duplicate earlier.
 // App-info keys (app_name/app_process_name/OKIND), search-path keys (PYTHONPATH/NODE_PATH/
 // NODE_OPTIONS) and the agent-path keys are intentionally NOT here: those preserve an
 // existing/user value (the operator only adds or prepends them).
@@ -160,6 +274,24 @@ var (
 		EnvWhatapHome, EnvWhatapMicroEnabled,
 		EnvNodeIP, EnvNodeName, EnvPodName,
 	)
+	phpForceEnvNames = toNameSet(
+		EnvPhpLicense, EnvPhpWhatapHost, EnvPhpWhatapPort,
+		EnvWhatapHome, EnvWhatapMicroEnabled,
+		EnvNodeIP, EnvNodeName, EnvPodName,
+	)
+	// Only one CLR profiler can be attached per process, so the CORECLR_* keys are forced as
+	// well: a profiler left behind by another APM would otherwise silently replace ours.
+	dotnetForceEnvNames = toNameSet(
+		EnvDotnetLicense, EnvDotnetWhatapHost, EnvDotnetWhatapPort,
+		EnvCoreclrEnableProfiling, EnvCoreclrProfiler, EnvCoreclrProfilerPath,
+		EnvWhatapHome, EnvWhatapMicroEnabled,
+		EnvNodeIP, EnvNodeName, EnvPodName,
+	)
+	golangForceEnvNames = toNameSet(
+		EnvGolangLicense, EnvGolangWhatapHost, EnvGolangWhatapPort,
+		EnvWhatapHome, EnvWhatapMicroEnabled,
+		EnvNodeIP, EnvNodeName, EnvPodName,
+	)
 )
 
 // operatorManagedEnvNames is every env name the operator itself produces. User CR target.Envs
@@ -173,6 +305,11 @@ var operatorManagedEnvNames = toNameSet(
 	EnvPythonLicense, EnvPythonWhatapHost, EnvPythonWhatapPort, EnvPythonAgentPath, EnvWhatapHome, EnvPythonPath,
 	EnvAppName, EnvAppProcessName, EnvOkind,
 	EnvNodejsLicense, EnvNodejsWhatapHost, EnvNodejsWhatapPort, EnvNodejsAgentPath, EnvNodejsOptions, EnvNodejsPath,
+	EnvPhpLicense, EnvPhpWhatapHost, EnvPhpWhatapPort, EnvPhpIniScanDir,
+	EnvDotnetLicense, EnvDotnetWhatapHost, EnvDotnetWhatapPort,
+	EnvCoreclrEnableProfiling, EnvCoreclrProfiler, EnvCoreclrProfilerPath,
+	EnvGolangLicense, EnvGolangWhatapHost, EnvGolangWhatapPort,
+	EnvOtelEndpoint, EnvOtelProtocol,
 )
 
 // matchesSelector checks if the given labels match the selector
@@ -289,16 +426,8 @@ func matchesNamespaceNames(namespaceName string, matchNames []string) bool {
 	return false
 }
 
-// getAgentImage returns the image name to use for the agent
-func getAgentImage(target monitoringv2alpha1.TargetSpec, lang, version string) string {
-	// Prefer new CustomImageFullName if provided
-	if target.CustomImageFullName != "" {
-		return target.CustomImageFullName
-	}
-	// Fallback to deprecated CustomImageName for backward compatibility
-	if target.CustomImageName != "" {
-		return target.CustomImageName
-	}
-	// Default image format
-	return fmt.Sprintf("public.ecr.aws/whatap/apm-init-%s:%s", lang, version)
+// getAgentImage returns the APM init image injected for target, pinned to its
+// resolved digest when spec.pinImageDigests is set
+func getAgentImage(target monitoringv2alpha1.TargetSpec, cr monitoringv2alpha1.WhatapAgent) string {
+	return cr.PinnedImage(cr.ApmInitImageFor(target))
 }
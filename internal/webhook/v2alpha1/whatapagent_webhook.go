@@ -20,8 +20,13 @@ import (
 	"context"
 	"fmt"
 	corev1 "k8s.io/api/core/v1"
+	"regexp"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -30,6 +35,14 @@ import (
 
 	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
 	"github.com/whatap/whatap-operator/internal/config"
+	"github.com/whatap/whatap-operator/internal/registry"
+)
+
+const (
+	// imageConfigCacheTTL is how long the webhook keeps the ENTRYPOINT/CMD of an image
+	imageConfigCacheTTL = time.Hour
+	// imageConfigErrorTTL is how long a failed image config lookup is remembered
+	imageConfigErrorTTL = time.Minute
 )
 
 // nolint:unused
@@ -37,20 +50,34 @@ import (
 var whatapWebhookLogger = logf.Log.WithName("whatap-webhook")
 
 // SetupWhatapAgentWebhookWithManager registers the webhook for WhatapAgent in the manager.
-func SetupWhatapAgentWebhookWithManager(mgr ctrl.Manager) error {
+// namespace is where the operator installs the agents. serveDryRun adds the injection
+// dry-run to the metrics server; only set it when the metrics server authenticates and
+// authorizes its requests.
+func SetupWhatapAgentWebhookWithManager(mgr ctrl.Manager, namespace string, serveDryRun bool) error {
+	// Image configs are read for Python targets wrapping the image ENTRYPOINT
+	keychain := registry.NewSecretKeychain(mgr.GetAPIReader(), namespace, config.GetRegistryAuthSecrets())
+	images := registry.NewConfigCache(registry.NewClient(keychain), imageConfigCacheTTL, imageConfigErrorTTL)
+
 	// Register the Pod webhook for injection
 	if err := ctrl.NewWebhookManagedBy(mgr).
 		For(&corev1.Pod{}).
-		WithDefaulter(&WhatapAgentCustomDefaulter{mgr.GetClient()}).
+		WithDefaulter(&WhatapAgentCustomDefaulter{client: mgr.GetClient(), images: images}).
 		WithDefaulterCustomPath("/whatap-injection--v1-pod").
 		Complete(); err != nil {
 		return err
 	}
+	if serveDryRun {
+		// The dry-run answers from the image configs admission already read, it never
+		// contacts a registry for the images of a request
+		if err := mgr.AddMetricsServerExtraHandler(DryRunPath, &dryRunHandler{client: mgr.GetClient(), images: images.Cached()}); err != nil {
+			return err
+		}
+	}
 
 	// Register the WhatapAgent webhook for validation
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&monitoringv2alpha1.WhatapAgent{}).
-		WithValidator(&WhatapAgentCustomValidator{client: mgr.GetClient()}).
+		WithValidator(&WhatapAgentCustomValidator{client: mgr.GetClient(), namespace: namespace}).
 		WithValidatorCustomPath("/whatap-validation--v2alpha1-whatapagent").
 		Complete()
 }
@@ -58,7 +85,8 @@ func SetupWhatapAgentWebhookWithManager(mgr ctrl.Manager) error {
 // TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 
 type WhatapAgentCustomDefaulter struct {
-	client client.Client // webhook 에 등록된 mgr.GetClient()
+	client client.Client           // webhook 에 등록된 mgr.GetClient()
+	images registry.ConfigResolver // 이미지 ENTRYPOINT/CMD 조회 (Python wrap 모드)
 }
 
 var _ webhook.CustomDefaulter = &WhatapAgentCustomDefaulter{}
@@ -70,115 +98,363 @@ func (d *WhatapAgentCustomDefaulter) Default(ctx context.Context, obj runtime.Ob
 		whatapWebhookLogger.Info("skipping non-Pod object")
 		return nil
 	}
-	// WhatapAgent CR 가져오기 (클러스터 스코프)
-	var whatapAgentCustomResource monitoringv2alpha1.WhatapAgent
+	// Check if APM agent is already injected
+	if pod.Annotations[monitoringv2alpha1.AnnotationApmInjected] == "true" && !hasInjectionArtifacts(&pod.Spec) {
+		whatapWebhookLogger.V(1).Info("APM agent already injected, skipping APM injection", "pod", podIdentifierOf(pod))
+		return nil
+	}
 
-	if err := d.client.Get(ctx, client.ObjectKey{Name: "whatap"}, &whatapAgentCustomResource); err != nil {
-		// CR이 아직 생성 안 됐으면 주입 안 함
-		whatapWebhookLogger.V(1).Info("WhatapAgent CR not found, skipping APM injection", "pod", pod.GetNamespace()+"/"+pod.GetName(), "error", err.Error())
+	// WhatapAgent CR 목록 가져오기 (클러스터 스코프, 여러 인스턴스 지원)
+	var agents monitoringv2alpha1.WhatapAgentList
+	if err := d.client.List(ctx, &agents); err != nil {
+		whatapWebhookLogger.Error(err, "Failed to list WhatapAgent CRs, skipping APM injection", "pod", podIdentifierOf(pod))
 		return nil
 	}
+
+	// Get the namespace object to check its labels
+	var namespace corev1.Namespace
+	if err := d.client.Get(ctx, client.ObjectKey{Name: pod.Namespace}, &namespace); err != nil {
+		whatapWebhookLogger.Error(err, "Failed to get namespace", "namespace", pod.Namespace)
+		return nil
+	}
+
+	mutatePod(ctx, pod, agents.Items, &namespace, d.client, d.images, whatapWebhookLogger)
+	return nil
+}
+
+// injectionDecision is the outcome of mutatePod.
+type injectionDecision struct {
+	Injected bool   `json:"injected"`
+	Instance string `json:"instance,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Language string `json:"language,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Reason explains why the pod was not instrumented
+	Reason string `json:"reason,omitempty"`
+	// Warnings lists what was injected differently than configured
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// mutatePod applies everything the pod injection webhook does to pod, given the
+// WhatapAgents and the pod's namespace, and reports the outcome. It is shared by
+// the admission webhook and the dry-run endpoint. secrets reads the credentials Secret
+// of non-default instances. images is only consulted to wrap containers running their
+// image ENTRYPOINT (Python wrap mode, runtime version check).
+func mutatePod(ctx context.Context, pod *corev1.Pod, agents []monitoringv2alpha1.WhatapAgent, namespace *corev1.Namespace, secrets client.Reader, images registry.ConfigResolver, logger logr.Logger) injectionDecision {
+	podIdentifier := podIdentifierOf(pod)
+
+	stale := hasInjectionArtifacts(&pod.Spec)
+	if pod.Annotations[monitoringv2alpha1.AnnotationApmInjected] == "true" && !stale {
+		return injectionDecision{Reason: "AlreadyInjected"}
+	}
+	// 워크로드 템플릿에 남아있는 이전 주입 흔적(init container, 볼륨, agent ENV)을 먼저 제거한다.
+	// 다시 주입 대상이면 아래에서 깨끗한 상태로 재주입된다.
+	if stale {
+		logger.Info("Stripping stale APM artifacts carried by the pod template", "pod", podIdentifier)
+		stripInjectionArtifacts(pod)
+	}
+
+	if len(agents) == 0 {
+		// CR이 아직 생성 안 됐으면 주입 안 함
+		logger.V(1).Info("WhatapAgent CR not found, skipping APM injection", "pod", podIdentifier)
+		return injectionDecision{Reason: "NoWhatapAgent"}
+	}
+
+	cr, target, candidates := selectInstanceForPod(agents, pod, namespace)
+	// apm.whatap.com/inject-<language>: "true" forces injection regardless of the target selectors,
+	// for the pods the webhook namespaceSelector lets through
+	if lang := forcedInjectionLanguage(pod.Annotations); lang != "" && (target == nil || target.Language != lang) {
+		cr, target = selectInstanceForLanguage(agents, lang, namespace)
+		candidates = nil
+		if cr != nil {
+			logger.Info("APM injection forced by pod annotation", "pod", podIdentifier, "language", lang, "instance", cr.Name, "target", target.Name)
+		} else {
+			logger.Info("APM injection forced by pod annotation, but no single instance owns the namespace or the language", "pod", podIdentifier, "language", lang)
+		}
+	}
+	if cr == nil {
+		logger.V(1).Info("No matching targets found for pod, skipping APM injection", "pod", podIdentifier)
+		return injectionDecision{Reason: "NoMatchingTarget"}
+	}
+	decision := injectionDecision{Instance: cr.Name, Target: target.Name, Language: target.Language}
+	if cr.Spec.Features.Apm.Instrumentation.TargetMode(*target) == monitoringv2alpha1.InstrumentationModeUninstrument {
+		logger.V(1).Info("Target is being uninstrumented, skipping APM injection", "pod", podIdentifier, "instance", cr.Name, "target", target.Name)
+		markInjectionSkipped(pod, cr, target, "Uninstrumented")
+		decision.Reason = "Uninstrumented"
+		return decision
+	}
+
+	// Pod-level opt-out: apm.whatap.com/inject: "false" or apm.whatap.com/inject-<language>: "false"
+	for _, key := range []string{AnnotationInject, AnnotationInjectLanguagePrefix + target.Language} {
+		if annotationIsFalse(pod.Annotations, key) {
+			logger.V(1).Info("Pod opted out of APM injection by annotation", "pod", podIdentifier, "annotation", key)
+			markInjectionSkipped(pod, cr, target, "OptedOut")
+			decision.Reason = "OptedOut"
+			return decision
+		}
+	}
+
+	// apm.whatap.com/container-names (or target.ContainerSelector) limits injection to specific containers
+	containerNames := injectionContainerNames(pod.Annotations)
+	if len(selectContainersForInjection(&pod.Spec, target.ContainerSelector, containerNames)) == 0 {
+		logger.Info("No container in pod is selected for APM injection, skipping", "pod", podIdentifier, "containerNames", containerNames, "containerSelector", target.ContainerSelector)
+		markInjectionSkipped(pod, cr, target, "NoContainerSelected")
+		decision.Reason = "NoContainerSelected"
+		return decision
+	}
+	if len(candidates) > 1 {
+		// Conflict rule: the oldest instance wins
+		logger.Info("Multiple WhatapAgent instances match pod, using the oldest", "pod", podIdentifier, "instances", candidates, "selected", cr.Name)
+	}
+
 	defaultNS := config.GetWhatapDefaultNamespace()
 	if defaultNS == "" {
 		defaultNS = "whatap-monitoring"
 	}
-	ns := whatapAgentCustomResource.Spec.Features.K8sAgent.Namespace
+	// 기본 인스턴스가 아니면 operator 환경변수(기본 인스턴스의 라이선스)로 대체하지 않는다
+	resolved, err := resolveInstanceCredentials(ctx, secrets, defaultNS, cr, *target)
+	if err != nil {
+		logger.Error(err, "WhatapAgent instance has no credentials, skipping APM injection", "pod", podIdentifier, "instance", cr.Name, "target", target.Name)
+		markInjectionSkipped(pod, cr, target, "CredentialsNotFound")
+		decision.Reason = "CredentialsNotFound"
+		return decision
+	}
+	cr = resolved
+	ns := cr.Spec.Features.K8sAgent.Namespace
 	if ns == "" {
 		ns = defaultNS
-		whatapWebhookLogger.Info("default namespace is set to " + ns)
+		logger.V(1).Info("default namespace is set to " + ns)
 	}
 
-	// Check if APM instrumentation is enabled before processing targets
-	// Handle the case where instrumentation field might be omitted (zero value)
-	instrumentation := whatapAgentCustomResource.Spec.Features.Apm.Instrumentation
-	if !instrumentation.Enabled {
-		whatapWebhookLogger.V(1).Info("APM instrumentation is disabled, skipping APM injection", "pod", pod.GetNamespace()+"/"+pod.GetName())
-		return nil
+	// Target matched! Proceed with APM injection
+	logger.Info("Target matched for APM injection", "pod", podIdentifier, "instance", cr.Name, "target", target.Name, "language", target.Language)
+
+	namespaceName := pod.Namespace
+	if namespaceName == "" && namespace != nil {
+		namespaceName = namespace.Name
 	}
+	// The templated application name is set first so that the agent injection keeps it
+	decision.Warnings = applyAppNameTemplate(pod, namespaceName, *target, containerNames, logger)
+
+	// 4) PodSpec 변형 (initContainer, volumes, env 등)
+	patchPodTemplateSpec(&pod.Spec, *cr, *target, ns, containerNames, logger)
+	if target.UsesOtel() {
+		setOtelResource(pod, namespaceName, *target, containerNames, logger)
+	} else {
+		// Every image lookup of the request shares one deadline, however many containers
+		// the pod has
+		lookupCtx, cancel := context.WithTimeout(ctx, imageConfigTimeout)
+		defer cancel()
+		if target.Language == "python" && target.PythonStartMode == monitoringv2alpha1.PythonStartModeWrap {
+			decision.Warnings = append(decision.Warnings, wrapPythonCommands(lookupCtx, pod, *target, containerNames, images, logger)...)
+		}
+		// The runtime gate goes around the whatap-start-agent wrapper so it can skip it
+		decision.Warnings = append(decision.Warnings, gateRuntimeVersions(lookupCtx, pod, *target, containerNames, images, logger)...)
+	}
+	// 어노테이션 추가 (operator가 status.instrumentation 집계에 사용)
+	setInjectionMetadata(pod, cr, target, "true")
+	// Resolve version with default fallback
+	resolvedVersion := target.AgentVersion()
+	pod.Annotations[monitoringv2alpha1.AnnotationApmVersion] = resolvedVersion
+
+	logger.Info("Successfully injected Whatap APM into Pod", "pod", podIdentifier, "instance", cr.Name, "target", target.Name, "language", target.Language, "version", resolvedVersion)
+	decision.Injected = true
+	decision.Version = resolvedVersion
+	return decision
+}
 
-	// Additional safety check: if targets slice is nil or empty, nothing to process
-	if len(instrumentation.Targets) == 0 {
-		whatapWebhookLogger.V(1).Info("No APM targets configured, skipping APM injection", "pod", pod.GetNamespace()+"/"+pod.GetName())
-		return nil
+// podIdentifierOf returns namespace/name of pod, or namespace/generateName* for
+// pods created by controllers that have no name yet.
+func podIdentifierOf(pod *corev1.Pod) string {
+	if pod.GetName() != "" {
+		return pod.GetNamespace() + "/" + pod.GetName()
 	}
+	if pod.GetGenerateName() != "" {
+		return pod.GetNamespace() + "/" + pod.GetGenerateName() + "*"
+	}
+	return pod.GetNamespace() + "/unknown"
+}
 
-	// Check if APM agent is already injected
-	if pod.Annotations != nil {
-		if injected, exists := pod.Annotations["whatap-apm-injected"]; exists && injected == "true" {
-			whatapWebhookLogger.V(1).Info("APM agent already injected, skipping APM injection", "pod", pod.GetNamespace()+"/"+pod.GetName())
-			return nil
-		}
+// setInjectionMetadata labels pod as matched by target of cr and records the
+// instance, target and language. injected is "true" or "false".
+func setInjectionMetadata(pod *corev1.Pod, cr *monitoringv2alpha1.WhatapAgent, target *monitoringv2alpha1.TargetSpec, injected string) {
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string, 1)
+	}
+	pod.Labels[monitoringv2alpha1.InjectionLabelKey] = injected
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string, 5)
 	}
+	pod.Annotations[monitoringv2alpha1.AnnotationApmInjected] = injected
+	pod.Annotations[monitoringv2alpha1.AnnotationApmLanguage] = target.Language
+	pod.Annotations[monitoringv2alpha1.AnnotationApmInstance] = cr.Name
+	pod.Annotations[monitoringv2alpha1.AnnotationApmTarget] = target.Name
+}
 
-	podIdentifier := pod.GetNamespace() + "/" + pod.GetName()
-	if pod.GetName() == "" {
-		// Use namespace + generateName as alternative identifier for pods created by controllers
-		podIdentifier = pod.GetNamespace()
-		if pod.GetGenerateName() != "" {
-			podIdentifier += "/" + pod.GetGenerateName() + "*"
-		} else {
-			podIdentifier += "/unknown"
+// markInjectionSkipped records that pod matched target but was not instrumented.
+func markInjectionSkipped(pod *corev1.Pod, cr *monitoringv2alpha1.WhatapAgent, target *monitoringv2alpha1.TargetSpec, reason string) {
+	setInjectionMetadata(pod, cr, target, "false")
+	pod.Annotations[monitoringv2alpha1.AnnotationApmSkippedReason] = reason
+}
+
+// selectInstanceForPod returns the WhatapAgent instance and target that should
+// instrument pod. Instances are tried oldest first and the first enabled
+// target matching the pod and its namespace wins. candidates lists every
+// instance that had a matching target, so callers can report conflicts.
+func selectInstanceForPod(agents []monitoringv2alpha1.WhatapAgent, pod *corev1.Pod, namespace *corev1.Namespace) (*monitoringv2alpha1.WhatapAgent, *monitoringv2alpha1.TargetSpec, []string) {
+	sorted := make([]*monitoringv2alpha1.WhatapAgent, 0, len(agents))
+	for i := range agents {
+		if agents[i].DeletionTimestamp.IsZero() {
+			sorted = append(sorted, &agents[i])
 		}
 	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].IsOlderThan(sorted[j]) })
+
+	var selected *monitoringv2alpha1.WhatapAgent
+	var selectedTarget *monitoringv2alpha1.TargetSpec
+	var candidates []string
+	for _, cr := range sorted {
+		if target := findMatchingTarget(cr, pod, namespace); target != nil {
+			candidates = append(candidates, cr.Name)
+			if selected == nil {
+				selected, selectedTarget = cr, target
+			}
+		}
+	}
+	return selected, selectedTarget, candidates
+}
 
-	whatapWebhookLogger.V(1).Info("Processing APM injection for pod", "pod", podIdentifier, "targets", len(instrumentation.Targets))
-
-	injected := false
-	for _, target := range instrumentation.Targets {
+// findMatchingTarget returns the first enabled instrumentation target of cr
+// whose pod and namespace selectors match, or nil.
+func findMatchingTarget(cr *monitoringv2alpha1.WhatapAgent, pod *corev1.Pod, namespace *corev1.Namespace) *monitoringv2alpha1.TargetSpec {
+	// Handle the case where instrumentation field might be omitted (zero value)
+	instrumentation := cr.Spec.Features.Apm.Instrumentation
+	if !instrumentation.Enabled {
+		return nil
+	}
+	for i := range instrumentation.Targets {
+		target := &instrumentation.Targets[i]
 		if !target.Enabled {
-			whatapWebhookLogger.V(2).Info("Target is disabled, skipping", "pod", podIdentifier, "target", target.Name)
 			continue
 		}
-
-		// Check if pod labels match the PodSelector
-		if !matchesSelector(pod.Labels, target.PodSelector) {
-			whatapWebhookLogger.V(2).Info("Pod labels do not match target selector, skipping", "pod", podIdentifier, "target", target.Name, "podLabels", pod.Labels, "targetSelector", target.PodSelector)
+		if reason := targetMismatch(target, pod, namespace); reason != "" {
+			whatapWebhookLogger.V(2).Info("Target does not match pod, skipping", "instance", cr.Name, "target", target.Name, "reason", reason,
+				"podLabels", pod.Labels, "targetSelector", target.PodSelector, "namespaceLabels", namespace.Labels, "targetNamespaceSelector", target.NamespaceSelector)
 			continue
 		}
+		return target
+	}
+	return nil
+}
 
-		// Get the namespace object to check its labels
-		var namespace corev1.Namespace
-		if err := d.client.Get(ctx, client.ObjectKey{Name: pod.Namespace}, &namespace); err != nil {
-			whatapWebhookLogger.Error(err, "Failed to get namespace", "namespace", pod.Namespace)
-			continue
-		}
+// targetMismatch returns why the selectors of target do not match pod and its
+// namespace, or "" when they match.
+func targetMismatch(target *monitoringv2alpha1.TargetSpec, pod *corev1.Pod, namespace *corev1.Namespace) string {
+	// Check if pod labels match the PodSelector
+	if !matchesSelector(pod.Labels, target.PodSelector) {
+		return "pod labels do not match podSelector"
+	}
+	// Check if namespace matches the NamespaceSelector
+	if !matchesNamespaceSelector(pod.Namespace, namespace.Labels, target.NamespaceSelector) {
+		return "namespace does not match namespaceSelector"
+	}
+	return ""
+}
 
-		// Check if namespace matches the NamespaceSelector
-		if !matchesNamespaceSelector(pod.Namespace, namespace.Labels, target.NamespaceSelector) {
-			whatapWebhookLogger.V(2).Info("Namespace does not match target selector, skipping", "pod", podIdentifier, "target", target.Name, "namespace", pod.Namespace, "namespaceLabels", namespace.Labels, "targetNamespaceSelector", target.NamespaceSelector)
-			continue
+// selectInstanceForLanguage returns the instance and target used when a pod
+// forces injection of lang by annotation. The instance owning the pod's namespace
+// wins (oldest instance first): its enabled target of that language matching the
+// namespace, else its first enabled target of that language, else a default
+// target. Without an owner, an instance is only picked when it is the only one
+// declaring the language (or, when none does, the only one with instrumentation
+// enabled); otherwise nil is returned rather than guessing.
+func selectInstanceForLanguage(agents []monitoringv2alpha1.WhatapAgent, lang string, namespace *corev1.Namespace) (*monitoringv2alpha1.WhatapAgent, *monitoringv2alpha1.TargetSpec) {
+	sorted := make([]*monitoringv2alpha1.WhatapAgent, 0, len(agents))
+	for i := range agents {
+		if agents[i].DeletionTimestamp.IsZero() && agents[i].Spec.Features.Apm.Instrumentation.Enabled {
+			sorted = append(sorted, &agents[i])
 		}
+	}
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].IsOlderThan(sorted[j]) })
 
-		// Target matched! Proceed with APM injection
-		whatapWebhookLogger.Info("Target matched for APM injection", "pod", podIdentifier, "target", target.Name, "language", target.Language)
-
-		// 4) PodSpec 변형 (initContainer, volumes, env 등)
-		patchPodTemplateSpec(&pod.Spec, whatapAgentCustomResource, target, ns, whatapWebhookLogger)
-		if pod.Annotations == nil {
-			pod.Annotations = map[string]string{}
+	var namespaceName string
+	var namespaceLabels map[string]string
+	if namespace != nil {
+		namespaceName, namespaceLabels = namespace.Name, namespace.Labels
+	}
+	matchesNamespace := func(target *monitoringv2alpha1.TargetSpec) bool {
+		return target.Enabled && matchesNamespaceSelector(namespaceName, namespaceLabels, target.NamespaceSelector)
+	}
+	defaultTarget := &monitoringv2alpha1.TargetSpec{Name: "annotation-" + lang, Enabled: true, Language: lang}
+
+	// 1) a target of the language selecting the namespace
+	for _, cr := range sorted {
+		targets := cr.Spec.Features.Apm.Instrumentation.Targets
+		for i := range targets {
+			if targets[i].Language == lang && matchesNamespace(&targets[i]) {
+				return cr, &targets[i]
+			}
+		}
+	}
+	// 2) the instance whose targets select the namespace for another language
+	var declaring []*monitoringv2alpha1.WhatapAgent
+	var declared []*monitoringv2alpha1.TargetSpec
+	for _, cr := range sorted {
+		targets := cr.Spec.Features.Apm.Instrumentation.Targets
+		var langTarget *monitoringv2alpha1.TargetSpec
+		owner := false
+		for i := range targets {
+			if targets[i].Enabled && targets[i].Language == lang && langTarget == nil {
+				langTarget = &targets[i]
+			}
+			owner = owner || matchesNamespace(&targets[i])
 		}
-		// 어노테이션 추가
-		if pod.Annotations == nil {
-			pod.Annotations = make(map[string]string, 3)
+		if owner {
+			if langTarget != nil {
+				return cr, langTarget
+			}
+			return cr, defaultTarget
 		}
-		pod.Annotations["whatap-apm-injected"] = "true"
-		pod.Annotations["whatap-apm-language"] = target.Language
-		// Resolve version with default fallback
-		resolvedVersion := target.WhatapApmVersions[target.Language]
-		if resolvedVersion == "" {
-			resolvedVersion = "latest"
+		if langTarget != nil {
+			declaring, declared = append(declaring, cr), append(declared, langTarget)
 		}
-		pod.Annotations["whatap-apm-version"] = resolvedVersion
+	}
+	// 3) no owner: only an unambiguous instance
+	switch {
+	case len(declaring) == 1:
+		return declaring[0], declared[0]
+	case len(declaring) == 0 && len(sorted) == 1:
+		return sorted[0], defaultTarget
+	}
+	return nil, nil
+}
 
-		whatapWebhookLogger.Info("Successfully injected Whatap APM into Pod", "pod", podIdentifier, "target", target.Name, "language", target.Language, "version", resolvedVersion)
-		injected = true
-		break
+// forcedInjectionLanguage returns the language whose apm.whatap.com/inject-<language>
+// annotation is "true", or "" when none is set. The webhook only sees pods of the namespaces
+// the operator derives from the targets (see InjectionWebhookSpec), so the annotation cannot
+// force injection outside of them.
+func forcedInjectionLanguage(annotations map[string]string) string {
+	for _, lang := range supportedLanguages {
+		if annotationIsTrue(annotations, AnnotationInjectLanguagePrefix+lang) {
+			return lang
+		}
 	}
+	return ""
+}
 
-	if !injected {
-		whatapWebhookLogger.V(1).Info("No matching targets found for pod, skipping APM injection", "pod", podIdentifier)
+// injectionContainerNames parses apm.whatap.com/container-names into a list of
+// container names. nil means every container is instrumented.
+func injectionContainerNames(annotations map[string]string) []string {
+	var names []string
+	for _, name := range strings.Split(annotations[AnnotationInjectContainerNames], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
 	}
-	return nil
+	return names
 }
 
 // WhatapAgentCredentialDefaulter handles defaulting for WhatapAgent resources
@@ -230,7 +506,8 @@ func (d *WhatapAgentCredentialDefaulter) Default(ctx context.Context, obj runtim
 }
 
 type WhatapAgentCustomValidator struct {
-	client client.Client
+	client    client.Client
+	namespace string // OpenAgent 가 TLS/인증 Secret 을 읽는 네임스페이스
 }
 
 var _ webhook.CustomValidator = &WhatapAgentCustomValidator{}
@@ -255,12 +532,41 @@ func (v *WhatapAgentCustomValidator) ValidateCreate(ctx context.Context, obj run
 	//	return nil, err
 	//}
 
+	// Validate container selectors (regex must compile, otherwise no container would ever match)
+	if err := validateContainerSelectors(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate otel targets (languages, endpoint) the auto-instrumentation cannot run with
+	if err := validateOtelTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate dotnet targets, whose CLR profiler has no default
+	if err := validateDotnetTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate appNameTemplates, which would otherwise only fail pod by pod
+	if err := validateAppNameTemplates(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate OpenAgent targets (relabel configs, intervals) the OpenAgent would fail to load
+	if err := validateOpenAgentTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
 	// Validate agent configurations
-	if err := validateAgentConfigurations(whatapagent); err != nil {
+	others, err := v.listOtherAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAgentConfigurations(whatapagent, others); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return openAgentTargetWarnings(ctx, v.client, v.namespace, whatapagent), nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type WhatapAgent.
@@ -283,12 +589,41 @@ func (v *WhatapAgentCustomValidator) ValidateUpdate(ctx context.Context, oldObj,
 	//	return nil, err
 	//}
 
+	// Validate container selectors (regex must compile, otherwise no container would ever match)
+	if err := validateContainerSelectors(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate otel targets (languages, endpoint) the auto-instrumentation cannot run with
+	if err := validateOtelTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate dotnet targets, whose CLR profiler has no default
+	if err := validateDotnetTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate appNameTemplates, which would otherwise only fail pod by pod
+	if err := validateAppNameTemplates(whatapagent); err != nil {
+		return nil, err
+	}
+
+	// Validate OpenAgent targets (relabel configs, intervals) the OpenAgent would fail to load
+	if err := validateOpenAgentTargets(whatapagent); err != nil {
+		return nil, err
+	}
+
 	// Validate agent configurations
-	if err := validateAgentConfigurations(whatapagent); err != nil {
+	others, err := v.listOtherAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAgentConfigurations(whatapagent, others); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return openAgentTargetWarnings(ctx, v.client, v.namespace, whatapagent), nil
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type WhatapAgent.
@@ -323,6 +658,19 @@ func validateRequiredFields(whatapagent *monitoringv2alpha1.WhatapAgent) error {
 	return nil
 }
 
+// validateContainerSelectors checks that every target's containerSelector.nameRegex compiles
+func validateContainerSelectors(whatapagent *monitoringv2alpha1.WhatapAgent) error {
+	for i, target := range whatapagent.Spec.Features.Apm.Instrumentation.Targets {
+		if target.ContainerSelector == nil || target.ContainerSelector.NameRegex == "" {
+			continue
+		}
+		if _, err := regexp.Compile(target.ContainerSelector.NameRegex); err != nil {
+			return fmt.Errorf("target[%d] %q: invalid containerSelector.nameRegex: %w", i, target.Name, err)
+		}
+	}
+	return nil
+}
+
 // validateApmTargets validates the APM targets configuration
 func validateApmTargets(whatapagent *monitoringv2alpha1.WhatapAgent) error {
 	for i, target := range whatapagent.Spec.Features.Apm.Instrumentation.Targets {
@@ -358,12 +706,34 @@ func validateApmTargets(whatapagent *monitoringv2alpha1.WhatapAgent) error {
 	return nil
 }
 
-// validateAgentConfigurations validates the agent configurations
-func validateAgentConfigurations(whatapagent *monitoringv2alpha1.WhatapAgent) error {
-	// Validate K8sAgent configuration
-	whatapAgentCrName := whatapagent.ObjectMeta.Name
-	if whatapAgentCrName != "whatap" {
-		return fmt.Errorf("agent configuration is not valid")
+// validateAgentConfigurations validates the agent configurations against the
+// other WhatapAgent instances in the cluster. Two instances may not run node
+// agents on the same nodes or instrument the same namespace by name.
+func validateAgentConfigurations(whatapagent *monitoringv2alpha1.WhatapAgent, others []monitoringv2alpha1.WhatapAgent) error {
+	var conflicts []string
+	for i := range others {
+		other := &others[i]
+		if other.Name == whatapagent.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if monitoringv2alpha1.NodeAgentsOverlap(whatapagent, other) {
+			conflicts = append(conflicts, fmt.Sprintf("nodeAgent overlaps with WhatapAgent %q (use disjoint nodeAgent.nodeSelector or required node affinity values)", other.Name))
+		}
+		if nss := monitoringv2alpha1.OverlappingNamespaces(whatapagent, other); len(nss) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("instrumentation namespaces %v are also claimed by WhatapAgent %q", nss, other.Name))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("WhatapAgent %q conflicts with existing instances: %s", whatapagent.Name, strings.Join(conflicts, "; "))
 	}
 	return nil
 }
+
+// listOtherAgents returns every WhatapAgent in the cluster.
+func (v *WhatapAgentCustomValidator) listOtherAgents(ctx context.Context) ([]monitoringv2alpha1.WhatapAgent, error) {
+	var agents monitoringv2alpha1.WhatapAgentList
+	if err := v.client.List(ctx, &agents); err != nil {
+		return nil, fmt.Errorf("failed to list WhatapAgents: %w", err)
+	}
+	return agents.Items, nil
+}
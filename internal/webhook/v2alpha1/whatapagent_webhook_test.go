@@ -0,0 +1,289 @@
+package v2alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	monitoringv2alpha1 "github.com/whatap/whatap-operator/api/v2alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newAgent(name string, created time.Time, namespaces ...string) monitoringv2alpha1.WhatapAgent {
+	return monitoringv2alpha1.WhatapAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Spec: monitoringv2alpha1.WhatapAgentSpec{
+			Features: monitoringv2alpha1.FeaturesSpec{
+				Apm: monitoringv2alpha1.ApmSpec{
+					Instrumentation: monitoringv2alpha1.InstrumentationSpec{
+						Enabled: true,
+						Targets: []monitoringv2alpha1.TargetSpec{
+							{
+								Name:              name + "-java",
+								Enabled:           true,
+								Language:          "java",
+								NamespaceSelector: monitoringv2alpha1.NamespaceSelector{MatchNames: namespaces},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSelectInstanceForPod(t *testing.T) {
+	now := time.Now()
+	agents := []monitoringv2alpha1.WhatapAgent{
+		newAgent("tenant", now, "tenant-a"),
+		newAgent("platform", now.Add(-time.Hour), "kube-system", "tenant-a"),
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "tenant-a"}}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}}
+
+	cr, target, candidates := selectInstanceForPod(agents, pod, ns)
+	if cr == nil || cr.Name != "platform" {
+		t.Fatalf("Expected the oldest instance to win, got %v", cr)
+	}
+	if target.Name != "platform-java" {
+		t.Errorf("Expected target of the selected instance, got %q", target.Name)
+	}
+	if len(candidates) != 2 {
+		t.Errorf("Expected both instances reported as candidates, got %v", candidates)
+	}
+
+	pod.Namespace = "other"
+	ns.Name = "other"
+	if cr, _, _ := selectInstanceForPod(agents, pod, ns); cr != nil {
+		t.Errorf("Expected no instance for an unclaimed namespace, got %q", cr.Name)
+	}
+}
+
+func TestValidateAgentConfigurations_Conflicts(t *testing.T) {
+	now := time.Now()
+	existing := []monitoringv2alpha1.WhatapAgent{newAgent("whatap", now, "shop")}
+
+	// Non-default names are allowed when nothing overlaps
+	tenant := newAgent("tenant", now, "tenant-a")
+	if err := validateAgentConfigurations(&tenant, existing); err != nil {
+		t.Errorf("Expected no conflict, got %v", err)
+	}
+
+	// Same namespace claimed by name
+	clash := newAgent("tenant", now, "shop")
+	if err := validateAgentConfigurations(&clash, existing); err == nil || !strings.Contains(err.Error(), "shop") {
+		t.Errorf("Expected namespace conflict, got %v", err)
+	}
+
+	// Node agents on the same nodes
+	existing[0].Spec.Features.K8sAgent.NodeAgent.Enabled = true
+	tenant.Spec.Features.K8sAgent.NodeAgent.Enabled = true
+	if err := validateAgentConfigurations(&tenant, existing); err == nil || !strings.Contains(err.Error(), "nodeAgent") {
+		t.Errorf("Expected node agent conflict, got %v", err)
+	}
+
+	// Disjoint node selectors resolve the node conflict
+	existing[0].Spec.Features.K8sAgent.NodeAgent.NodeSelector = map[string]string{"pool": "platform"}
+	tenant.Spec.Features.K8sAgent.NodeAgent.NodeSelector = map[string]string{"pool": "tenant"}
+	if err := validateAgentConfigurations(&tenant, existing); err != nil {
+		t.Errorf("Expected disjoint node selectors to be accepted, got %v", err)
+	}
+
+	// Required node affinity separates the agents as well, tolerations do not
+	tenant.Spec.Features.K8sAgent.NodeAgent.NodeSelector = nil
+	tenant.Spec.Features.K8sAgent.NodeAgent.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+	if err := validateAgentConfigurations(&tenant, existing); err == nil {
+		t.Errorf("Expected tolerations alone not to separate the node agents")
+	}
+	tenant.Spec.Features.K8sAgent.NodeAgent.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"platform"}}}},
+		}},
+	}}
+	if err := validateAgentConfigurations(&tenant, existing); err != nil {
+		t.Errorf("Expected a node affinity excluding the other pool to be accepted, got %v", err)
+	}
+	tenant.Spec.Features.K8sAgent.NodeAgent.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = append(
+		tenant.Spec.Features.K8sAgent.NodeAgent.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms,
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpExists}}})
+	if err := validateAgentConfigurations(&tenant, existing); err == nil {
+		t.Errorf("Expected an alternative affinity term reaching the other pool to overlap")
+	}
+}
+
+func TestSelectInstanceForLanguage(t *testing.T) {
+	now := time.Now()
+	agents := []monitoringv2alpha1.WhatapAgent{
+		newAgent("tenant", now, "tenant-a"),
+		newAgent("platform", now.Add(-time.Hour), "kube-system"),
+	}
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	// The instance whose target selects the namespace wins over the older one
+	cr, target := selectInstanceForLanguage(agents, "java", namespace("tenant-a"))
+	if cr == nil || cr.Name != "tenant" || target.Name != "tenant-java" {
+		t.Fatalf("Expected the java target of the instance owning tenant-a, got %v / %v", cr, target)
+	}
+
+	// The owner of the namespace gets a default target for a language it does not declare
+	cr, target = selectInstanceForLanguage(agents, "python", namespace("tenant-a"))
+	if cr == nil || cr.Name != "tenant" || target.Language != "python" || !target.Enabled {
+		t.Fatalf("Expected a default python target on the owning instance, got %v / %v", cr, target)
+	}
+
+	// Nobody owns the namespace and both instances declare java: ambiguous
+	if cr, _ := selectInstanceForLanguage(agents, "java", namespace("shop")); cr != nil {
+		t.Errorf("Expected no instance for an unowned namespace with two java instances, got %q", cr.Name)
+	}
+
+	// ... unless only one instance declares the language
+	agents[1].Spec.Features.Apm.Instrumentation.Targets[0].Language = "nodejs"
+	cr, target = selectInstanceForLanguage(agents, "java", namespace("shop"))
+	if cr == nil || cr.Name != "tenant" || target.Name != "tenant-java" {
+		t.Fatalf("Expected the only instance declaring java, got %v / %v", cr, target)
+	}
+
+	// With one instrumenting instance left, it gets a default target
+	agents[0].Spec.Features.Apm.Instrumentation.Enabled = false
+	cr, target = selectInstanceForLanguage(agents, "python", namespace("shop"))
+	if cr == nil || cr.Name != "platform" || target.Language != "python" {
+		t.Fatalf("Expected a default python target on the only instrumenting instance, got %v / %v", cr, target)
+	}
+
+	agents[1].Spec.Features.Apm.Instrumentation.Enabled = false
+	if cr, _ := selectInstanceForLanguage(agents, "java", namespace("tenant-a")); cr != nil {
+		t.Errorf("Expected no instance when instrumentation is disabled everywhere, got %q", cr.Name)
+	}
+}
+
+func TestInjectionAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		AnnotationInject: "False",
+		AnnotationInjectLanguagePrefix + "nodejs": "true",
+		AnnotationInjectContainerNames:            " app, ,worker ",
+	}
+	if !annotationIsFalse(annotations, AnnotationInject) {
+		t.Errorf("Expected %s=False to opt out", AnnotationInject)
+	}
+	if annotationIsFalse(map[string]string{AnnotationInject: "maybe"}, AnnotationInject) {
+		t.Errorf("Unparsable value must not opt out")
+	}
+	if lang := forcedInjectionLanguage(annotations); lang != "nodejs" {
+		t.Errorf("Expected forced language nodejs, got %q", lang)
+	}
+	names := injectionContainerNames(annotations)
+	if strings.Join(names, ",") != "app,worker" {
+		t.Errorf("Expected container names [app worker], got %v", names)
+	}
+	if injectionContainerNames(nil) != nil {
+		t.Errorf("Expected no container filter without the annotation")
+	}
+}
+
+func TestPatchPodTemplateSpec_ContainerNames(t *testing.T) {
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "app"}, {Name: "envoy"}},
+	}
+	target := monitoringv2alpha1.TargetSpec{Name: "t", Enabled: true, Language: "java"}
+
+	patchPodTemplateSpec(&podSpec, monitoringv2alpha1.WhatapAgent{}, target, "whatap-monitoring", []string{"app"}, logr.Discard())
+
+	if len(podSpec.Containers[0].VolumeMounts) != 1 || len(podSpec.Containers[0].Env) == 0 {
+		t.Errorf("Expected container app to be instrumented, got %+v", podSpec.Containers[0])
+	}
+	if len(podSpec.Containers[1].VolumeMounts) != 0 || len(podSpec.Containers[1].Env) != 0 {
+		t.Errorf("Expected container envoy to be left untouched, got %+v", podSpec.Containers[1])
+	}
+}
+
+func TestPatchPodTemplateSpec_PinnedImage(t *testing.T) {
+	cr := newAgent("whatap", time.Now(), "shop")
+	cr.Generation = 2
+	cr.Spec.ImageRegistry = "harbor.example.com/ecr-proxy"
+	cr.Spec.ImagePullPolicy = corev1.PullIfNotPresent
+	cr.Spec.PinImageDigests = true
+	cr.Status.ImageDigests = &monitoringv2alpha1.ImageDigestStatus{
+		ObservedGeneration: 2,
+		Digests:            map[string]string{"harbor.example.com/ecr-proxy/whatap/apm-init-java:latest": "sha256:1111"},
+	}
+	podSpec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}
+
+	patchPodTemplateSpec(&podSpec, cr, cr.Spec.Features.Apm.Instrumentation.Targets[0], "whatap-monitoring", nil, logr.Discard())
+
+	if len(podSpec.InitContainers) != 1 {
+		t.Fatalf("Expected the APM init container, got %+v", podSpec.InitContainers)
+	}
+	init := podSpec.InitContainers[0]
+	if init.Image != "harbor.example.com/ecr-proxy/whatap/apm-init-java@sha256:1111" || init.ImagePullPolicy != corev1.PullIfNotPresent {
+		t.Errorf("Expected the mirrored image pinned by digest with pull policy IfNotPresent, got %s (%s)", init.Image, init.ImagePullPolicy)
+	}
+}
+
+func TestMarkInjectionSkipped(t *testing.T) {
+	cr := newAgent("tenant", time.Now(), "tenant-a")
+	target := &cr.Spec.Features.Apm.Instrumentation.Targets[0]
+	pod := &corev1.Pod{}
+
+	markInjectionSkipped(pod, &cr, target, "OptedOut")
+
+	if pod.Labels[monitoringv2alpha1.InjectionLabelKey] != "false" {
+		t.Errorf("Expected skipped pods to be labelled, got %v", pod.Labels)
+	}
+	for key, want := range map[string]string{
+		monitoringv2alpha1.AnnotationApmInjected:      "false",
+		monitoringv2alpha1.AnnotationApmInstance:      "tenant",
+		monitoringv2alpha1.AnnotationApmTarget:        "tenant-java",
+		monitoringv2alpha1.AnnotationApmSkippedReason: "OptedOut",
+	} {
+		if pod.Annotations[key] != want {
+			t.Errorf("Expected annotation %s=%s, got %q", key, want, pod.Annotations[key])
+		}
+	}
+}
+
+func TestResolveInstanceCredentials(t *testing.T) {
+	ctx := context.Background()
+	tenant := newAgent("tenant", time.Now(), "tenant-a")
+	target := tenant.Spec.Features.Apm.Instrumentation.Targets[0]
+
+	// Without its own Secret a non-default instance is not injected with the operator credentials
+	if _, err := resolveInstanceCredentials(ctx, newFakeClient(), "whatap-monitoring", &tenant, target); err == nil {
+		t.Fatalf("Expected an error without the credentials Secret of the instance")
+	}
+	if env := getWhatapLicenseEnvVar(tenant, target); env.Value != "" {
+		t.Errorf("Expected no fallback to the operator license for a non-default instance, got %q", env.Value)
+	}
+
+	c := newFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "whatap-monitoring", Name: "tenant-whatap-credentials"},
+		Data: map[string][]byte{
+			"WHATAP_LICENSE": []byte("tenant-license"),
+			"WHATAP_HOST":    []byte("10.0.0.2"),
+			"WHATAP_PORT":    []byte("6600"),
+		},
+	})
+	resolved, err := resolveInstanceCredentials(ctx, c, "whatap-monitoring", &tenant, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Spec.License != "tenant-license" || resolved.Spec.Host != "10.0.0.2" || resolved.Spec.Port != "6600" {
+		t.Errorf("Expected the credentials of the instance Secret, got %+v", resolved.Spec)
+	}
+	if tenant.Spec.License != "" {
+		t.Errorf("Expected the instance in the list to be left unchanged")
+	}
+	if env := getWhatapLicenseEnvVar(*resolved, target); env.Value != "tenant-license" {
+		t.Errorf("Expected the instance license to be injected, got %q", env.Value)
+	}
+
+	// The default instance keeps using the operator credentials
+	def := newAgent(monitoringv2alpha1.DefaultWhatapAgentName, time.Now())
+	if got, err := resolveInstanceCredentials(ctx, nil, "whatap-monitoring", &def, target); err != nil || got != &def {
+		t.Errorf("Expected the default instance to be returned as is, got %v %v", got, err)
+	}
+}
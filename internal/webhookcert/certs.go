@@ -0,0 +1,175 @@
+package webhookcert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	// caValidity is the lifetime of a generated CA
+	caValidity = 10 * 365 * 24 * time.Hour
+	// serverValidity is the lifetime of a generated serving certificate
+	serverValidity = 365 * 24 * time.Hour
+)
+
+// Bundle is the webhook serving certificate together with the CA bundle the API
+// server uses to verify it. CAKey is only known in self-signed mode.
+type Bundle struct {
+	// CACert holds one or more PEM encoded CA certificates; the first one signs ServerCert
+	CACert     []byte
+	CAKey      []byte
+	ServerCert []byte
+	ServerKey  []byte
+}
+
+// Equal reports whether b and other hold the same certificates and keys.
+func (b Bundle) Equal(other Bundle) bool {
+	return bytes.Equal(b.CACert, other.CACert) && bytes.Equal(b.CAKey, other.CAKey) &&
+		bytes.Equal(b.ServerCert, other.ServerCert) && bytes.Equal(b.ServerKey, other.ServerKey)
+}
+
+// DNSNames returns the names the serving certificate of serviceName.namespace must cover.
+func DNSNames(serviceName, namespace string) []string {
+	return []string{
+		serviceName + "." + namespace + ".svc",
+		serviceName + "." + namespace + ".svc.cluster.local",
+	}
+}
+
+// generateCA creates a self-signed CA valid from now.
+func generateCA(now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: "whatap-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// generateServerCert issues a serving certificate for dnsNames signed by the CA. The
+// certificate never outlives the CA.
+func generateServerCert(caCertPEM, caKeyPEM []byte, dnsNames []string, now time.Time) (certPEM, keyPEM []byte, err error) {
+	caCert, err := parseCert(caCertPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	caKey, err := parseKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse CA key: %w", err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	notAfter := now.Add(serverValidity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// parseCert parses the first certificate of a PEM bundle.
+func parseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("CA key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// validFor reports whether the certificate in certPEM is valid at now and stays valid
+// for at least margin.
+func validFor(certPEM []byte, now time.Time, margin time.Duration) bool {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return false
+	}
+	return !now.Before(cert.NotBefore) && now.Add(margin).Before(cert.NotAfter)
+}
+
+// coversNames reports whether the certificate in certPEM is valid for every name.
+func coversNames(certPEM []byte, names []string) bool {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// unexpiredCerts returns the PEM blocks of bundle that are still valid at now.
+func unexpiredCerts(bundle []byte, now time.Time) []byte {
+	var out []byte
+	for rest := bundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return out
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) {
+			continue
+		}
+		out = append(out, pem.EncodeToMemory(block)...)
+	}
+}
@@ -0,0 +1,391 @@
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ModeSelfSigned keeps a self-signed CA in a Secret and rotates the serving certificate
+	ModeSelfSigned = "self-signed"
+	// ModeCertManager serves the certificate cert-manager issues into a Secret
+	ModeCertManager = "cert-manager"
+
+	// DefaultSecretName is the Secret holding the self-signed CA and serving certificate
+	DefaultSecretName = "whatap-webhook-certificate"
+	// DefaultCertManagerSecretName matches config/certmanager/certificate-webhook.yaml
+	DefaultCertManagerSecretName = "webhook-server-cert"
+
+	// Secret keys in self-signed mode (kept from earlier releases)
+	secretKeyCABundle = "cert.pem"
+	secretKeyCAKey    = "key.pem"
+	// cert-manager stores the issuing CA under ca.crt
+	secretKeyCertManagerCA = "ca.crt"
+
+	// files the webhook server reads from CertDir
+	fileCert = "tls.crt"
+	fileKey  = "tls.key"
+	fileCA   = "ca.crt"
+
+	defaultRenewBefore   = 30 * 24 * time.Hour
+	defaultCheckInterval = time.Hour
+	initPollInterval     = 5 * time.Second
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// Manager provides the webhook serving certificate. It loads (self-signed mode: and
+// issues) the certificate from a Secret, writes it to CertDir where the webhook server
+// picks up changes without a restart, and keeps the caBundle of the webhook
+// configuration in sync. Every replica runs it, so all of them serve the same certificate.
+type Manager struct {
+	// Client must read from the API server: Init runs before the manager cache starts
+	Client client.Client
+	// Mode is ModeSelfSigned (default) or ModeCertManager
+	Mode        string
+	Namespace   string
+	ServiceName string
+	// SecretName holds the certificate; it is owned by the operator in self-signed mode
+	// and issued by cert-manager in cert-manager mode
+	SecretName string
+	// Issuer ("Issuer/<name>" or "ClusterIssuer/<name>") makes the operator create the
+	// cert-manager Certificate CertificateName itself. Without it the Certificate is
+	// expected to be deployed alongside the operator (config/certmanager).
+	Issuer          string
+	CertificateName string
+	CertDir         string
+	// WebhookConfigurationName is the MutatingWebhookConfiguration whose caBundle is kept in sync
+	WebhookConfigurationName string
+	// RenewBefore is how long before expiry a self-signed certificate is reissued
+	RenewBefore time.Duration
+	// CheckInterval is how often the Secret is checked for rotation
+	CheckInterval time.Duration
+
+	mu      sync.RWMutex
+	current Bundle
+}
+
+// Bundle returns the certificates currently served.
+func (m *Manager) Bundle() Bundle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Ready returns an error until a certificate has been loaded.
+func (m *Manager) Ready() error {
+	if len(m.Bundle().ServerCert) == 0 {
+		return errors.New("webhook certificate is not loaded yet")
+	}
+	return nil
+}
+
+// SelfSigned reports whether the operator owns the certificate Secret.
+func (m *Manager) SelfSigned() bool {
+	return m.Mode != ModeCertManager
+}
+
+// SecretData returns the Secret contents for b in self-signed mode.
+func (b Bundle) SecretData() map[string][]byte {
+	return map[string][]byte{
+		secretKeyCABundle:       b.CACert,
+		secretKeyCAKey:          b.CAKey,
+		corev1.TLSCertKey:       b.ServerCert,
+		corev1.TLSPrivateKeyKey: b.ServerKey,
+	}
+}
+
+// Init loads the certificate and writes it to CertDir. It retries until ctx is done,
+// e.g. while cert-manager has not issued the certificate yet.
+func (m *Manager) Init(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("webhook-cert")
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, initPollInterval, true, func(ctx context.Context) (bool, error) {
+		if lastErr = m.refresh(ctx, time.Now()); lastErr != nil {
+			logger.Info("Webhook certificate not ready, retrying", "mode", m.Mode, "secret", m.SecretName, "error", lastErr.Error())
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// Start implements manager.Runnable and rotates the certificate until ctx is done.
+func (m *Manager) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("webhook-cert")
+	interval := m.CheckInterval
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.refresh(ctx, time.Now()); err != nil {
+				logger.Error(err, "Failed to refresh webhook certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: every replica serves webhooks.
+func (m *Manager) NeedLeaderElection() bool {
+	return false
+}
+
+// refresh loads the current certificate, issuing a new one when needed, and applies it.
+func (m *Manager) refresh(ctx context.Context, now time.Time) error {
+	var b Bundle
+	var err error
+	if m.SelfSigned() {
+		b, err = m.loadOrIssue(ctx, now)
+	} else {
+		b, err = m.loadIssued(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if b.Equal(m.Bundle()) {
+		return nil
+	}
+
+	if err := m.writeFiles(b); err != nil {
+		return err
+	}
+	if err := m.updateWebhookConfiguration(ctx, b.CACert); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.current = b
+	m.mu.Unlock()
+	log.FromContext(ctx).WithName("webhook-cert").Info("Webhook certificate loaded", "mode", m.Mode, "secret", m.SecretName, "notAfter", notAfter(b.ServerCert))
+	return nil
+}
+
+// loadOrIssue returns the self-signed certificate from the Secret, replacing the CA or the
+// serving certificate when missing, invalid or due for renewal.
+func (m *Manager) loadOrIssue(ctx context.Context, now time.Time) (Bundle, error) {
+	secret := &corev1.Secret{}
+	err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.SecretName}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return Bundle{}, err
+	}
+	exists := err == nil
+
+	b := Bundle{
+		CACert:     secret.Data[secretKeyCABundle],
+		CAKey:      secret.Data[secretKeyCAKey],
+		ServerCert: secret.Data[corev1.TLSCertKey],
+		ServerKey:  secret.Data[corev1.TLSPrivateKeyKey],
+	}
+	if !exists {
+		// Deleted while running (e.g. with the last WhatapAgent): keep serving the same CA
+		b = m.Bundle()
+	}
+
+	renewBefore := m.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = defaultRenewBefore
+	}
+	issued := false
+	if _, err := parseKey(b.CAKey); err != nil || !validFor(b.CACert, now, renewBefore) {
+		caCert, caKey, err := generateCA(now)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("generate CA: %w", err)
+		}
+		// Keep trusting the previous CA until it expires: other replicas may still
+		// serve a certificate it signed
+		b.CACert = append(caCert, unexpiredCerts(b.CACert, now)...)
+		b.CAKey = caKey
+		b.ServerCert = nil
+		issued = true
+	}
+	dnsNames := DNSNames(m.ServiceName, m.Namespace)
+	if issued || len(b.ServerKey) == 0 || !validFor(b.ServerCert, now, renewBefore) || !coversNames(b.ServerCert, dnsNames) {
+		b.ServerCert, b.ServerKey, err = generateServerCert(b.CACert, b.CAKey, dnsNames, now)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("issue serving certificate: %w", err)
+		}
+		issued = true
+	}
+	if !issued && exists {
+		return b, nil
+	}
+
+	secret.Data = b.SecretData()
+	if exists {
+		// A conflict means another replica rotated first; the next refresh picks up its certificate
+		err = m.Client.Update(ctx, secret)
+	} else {
+		secret.ObjectMeta = metav1.ObjectMeta{
+			Name:      m.SecretName,
+			Namespace: m.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "whatap-operator",
+				"app.kubernetes.io/managed-by": "whatap-operator",
+			},
+		}
+		err = m.Client.Create(ctx, secret)
+	}
+	if err != nil {
+		return Bundle{}, fmt.Errorf("store webhook certificate in Secret %s: %w", m.SecretName, err)
+	}
+	return b, nil
+}
+
+// loadIssued returns the certificate cert-manager issued into the Secret, creating the
+// Certificate first when an Issuer is configured.
+func (m *Manager) loadIssued(ctx context.Context) (Bundle, error) {
+	if m.Issuer != "" {
+		if err := m.ensureCertificate(ctx); err != nil {
+			return Bundle{}, err
+		}
+	}
+	secret := &corev1.Secret{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.SecretName}, secret); err != nil {
+		return Bundle{}, err
+	}
+	b := Bundle{
+		CACert:     secret.Data[secretKeyCertManagerCA],
+		ServerCert: secret.Data[corev1.TLSCertKey],
+		ServerKey:  secret.Data[corev1.TLSPrivateKeyKey],
+	}
+	if len(b.ServerCert) == 0 || len(b.ServerKey) == 0 {
+		return Bundle{}, fmt.Errorf("secret %s has no issued certificate yet", m.SecretName)
+	}
+	if len(b.CACert) == 0 {
+		return Bundle{}, fmt.Errorf("secret %s has no %s; use an issuer that publishes its CA", m.SecretName, secretKeyCertManagerCA)
+	}
+	return b, nil
+}
+
+// ensureCertificate creates or updates the cert-manager Certificate for the webhook Service.
+func (m *Manager) ensureCertificate(ctx context.Context) error {
+	kind, name, ok := strings.Cut(m.Issuer, "/")
+	if !ok || (kind != "Issuer" && kind != "ClusterIssuer") || name == "" {
+		return fmt.Errorf("invalid issuer %q, expected Issuer/<name> or ClusterIssuer/<name>", m.Issuer)
+	}
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetNamespace(m.Namespace)
+	cert.SetName(m.CertificateName)
+	_, err := controllerutil.CreateOrUpdate(ctx, m.Client, cert, func() error {
+		dnsNames := make([]interface{}, 0, 2)
+		for _, n := range DNSNames(m.ServiceName, m.Namespace) {
+			dnsNames = append(dnsNames, n)
+		}
+		return unstructured.SetNestedMap(cert.Object, map[string]interface{}{
+			"secretName": m.SecretName,
+			"dnsNames":   dnsNames,
+			"issuerRef": map[string]interface{}{
+				"group": "cert-manager.io",
+				"kind":  kind,
+				"name":  name,
+			},
+		}, "spec")
+	})
+	if err != nil {
+		return fmt.Errorf("ensure cert-manager Certificate %s: %w", m.CertificateName, err)
+	}
+	return nil
+}
+
+// writeFiles atomically replaces the files in CertDir. The key is written first so the
+// webhook server never pairs a new certificate with the old key.
+func (m *Manager) writeFiles(b Bundle) error {
+	if err := os.MkdirAll(m.CertDir, 0o700); err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name string
+		data []byte
+		mode os.FileMode
+	}{
+		{fileKey, b.ServerKey, 0o600},
+		{fileCert, b.ServerCert, 0o644},
+		{fileCA, b.CACert, 0o644},
+	} {
+		if err := writeFileAtomic(filepath.Join(m.CertDir, f.name), f.data, f.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateWebhookConfiguration sets caBundle on every webhook of the configuration. A missing
+// configuration is not an error: the controller creates it with the current bundle.
+func (m *Manager) updateWebhookConfiguration(ctx context.Context, caBundle []byte) error {
+	if m.WebhookConfigurationName == "" {
+		return nil
+	}
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Name: m.WebhookConfigurationName}, mwc); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	changed := false
+	for i := range mwc.Webhooks {
+		if !bytes.Equal(mwc.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			mwc.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := m.Client.Update(ctx, mwc); err != nil {
+		return fmt.Errorf("update caBundle of %s: %w", m.WebhookConfigurationName, err)
+	}
+	return nil
+}
+
+func notAfter(certPEM []byte) time.Time {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return time.Time{}
+	}
+	return cert.NotAfter
+}
@@ -0,0 +1,165 @@
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestManager(t *testing.T, mode string, objs ...client.Object) *Manager {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	return &Manager{
+		Client:                   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Mode:                     mode,
+		Namespace:                "whatap-monitoring",
+		ServiceName:              "whatap-admission-controller",
+		SecretName:               DefaultSecretName,
+		CertDir:                  t.TempDir(),
+		WebhookConfigurationName: "whatap-webhook",
+	}
+}
+
+func TestRefresh_SelfSignedPersistsAndRotates(t *testing.T) {
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "whatap-webhook"},
+		Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mpod.kb.io"}},
+	}
+	m := newTestManager(t, ModeSelfSigned, mwc)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := m.refresh(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := m.Bundle()
+	secret := &corev1.Secret{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.SecretName}, secret); err != nil {
+		t.Fatalf("Expected the certificate to be stored in a Secret: %v", err)
+	}
+	if !coversNames(first.ServerCert, DNSNames(m.ServiceName, m.Namespace)) {
+		t.Errorf("Expected the serving certificate to cover the webhook Service")
+	}
+	info, err := os.Stat(filepath.Join(m.CertDir, fileKey))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected %s with mode 0600, got %v (%v)", fileKey, info, err)
+	}
+	if err := m.Client.Get(ctx, client.ObjectKey{Name: "whatap-webhook"}, mwc); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mwc.Webhooks[0].ClientConfig.CABundle, first.CACert) {
+		t.Errorf("Expected the caBundle of the webhook configuration to be updated")
+	}
+
+	// A restarted operator (fresh manager) reuses the persisted certificate
+	restarted := &Manager{Client: m.Client, Mode: ModeSelfSigned, Namespace: m.Namespace, ServiceName: m.ServiceName, SecretName: m.SecretName, CertDir: t.TempDir()}
+	if err := restarted.refresh(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !restarted.Bundle().Equal(first) {
+		t.Errorf("Expected the persisted certificate to be reused")
+	}
+
+	// Close to expiry the serving certificate is reissued by the same CA
+	if err := m.refresh(ctx, now.Add(serverValidity-defaultRenewBefore+time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rotated := m.Bundle()
+	if bytes.Equal(rotated.ServerCert, first.ServerCert) || !bytes.Equal(rotated.CACert, first.CACert) {
+		t.Errorf("Expected only the serving certificate to be reissued")
+	}
+	served, _ := os.ReadFile(filepath.Join(m.CertDir, fileCert))
+	if !bytes.Equal(served, rotated.ServerCert) {
+		t.Errorf("Expected the reissued certificate to be written to %s", fileCert)
+	}
+}
+
+func TestRefresh_SelfSignedCARotationKeepsPreviousCA(t *testing.T) {
+	m := newTestManager(t, ModeSelfSigned)
+	ctx := context.Background()
+	now := time.Now()
+	if err := m.refresh(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := m.Bundle()
+
+	if err := m.refresh(ctx, now.Add(caValidity-defaultRenewBefore+time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rotated := m.Bundle()
+	if bytes.Equal(rotated.CAKey, first.CAKey) {
+		t.Fatalf("Expected a new CA close to expiry")
+	}
+	if !bytes.HasSuffix(rotated.CACert, first.CACert) {
+		t.Errorf("Expected the CA bundle to keep trusting the previous CA until it expires")
+	}
+}
+
+func TestRefresh_CertManager(t *testing.T) {
+	m := newTestManager(t, ModeCertManager)
+	m.SecretName = DefaultCertManagerSecretName
+	ctx := context.Background()
+	if err := m.refresh(ctx, time.Now()); err == nil {
+		t.Fatalf("Expected an error while the certificate is not issued")
+	}
+
+	caCert, caKey, err := generateCA(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := generateServerCert(caCert, caKey, DNSNames(m.ServiceName, m.Namespace), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Client.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: m.Namespace, Name: m.SecretName},
+		Data:       map[string][]byte{"ca.crt": caCert, "tls.crt": cert, "tls.key": key},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.refresh(ctx, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := m.Bundle(); !bytes.Equal(b.CACert, caCert) || len(b.CAKey) != 0 {
+		t.Errorf("Expected the issued certificate and its CA, got %+v", b)
+	}
+}
+
+func TestEnsureCertificate(t *testing.T) {
+	m := newTestManager(t, ModeCertManager)
+	m.CertificateName = "whatap-webhook-certificate"
+	ctx := context.Background()
+
+	m.Issuer = "internal-ca"
+	if err := m.ensureCertificate(ctx); err == nil {
+		t.Errorf("Expected an error for an issuer without kind")
+	}
+
+	m.Issuer = "ClusterIssuer/internal-ca"
+	if err := m.ensureCertificate(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	if err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.CertificateName}, cert); err != nil {
+		t.Fatal(err)
+	}
+	if kind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind"); kind != "ClusterIssuer" {
+		t.Errorf("Expected issuerRef kind ClusterIssuer, got %q", kind)
+	}
+	if name, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName"); name != m.SecretName {
+		t.Errorf("Expected secretName %q, got %q", m.SecretName, name)
+	}
+}